@@ -0,0 +1,618 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+	"github.com/mackeper/lin_router/specctra"
+)
+
+// runRoute implements `lin_router route FILE`, which adds tracks and vias to
+// a board. It exits with 0 when every net is connected afterwards, 1 when
+// some are not or the new copper breaks the clearance and 2 on errors, so CI
+// can fail on partially routed or unsafe boards.
+func runRoute(g *globalFlags, args []string) (status int) {
+	flags := g.newFlagSet("route", "FILE", "Route a KiCad PCB file and write the board with the new tracks and vias.")
+	inputPath := flags.String("i", "", "Path to the KiCad PCB file to route, instead of FILE")
+	maxDistance := flags.Float64("max-distance", 3.0, "Maximum routing distance in mm")
+	router := flags.String("router", "trivial", "Routing algorithm: trivial or pathfinder")
+	gridPitch := flags.Float64("grid", pcb.DefaultPathFinderOptions().GridPitch, "Routing grid pitch in mm (pathfinder)")
+	netOrder := flags.String("net-order", string(pcb.OrderNetNumber), "Net routing order: net, shortest, longest, pins, area or power")
+	netPriority := flags.String("net-priority", "", "Comma-separated net names to route first, in order")
+	maxIterations := flags.Int("max-iterations", pcb.DefaultPathFinderOptions().MaxIterations, "Maximum rip-up and reroute iterations (pathfinder)")
+	seed := flags.String("seed", "", "Derive segment and via UUIDs from this seed for reproducible output")
+	workers := flags.Int("j", runtime.NumCPU(), "Number of nets routed concurrently; the output does not depend on it")
+	recoverErrors := flags.Bool("recover", false, "Skip malformed expressions and board items that cannot be read with a warning instead of failing")
+	targetVersion := flags.String("target-version", "", "Write the output as this KiCad release (e.g. 8) or file version instead of the input's")
+	inPlace := flags.Bool("inplace", false, "Replace the input file with the routed board")
+	backup := flags.Bool("backup", false, "Keep the file replaced by -o or -inplace as FILE.bak")
+	reportPath := flags.String("report", "", "Write a JSON summary of the run to this file, also when it fails")
+	clearance := flags.Float64("clearance", pcb.DefaultClearance, "Clearance to copper of other nets in mm, kept by the pathfinder router and between nets routed at the same time, and checked for -report")
+	g.parse(flags, args)
+
+	if *inputPath == "" && flags.NArg() == 1 {
+		*inputPath = flags.Arg(0)
+	}
+	if *inputPath == "" || flags.NArg() > 1 {
+		flags.Usage()
+		return exitError
+	}
+	if *inPlace && g.output != "" {
+		printError(fmt.Errorf("-o and -inplace cannot be used together"))
+		return exitError
+	}
+	if *inPlace {
+		g.output = *inputPath
+	}
+
+	start := time.Now()
+	summary := RunSummary{Status: statusError}
+	if *reportPath != "" {
+		defer func() {
+			if err := writeRunSummary(*reportPath, summary, start); err != nil {
+				printError(err)
+				status = exitError
+			}
+		}()
+	}
+	// fail reports err, about the input file when inFile, and records it in
+	// the summary
+	fail := func(err error, inFile bool) int {
+		if inFile {
+			printFileError(*inputPath, err)
+		} else {
+			printError(err)
+		}
+		summary.Status = statusError
+		summary.Error = err.Error()
+		return exitError
+	}
+
+	// Hash the input before reading it, so that changes made while routing
+	// stop it from being overwritten
+	inputHash, err := HashFile(*inputPath)
+	if err != nil {
+		return fail(err, true)
+	}
+
+	slog.Debug("Parsing PCB file", "path", *inputPath)
+	var expr lexer.Expr
+	if *recoverErrors {
+		var diagnostics []*lexer.ParseError
+		expr, diagnostics, err = ParsePcbFileRecover(*inputPath)
+		for _, diagnostic := range diagnostics {
+			printDiagnostic(*inputPath, "warning", diagnostic)
+		}
+	} else {
+		expr, err = ParsePcbFile(*inputPath)
+	}
+	if err != nil {
+		return fail(err, true)
+	}
+
+	if *targetVersion != "" {
+		version, err := ParseTargetVersion(*targetVersion)
+		if err != nil {
+			return fail(fmt.Errorf("invalid -target-version: %w", err), false)
+		}
+		if err := UpgradeBoard(&expr, version); err != nil {
+			return fail(err, true)
+		}
+	}
+
+	strategy, err := pcb.ParseNetOrder(*netOrder)
+	if err != nil {
+		return fail(fmt.Errorf("invalid -net-order: %w", err), false)
+	}
+	if *gridPitch <= 0 {
+		return fail(fmt.Errorf("invalid -grid: must be positive, got %g", *gridPitch), false)
+	}
+	opts := RouteOptions{
+		Router:      *router,
+		MaxDistance: *maxDistance,
+		Clearance:   *clearance,
+		PathFinder:  pcb.DefaultPathFinderOptions(),
+		Ordering:    pcb.NetOrdering{Strategy: strategy},
+		Seed:        *seed,
+		Workers:     *workers,
+		Recover:     *recoverErrors,
+	}
+	opts.PathFinder.GridPitch = *gridPitch
+	opts.PathFinder.MaxIterations = *maxIterations
+	if *netPriority != "" {
+		for _, name := range strings.Split(*netPriority, ",") {
+			opts.Ordering.Priority = append(opts.Ordering.Priority, strings.TrimSpace(name))
+		}
+	}
+
+	var before *pcb.Board
+	if *recoverErrors {
+		var diagnostics []*lexer.ParseError
+		before, diagnostics = ExprToPCBRecover(expr)
+		for _, diagnostic := range diagnostics {
+			printDiagnostic(*inputPath, "warning", diagnostic)
+		}
+	} else if before, err = ExprToPCB(expr); err != nil {
+		return fail(err, true)
+	}
+	for _, name := range unknownNets(before, opts.Ordering.Priority) {
+		printWarning(fmt.Errorf("-net-priority: no net is named %q", name))
+	}
+	expr, report, err := RouteExpr(expr, opts)
+	if err != nil {
+		return fail(err, true)
+	}
+	after, err := exprToBoard(expr, *recoverErrors)
+	if err != nil {
+		return fail(err, true)
+	}
+	if report != nil {
+		printRouteReport(*report)
+	}
+	summary = SummarizeRoute(before, after, *clearance)
+	fmt.Fprintf(os.Stderr, "Connected %d of %d nets\n", len(summary.Nets.Routed), summary.Nets.Total)
+	if summary.NewViolations > 0 {
+		fmt.Fprintf(os.Stderr, "Added %d clearance violations, see lin_router drc\n", summary.NewViolations)
+	}
+
+	if g.output == "" {
+		fmt.Print(expr.String())
+	} else {
+		writeOpts := WriteOptions{Backup: *backup}
+		if sameFile(g.output, *inputPath) {
+			writeOpts.Expected = &inputHash
+		}
+		if err := WritePcbFile(g.output, []byte(expr.String()), writeOpts); err != nil {
+			return fail(err, false)
+		}
+	}
+	if summary.Status != statusRouted {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// runDRC implements `lin_router drc FILE`, which lists pads, tracks and vias
+// closer than the clearance to copper of another net. It exits with 1 when
+// there are violations.
+func runDRC(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("drc", "FILE", "List copper of different nets that is closer than the clearance.")
+	clearance := flags.Float64("clearance", pcb.DefaultClearance, "Minimum clearance between copper of different nets in mm")
+	jsonOutput := flags.Bool("json", false, "Print the violations as JSON")
+	g.parse(flags, args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	_, board, ok := readBoard(flags.Arg(0))
+	if !ok {
+		return exitError
+	}
+	violations := CheckClearance(board, *clearance)
+	if !writeReport(g, *jsonOutput, violations, func(sb *strings.Builder) {
+		for _, violation := range violations {
+			fmt.Fprintln(sb, violation)
+		}
+		fmt.Fprintf(sb, "%d violations\n", len(violations))
+	}) {
+		return exitError
+	}
+	if len(violations) > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// runStats implements `lin_router stats FILE`, which counts what a board
+// holds.
+func runStats(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("stats", "FILE", "Count the nets, footprints, pads, tracks and vias of a KiCad PCB file.")
+	jsonOutput := flags.Bool("json", false, "Print the counts as JSON")
+	g.parse(flags, args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	_, board, ok := readBoard(flags.Arg(0))
+	if !ok {
+		return exitError
+	}
+	stats := ComputeStats(board)
+	if !writeReport(g, *jsonOutput, stats, func(sb *strings.Builder) {
+		fmt.Fprintln(sb, stats)
+	}) {
+		return exitError
+	}
+	return exitOK
+}
+
+// runRatsnest implements `lin_router ratsnest FILE`, which lists the
+// connections still to be routed. It exits with 1 when there are any.
+func runRatsnest(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("ratsnest", "FILE", "List the connections between pads that are not routed yet.")
+	jsonOutput := flags.Bool("json", false, "Print the connections as JSON")
+	g.parse(flags, args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	_, board, ok := readBoard(flags.Arg(0))
+	if !ok {
+		return exitError
+	}
+	connections := Ratsnest(board)
+	if !writeReport(g, *jsonOutput, connections, func(sb *strings.Builder) {
+		for _, connection := range connections {
+			fmt.Fprintln(sb, connection)
+		}
+		fmt.Fprintf(sb, "%d unrouted connections\n", len(connections))
+	}) {
+		return exitError
+	}
+	if len(connections) > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// writeReport writes v as indented JSON or as the text written by text, to
+// the -o file or stdout. It reports whether that succeeded.
+func writeReport(g *globalFlags, asJSON bool, v any, text func(sb *strings.Builder)) bool {
+	var sb strings.Builder
+	if asJSON {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			printError(err)
+			return false
+		}
+		sb.Write(data)
+		sb.WriteString("\n")
+	} else {
+		text(&sb)
+	}
+	if err := g.writeOutput([]byte(sb.String())); err != nil {
+		printError(err)
+		return false
+	}
+	return true
+}
+
+// runFmt implements `lin_router fmt FILE...`, which reformats board files in
+// place the way pcbnew writes them, or writes a single one to -o.
+func runFmt(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("fmt", "FILE...", "Reformat KiCad PCB files in place in pcbnew's style.")
+	g.parse(flags, args)
+	if flags.NArg() == 0 || (g.output != "" && flags.NArg() > 1) {
+		flags.Usage()
+		return exitError
+	}
+
+	if g.output != "" {
+		path := flags.Arg(0)
+		expr, err := ParsePcbFile(path)
+		if err != nil {
+			printFileError(path, err)
+			return exitError
+		}
+		if err := g.writeOutput([]byte(lexer.FormatFile(expr))); err != nil {
+			printError(err)
+			return exitError
+		}
+		return exitOK
+	}
+	status := exitOK
+	for _, path := range flags.Args() {
+		if err := FormatPcbFile(path); err != nil {
+			printFileError(path, err)
+			status = exitError
+		}
+	}
+	return status
+}
+
+// runCheck implements `lin_router check FILE...`, which lists every syntax
+// problem in board files instead of stopping at the first one. It exits with
+// 1 when there are any.
+func runCheck(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("check", "FILE...", "List all syntax errors in KiCad PCB files.")
+	g.parse(flags, args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitError
+	}
+
+	status := exitOK
+	for _, path := range flags.Args() {
+		_, diagnostics, err := ParsePcbFileRecover(path)
+		if err != nil {
+			printFileError(path, err)
+			status = exitError
+			continue
+		}
+		for _, diagnostic := range diagnostics {
+			printDiagnostic(path, "error", diagnostic)
+		}
+		if len(diagnostics) > 0 && status == exitOK {
+			status = exitFindings
+		}
+	}
+	return status
+}
+
+// runQuery implements `lin_router query PATH FILE...`, which prints the
+// expressions matching a lexer.ParseQuery path one per line. Like grep, it
+// exits with 0 when something matched, 1 when nothing did and 2 on errors.
+func runQuery(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("query", "PATH FILE...", "Print the expressions matching PATH, e.g. 'footprint/pad[net.1=GND]/at'.")
+	positions := flags.Bool("n", false, "Prefix matches with file:line:column")
+	valuesOnly := flags.Bool("values", false, "Print only the scalar values of matches")
+	count := flags.Bool("count", false, "Print the number of matches per file")
+	g.parse(flags, args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return exitError
+	}
+
+	query, err := lexer.ParseQuery(flags.Arg(0))
+	if err != nil {
+		printError(err)
+		return exitError
+	}
+
+	var sb strings.Builder
+	files := flags.Args()[1:]
+	status := exitFindings
+	for _, path := range files {
+		expr, err := ParsePcbFile(path)
+		if err != nil {
+			printFileError(path, err)
+			return exitError
+		}
+
+		matches := query.FindAll(expr)
+		if len(matches) > 0 {
+			status = exitOK
+		}
+		if *count {
+			if len(files) > 1 {
+				fmt.Fprintf(&sb, "%s:", path)
+			}
+			fmt.Fprintln(&sb, len(matches))
+			continue
+		}
+		for _, match := range matches {
+			switch {
+			case *positions:
+				fmt.Fprintf(&sb, "%s:%s: ", path, match.Pos)
+			case len(files) > 1:
+				fmt.Fprintf(&sb, "%s: ", path)
+			}
+			if *valuesOnly {
+				fmt.Fprintln(&sb, scalarValues(match))
+			} else {
+				fmt.Fprintln(&sb, lexer.FormatInline(match))
+			}
+		}
+	}
+	if err := g.writeOutput([]byte(sb.String())); err != nil {
+		printError(err)
+		return exitError
+	}
+	return status
+}
+
+// runExport implements `lin_router export FILE`, which writes a board as
+// JSON: the model the router reads, see pcb/board.schema.json, or the
+// expression tree for scripts that work on the file itself. It also writes
+// Specctra designs for external autorouters.
+func runExport(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("export", "FILE", "Write a KiCad PCB file as JSON or as a Specctra design.")
+	format := flags.String("format", "board", "board for the nets, footprints, pads, tracks, vias and outline (pcb/board.schema.json), tree for the expression tree, dsn for a Specctra design")
+	dsnOpts := specctra.DefaultOptions()
+	flags.Float64Var(&dsnOpts.TraceWidth, "width", dsnOpts.TraceWidth, "Track width of the net class in mm (dsn)")
+	flags.Float64Var(&dsnOpts.Clearance, "clearance", dsnOpts.Clearance, "Clearance of the net class in mm (dsn)")
+	flags.Float64Var(&dsnOpts.ViaSize, "via-size", dsnOpts.ViaSize, "Diameter of the vias the router may add in mm (dsn)")
+	flags.Float64Var(&dsnOpts.ViaDrill, "via-drill", dsnOpts.ViaDrill, "Drill of the vias the router may add in mm (dsn)")
+	g.parse(flags, args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	path := flags.Arg(0)
+	var v any
+	switch *format {
+	case "board":
+		_, board, ok := readBoard(path)
+		if !ok {
+			return exitError
+		}
+		v = board
+	case "tree":
+		expr, err := ParsePcbFile(path)
+		if err != nil {
+			printFileError(path, err)
+			return exitError
+		}
+		v = expr
+	case "dsn":
+		_, board, ok := readBoard(path)
+		if !ok {
+			return exitError
+		}
+		dsnOpts.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		dsn := specctra.BoardToDSN(board, dsnOpts)
+		if err := g.writeOutput([]byte(lexer.Format(dsn) + "\n")); err != nil {
+			printError(err)
+			return exitError
+		}
+		return exitOK
+	default:
+		printError(fmt.Errorf("unknown -format %q: want board, tree or dsn", *format))
+		return exitError
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		printFileError(path, err)
+		return exitError
+	}
+	if err := g.writeOutput(append(data, '\n')); err != nil {
+		printError(err)
+		return exitError
+	}
+	return exitOK
+}
+
+// runImport implements `lin_router import FILE`, which turns JSON written by
+// `export -format tree` back into a board file in pcbnew's style, or adds
+// the wires and vias of a Specctra session to a board.
+func runImport(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("import", "FILE", "Write a KiCad PCB file from JSON written by export -format tree, or from a board and a Specctra session.")
+	format := flags.String("format", "tree", "tree for JSON written by export -format tree, ses for a Specctra session routing -board")
+	boardPath := flags.String("board", "", "KiCad PCB file the session's design was exported from (ses)")
+	g.parse(flags, args)
+	if flags.NArg() != 1 || (*format == "ses") != (*boardPath != "") {
+		flags.Usage()
+		return exitError
+	}
+
+	path := flags.Arg(0)
+	var expr lexer.Expr
+	switch *format {
+	case "tree":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			printError(err)
+			return exitError
+		}
+		if err := json.Unmarshal(data, &expr); err != nil {
+			printFileError(path, err)
+			return exitError
+		}
+		if err := g.writeOutput([]byte(lexer.Format(expr) + "\n")); err != nil {
+			printError(err)
+			return exitError
+		}
+		return exitOK
+	case "ses":
+		board, err := ParsePcbFile(*boardPath)
+		if err != nil {
+			printFileError(*boardPath, err)
+			return exitError
+		}
+		session, err := ParseSessionFile(path)
+		if err != nil {
+			printFileError(path, err)
+			return exitError
+		}
+		expr, segments, vias, err := ImportSession(board, session)
+		if err != nil {
+			printFileError(path, err)
+			return exitError
+		}
+		fmt.Fprintf(os.Stderr, "Added %d tracks and %d vias\n", segments, vias)
+		if err := g.writeOutput([]byte(expr.String())); err != nil {
+			printError(err)
+			return exitError
+		}
+		return exitOK
+	default:
+		printError(fmt.Errorf("unknown -format %q: want tree or ses", *format))
+		return exitError
+	}
+}
+
+// runDiff implements `lin_router diff OLD NEW`, which lists the tracks, vias,
+// footprints and zones that differ between two boards. It exits with 1 when
+// copper changed.
+func runDiff(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("diff", "OLD NEW", "List the tracks, vias, footprints and zones that differ between two KiCad PCB files.")
+	jsonOutput := flags.Bool("json", false, "Print the differences as JSON")
+	g.parse(flags, args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitError
+	}
+
+	boards := make([]lexer.Expr, 2)
+	for i, path := range flags.Args() {
+		expr, err := ParsePcbFile(path)
+		if err != nil {
+			printFileError(path, err)
+			return exitError
+		}
+		boards[i] = expr
+	}
+
+	diff := DiffBoards(boards[0], boards[1])
+	if !writeReport(g, *jsonOutput, diff, func(sb *strings.Builder) {
+		for _, change := range diff.Changes {
+			fmt.Fprintln(sb, change)
+		}
+		fmt.Fprintln(sb, diff.Summary())
+	}) {
+		return exitError
+	}
+	if diff.CopperChanged {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// runMerge implements `lin_router merge BASE OURS THEIRS`, a three-way merge
+// of board files. It exits with 1 when there are conflicts, which are listed
+// on stderr, so it can be used as a git merge driver.
+func runMerge(g *globalFlags, args []string) int {
+	flags := g.newFlagSet("merge", "BASE OURS THEIRS", `Merge the changes from BASE to THEIRS into OURS, item by item. Items changed
+differently on both sides, and new copper of one side touching new copper of
+another net on the other, are reported as conflicts; OURS wins them.
+
+To use it as a git merge driver, add to .git/config:
+
+	[merge "kicad_pcb"]
+		name = KiCad board merge
+		driver = lin_router merge -o %A %O %A %B
+
+and to .gitattributes:
+
+	*.kicad_pcb merge=kicad_pcb`)
+	g.parse(flags, args)
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return exitError
+	}
+
+	boards := make([]lexer.Expr, 3)
+	for i, path := range flags.Args() {
+		expr, err := ParsePcbFile(path)
+		if err != nil {
+			printFileError(path, err)
+			return exitError
+		}
+		boards[i] = expr
+	}
+
+	merged, conflicts := MergeBoards(boards[0], boards[1], boards[2])
+	if err := g.writeOutput([]byte(merged.String())); err != nil {
+		printError(err)
+		return exitError
+	}
+	for _, conflict := range conflicts {
+		fmt.Fprintf(os.Stderr, "conflict: %s\n", conflict)
+	}
+	if len(conflicts) > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mackeper/lin_router/lexer"
+)
+
+// BoardDiff lists the items that differ between two versions of a board.
+type BoardDiff struct {
+	Changes       []ItemChange `json:"changes"`
+	CopperChanged bool         `json:"copper_changed"`
+}
+
+// ItemChange is a track, via, footprint or zone that was added, removed or
+// modified. The descriptive fields are taken from the new item, or from the
+// old one when it was removed.
+type ItemChange struct {
+	Change    string        `json:"change"` // added, removed or modified
+	Kind      string        `json:"kind"`   // segment, arc, via, footprint or zone
+	UUID      string        `json:"uuid,omitempty"`
+	Net       string        `json:"net,omitempty"`
+	Reference string        `json:"reference,omitempty"`
+	Layer     string        `json:"layer,omitempty"`
+	At        *diffPoint    `json:"at,omitempty"`  // Start of tracks
+	End       *diffPoint    `json:"end,omitempty"` // End of tracks
+	Fields    []FieldChange `json:"fields,omitempty"`
+	Copper    bool          `json:"copper"`
+}
+
+// FieldChange is a child expression of a modified item, e.g. its (width ...).
+// Old or New is empty when the child was added or removed.
+type FieldChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+type diffPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func (p diffPoint) String() string {
+	return fmt.Sprintf("(%s, %s)", lexer.FormatNumber(p.X), lexer.FormatNumber(p.Y))
+}
+
+// diffKinds are the board items DiffBoards compares.
+var diffKinds = map[lexer.ExprType]bool{
+	lexer.ExprSegment:   true,
+	lexer.ExprArc:       true,
+	lexer.ExprVia:       true,
+	lexer.ExprFootprint: true,
+	lexer.ExprZone:      true,
+}
+
+// footprintCopper are the children of a footprint that change its copper.
+var footprintCopper = map[string]bool{"at": true, "layer": true, "pad": true}
+
+type boardItem struct {
+	expr    lexer.Expr
+	uuid    string
+	matched bool
+}
+
+// boardItems returns the items of a board that diff compares, in file order.
+func boardItems(board lexer.Expr) []*boardItem {
+	items := []*boardItem{}
+	for _, val := range board.Values {
+		if v, ok := val.(lexer.ExprValue); ok && diffKinds[v.Value.Type] {
+			items = append(items, &boardItem{expr: v.Value, uuid: itemUUID(v.Value)})
+		}
+	}
+	return items
+}
+
+// DiffBoards compares the tracks, vias, footprints and zones of two boards.
+// Items are paired by UUID, then by identityKey for items whose UUID changed,
+// such as tracks rerouted without a seed.
+func DiffBoards(oldBoard, newBoard lexer.Expr) BoardDiff {
+	oldItems, newItems := boardItems(oldBoard), boardItems(newBoard)
+	oldDec, newDec := newDecoder(oldBoard), newDecoder(newBoard)
+
+	pairs := make(map[*boardItem]*boardItem) // New item -> old item
+	byUUID := make(map[string]*boardItem)
+	for _, item := range oldItems {
+		if item.uuid != "" {
+			byUUID[item.expr.Type.String()+"|"+item.uuid] = item
+		}
+	}
+	for _, item := range newItems {
+		if old, ok := byUUID[item.expr.Type.String()+"|"+item.uuid]; ok && item.uuid != "" && !old.matched {
+			old.matched, item.matched = true, true
+			pairs[item] = old
+		}
+	}
+	byKey := make(map[string][]*boardItem)
+	for _, item := range oldItems {
+		if !item.matched {
+			key := identityKey(item.expr, oldDec)
+			byKey[key] = append(byKey[key], item)
+		}
+	}
+	for _, item := range newItems {
+		if item.matched {
+			continue
+		}
+		key := identityKey(item.expr, newDec)
+		if candidates := byKey[key]; len(candidates) > 0 {
+			old := candidates[0]
+			byKey[key] = candidates[1:]
+			old.matched, item.matched = true, true
+			pairs[item] = old
+		}
+	}
+
+	diff := BoardDiff{Changes: []ItemChange{}}
+	add := func(change ItemChange) {
+		diff.Changes = append(diff.Changes, change)
+		diff.CopperChanged = diff.CopperChanged || change.Copper
+	}
+	for _, item := range oldItems {
+		if !item.matched {
+			change := describeItem("removed", item, oldDec)
+			change.Copper = hasCopper(item.expr)
+			add(change)
+		}
+	}
+	for _, item := range newItems {
+		old, ok := pairs[item]
+		if !ok {
+			change := describeItem("added", item, newDec)
+			change.Copper = hasCopper(item.expr)
+			add(change)
+			continue
+		}
+		fields := diffFields(old.expr, item.expr)
+		if len(fields) == 0 {
+			continue
+		}
+		change := describeItem("modified", item, newDec)
+		change.Fields = fields
+		for _, field := range fields {
+			name, _, _ := strings.Cut(field.Name, "[")
+			if item.expr.Type != lexer.ExprFootprint || footprintCopper[name] {
+				change.Copper = true
+			}
+		}
+		add(change)
+	}
+	return diff
+}
+
+// hasCopper reports whether an item puts copper on the board. Footprints do
+// through their pads.
+func hasCopper(e lexer.Expr) bool {
+	return e.Type != lexer.ExprFootprint || len(e.Children("pad")) > 0
+}
+
+// identityKey names an item by what it is rather than its UUID: tracks by
+// their ends, vias by position, footprints by reference and zones by net
+// and layers.
+func identityKey(e lexer.Expr, d *decoder) string {
+	inline := func(identifier string) string {
+		child, err := e.Child(identifier)
+		if err != nil {
+			return ""
+		}
+		return lexer.FormatInline(child)
+	}
+	switch e.Type {
+	case lexer.ExprSegment, lexer.ExprArc:
+		ends := []string{inline("start"), inline("end")}
+		ends[0] = strings.TrimPrefix(ends[0], "(start")
+		ends[1] = strings.TrimPrefix(ends[1], "(end")
+		if ends[0] > ends[1] {
+			// The same track drawn the other way round
+			ends[0], ends[1] = ends[1], ends[0]
+		}
+		return strings.Join([]string{e.Type.String(), inline("layer"), inline("mid"), ends[0], ends[1]}, "|")
+	case lexer.ExprVia:
+		return "via|" + inline("at")
+	case lexer.ExprFootprint:
+		if reference := d.footprintReference(e); reference != "" {
+			return "footprint|" + reference
+		}
+		name, _ := e.TextAt(0)
+		return "footprint|" + name + "|" + inline("at")
+	default:
+		return strings.Join([]string{"zone", itemNet(e, d), inline("layer"), inline("layers"), inline("name")}, "|")
+	}
+}
+
+// itemNet returns the net name of an item, from the board's net table for
+// (net N) or from (net_name ...) in zones.
+func itemNet(e lexer.Expr, d *decoder) string {
+	if netName, ok := e.First("net_name"); ok {
+		name, _ := netName.TextAt(0)
+		return name
+	}
+	net, ok := e.First("net")
+	if !ok {
+		return ""
+	}
+	if name, err := net.TextAt(1); err == nil {
+		return name
+	}
+	if name, err := net.TextAt(0); err == nil {
+		return name
+	}
+	number, _ := net.NumberAt(0)
+	return d.netNames[int(number)]
+}
+
+func describeItem(change string, item *boardItem, d *decoder) ItemChange {
+	e := item.expr
+	described := ItemChange{
+		Change: change,
+		Kind:   e.Type.String(),
+		UUID:   item.uuid,
+		Net:    itemNet(e, d),
+	}
+	if layer, ok := e.First("layer"); ok {
+		described.Layer, _ = layer.TextAt(0)
+	} else if layers, ok := e.First("layers"); ok {
+		names := []string{}
+		for i := range layers.Values {
+			if name, err := layers.TextAt(i); err == nil {
+				names = append(names, name)
+			}
+		}
+		described.Layer = strings.Join(names, " ")
+	}
+	point := func(identifier string) *diffPoint {
+		child, ok := e.First(identifier)
+		if !ok {
+			return nil
+		}
+		position, err := parseAtPosition(child)
+		if err != nil {
+			return nil
+		}
+		return &diffPoint{X: position.X, Y: position.Y}
+	}
+	switch e.Type {
+	case lexer.ExprSegment, lexer.ExprArc:
+		described.At, described.End = point("start"), point("end")
+	case lexer.ExprFootprint:
+		described.At = point("at")
+		described.Reference = d.footprintReference(e)
+	case lexer.ExprVia:
+		described.At = point("at")
+	case lexer.ExprZone:
+		if polygon, ok := e.First("polygon/pts/xy"); ok {
+			if position, err := parseAtPosition(polygon); err == nil {
+				described.At = &diffPoint{X: position.X, Y: position.Y}
+			}
+		}
+	}
+	return described
+}
+
+// diffFields compares the children of two versions of an item by
+// identifier, the n-th (pad ...) of one with the n-th of the other. UUIDs
+// are left out, as items are only paired when they are the same item.
+func diffFields(oldItem, newItem lexer.Expr) []FieldChange {
+	fields := []FieldChange{}
+	oldScalars, newScalars := scalarValues(oldItem), scalarValues(newItem)
+	if oldScalars != newScalars {
+		fields = append(fields, FieldChange{Name: "values", Old: oldScalars, New: newScalars})
+	}
+
+	oldChildren, names := childrenByName(oldItem, nil)
+	newChildren, names := childrenByName(newItem, names)
+	for _, name := range names {
+		if name == "uuid" || name == "tstamp" {
+			continue
+		}
+		olds, news := oldChildren[name], newChildren[name]
+		for i := 0; i < max(len(olds), len(news)); i++ {
+			field := FieldChange{Name: name}
+			if i < len(olds) {
+				field.Old = olds[i]
+			}
+			if i < len(news) {
+				field.New = news[i]
+			}
+			if field.Old == field.New {
+				continue
+			}
+			if len(olds) > 1 || len(news) > 1 {
+				field.Name = fmt.Sprintf("%s[%d]", name, i)
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// childrenByName returns the nested expressions of e on one line each,
+// grouped by identifier, and adds identifiers not yet in names to it.
+func childrenByName(e lexer.Expr, names []string) (map[string][]string, []string) {
+	children := make(map[string][]string)
+	for _, val := range e.Values {
+		v, ok := val.(lexer.ExprValue)
+		if !ok {
+			continue
+		}
+		name := v.Value.Identifier
+		if _, seen := children[name]; !seen && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+		children[name] = append(children[name], lexer.FormatInline(v.Value))
+	}
+	return children, names
+}
+
+// String returns the change as one line, followed by one indented line per
+// modified field, e.g.
+//
+//	~ segment GND F.Cu (10, 20) -> (30, 20)
+//	    width: (width 0.2) -> (width 0.25)
+func (c ItemChange) String() string {
+	var sb strings.Builder
+	sb.WriteString(map[string]string{"added": "+", "removed": "-", "modified": "~"}[c.Change])
+	sb.WriteString(" " + c.Kind)
+	for _, part := range []string{c.Reference, c.Net, c.Layer} {
+		if part != "" {
+			sb.WriteString(" " + part)
+		}
+	}
+	if c.At != nil {
+		sb.WriteString(" " + c.At.String())
+	}
+	if c.End != nil {
+		sb.WriteString(" -> " + c.End.String())
+	}
+	for _, field := range c.Fields {
+		before, after := field.Old, field.New
+		if before == "" {
+			before = "none"
+		}
+		if after == "" {
+			after = "none"
+		}
+		fmt.Fprintf(&sb, "\n    %s: %s -> %s", field.Name, before, after)
+	}
+	return sb.String()
+}
+
+// Summary counts the changes, e.g. "2 added, 1 removed, 0 modified".
+func (d BoardDiff) Summary() string {
+	counts := map[string]int{}
+	for _, change := range d.Changes {
+		counts[change.Change]++
+	}
+	summary := fmt.Sprintf("%d added, %d removed, %d modified", counts["added"], counts["removed"], counts["modified"])
+	if d.CopperChanged {
+		summary += "; copper changed"
+	}
+	return summary
+}
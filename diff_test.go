@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const diffBase = `(kicad_pcb (version 20240108)
+	(net 0 "") (net 1 "GND") (net 2 "VCC")
+	(footprint "R_0805" (layer "F.Cu") (at 10 10)
+		(property "Reference" "R1" (at 0 -1.5) (layer "F.SilkS"))
+		(pad "1" smd rect (at -1 0) (size 1 1) (layers "F.Cu") (net 1 "GND")))
+	(segment (start 0 0) (end 5 0) (width 0.2) (layer "F.Cu") (net 1) (uuid "s1"))
+	(via (at 5 0) (size 0.6) (drill 0.3) (layers "F.Cu" "B.Cu") (net 1) (uuid "v1"))
+	(zone (net 2) (net_name "VCC") (layer "B.Cu") (uuid "z1") (polygon (pts (xy 0 0) (xy 10 0) (xy 10 10))))
+)`
+
+func TestDiffBoards(t *testing.T) {
+	tests := []struct {
+		name     string
+		replace  []string // Pairs of old and new text applied to diffBase
+		expected []string // ItemChange.String of each change
+		copper   bool
+	}{
+		{
+			"identical",
+			nil,
+			[]string{},
+			false,
+		},
+		{
+			"new UUIDs only",
+			[]string{`"s1"`, `"s9"`, `"v1"`, `"v9"`},
+			[]string{},
+			false,
+		},
+		{
+			"segment drawn the other way round",
+			[]string{`(start 0 0) (end 5 0) (width 0.2) (layer "F.Cu") (net 1) (uuid "s1")`, `(start 5 0) (end 0 0) (width 0.2) (layer "F.Cu") (net 1) (uuid "s2")`},
+			[]string{"~ segment GND F.Cu (5, 0) -> (0, 0)\n    start: (start 0 0) -> (start 5 0)\n    end: (end 5 0) -> (end 0 0)"},
+			true,
+		},
+		{
+			"segment added",
+			[]string{"\n)", "\n(segment (start 5 0) (end 5 5) (width 0.2) (layer \"B.Cu\") (net 1) (uuid \"s2\")))"},
+			[]string{"+ segment GND B.Cu (5, 0) -> (5, 5)"},
+			true,
+		},
+		{
+			"via removed",
+			[]string{`(via (at 5 0) (size 0.6) (drill 0.3) (layers "F.Cu" "B.Cu") (net 1) (uuid "v1"))`, ""},
+			[]string{"- via GND F.Cu B.Cu (5, 0)"},
+			true,
+		},
+		{
+			"track width changed",
+			[]string{"(width 0.2)", "(width 0.25)"},
+			[]string{"~ segment GND F.Cu (0, 0) -> (5, 0)\n    width: (width 0.2) -> (width 0.25)"},
+			true,
+		},
+		{
+			"footprint moved",
+			[]string{"(at 10 10)", "(at 12 10)"},
+			[]string{"~ footprint R1 F.Cu (12, 10)\n    at: (at 10 10) -> (at 12 10)"},
+			true,
+		},
+		{
+			"footprint silkscreen moved",
+			[]string{"(at 0 -1.5)", "(at 0 -2)"},
+			[]string{`~ footprint R1 F.Cu (10, 10)` + "\n" + `    property: (property "Reference" "R1" (at 0 -1.5) (layer "F.SilkS")) -> (property "Reference" "R1" (at 0 -2) (layer "F.SilkS"))`},
+			false,
+		},
+		{
+			"zone net changed",
+			[]string{`(net 2) (net_name "VCC")`, `(net 1) (net_name "GND")`},
+			[]string{"~ zone GND B.Cu (0, 0)\n    net: (net 2) -> (net 1)\n    net_name: (net_name \"VCC\") -> (net_name \"GND\")"},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			oldBoard := parseBoardString(t, diffBase)
+			source := diffBase
+			for i := 0; i+1 < len(tt.replace); i += 2 {
+				source = strings.Replace(source, tt.replace[i], tt.replace[i+1], 1)
+			}
+			newBoard := parseBoardString(t, source)
+
+			// Act
+			diff := DiffBoards(oldBoard, newBoard)
+
+			// Assert
+			got := []string{}
+			for _, change := range diff.Changes {
+				got = append(got, change.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected changes\n%s\ngot\n%s", strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+			}
+			if diff.CopperChanged != tt.copper {
+				t.Errorf("Expected copper changed %v, got %v", tt.copper, diff.CopperChanged)
+			}
+		})
+	}
+}
+
+func TestDiffBoards_JSON(t *testing.T) {
+	// Arrange
+	oldBoard := parseBoardString(t, diffBase)
+	newBoard := parseBoardString(t, strings.Replace(diffBase, "(width 0.2)", "(width 0.25)", 1))
+
+	// Act
+	data, err := json.Marshal(DiffBoards(oldBoard, newBoard))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := `{"changes":[{"change":"modified","kind":"segment","uuid":"s1","net":"GND","layer":"F.Cu",` +
+		`"at":{"x":0,"y":0},"end":{"x":5,"y":0},"fields":[{"name":"width","old":"(width 0.2)","new":"(width 0.25)"}],` +
+		`"copper":true}],"copper_changed":true}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
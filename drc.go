@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+// Violation is copper of two nets closer than the clearance.
+type Violation struct {
+	Items    [2]string `json:"items"` // e.g. segment GND
+	Layer    string    `json:"layer"`
+	Position diffPoint `json:"position"`
+	Distance float64   `json:"distance"` // Between the copper edges, negative when they overlap
+	Required float64   `json:"required"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("clearance %s %s: %s and %s are %s mm apart, need %s",
+		v.Layer, v.Position, v.Items[0], v.Items[1], lexer.FormatNumber(v.Distance), lexer.FormatNumber(v.Required))
+}
+
+// copperItem is a pad, track or via as a thick line, a stadium, on a set of
+// layers. Vias and round pads are lines of length 0.
+type copperItem struct {
+	name   string
+	net    int
+	shape  pcb.Segment
+	kind   string   // pad, segment or via
+	layers []string // Empty for every layer
+}
+
+func (c copperItem) onLayer(layer string) bool {
+	return len(c.layers) == 0 || slices.Contains(c.layers, layer)
+}
+
+// sharedLayer returns a layer both items are on.
+func (c copperItem) sharedLayer(other copperItem) (string, bool) {
+	switch {
+	case len(c.layers) == 0 && len(other.layers) == 0:
+		return "", true
+	case len(c.layers) == 0:
+		return other.layers[0], true
+	}
+	for _, layer := range c.layers {
+		if other.onLayer(layer) {
+			return layer, true
+		}
+	}
+	return "", false
+}
+
+// boardCopper returns the pads, tracks and vias of a board as copperItems.
+func boardCopper(board *pcb.Board) []copperItem {
+	names := netNames(board)
+	items := []copperItem{}
+	for _, pad := range board.Pads {
+		layers := []string{}
+		for _, layer := range pad.Layers {
+			if strings.HasSuffix(layer, ".Cu") {
+				layers = append(layers, layer)
+			}
+		}
+		if len(layers) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("pad %s.%s", pad.Reference, pad.Number)
+		items = append(items, copperItem{name: describeNet(name, names[pad.Net.Number]), net: pad.Net.Number, shape: pad.Copper(), layers: layers, kind: "pad"})
+	}
+	for _, seg := range board.Segments {
+		items = append(items, copperItem{name: describeNet("segment", names[seg.Net]), net: seg.Net, shape: seg, kind: "segment", layers: []string{seg.Layer}})
+	}
+	for _, via := range board.Vias {
+		shape := pcb.Segment{Start: via.Position, End: via.Position, Width: via.Size}
+		items = append(items, copperItem{name: describeNet("via", names[via.Net]), net: via.Net, shape: shape, kind: "via"})
+	}
+	return items
+}
+
+func netNames(board *pcb.Board) map[int]string {
+	names := make(map[int]string)
+	for _, net := range board.Nets {
+		names[net.Number] = net.Name
+	}
+	for _, pad := range board.Pads {
+		if pad.Net.Name != "" {
+			names[pad.Net.Number] = pad.Net.Name
+		}
+	}
+	return names
+}
+
+func describeNet(item, net string) string {
+	if net == "" {
+		return item + " <no net>"
+	}
+	return item + " " + net
+}
+
+// CheckClearance lists the pads, tracks and vias on a shared layer that
+// come closer than clearance to copper of another net. Pads are not checked
+// against each other, as their spacing is fixed by the footprint.
+func CheckClearance(board *pcb.Board, clearance float64) []Violation {
+	items := boardCopper(board)
+	violations := []Violation{}
+	for i, a := range items {
+		for _, b := range items[i+1:] {
+			if a.net == b.net || (a.kind == "pad" && b.kind == "pad") {
+				continue
+			}
+			layer, ok := a.sharedLayer(b)
+			if !ok {
+				continue
+			}
+			gap := a.shape.DistanceTo(b.shape) - (a.shape.Width+b.shape.Width)/2
+			if gap >= clearance-1e-5 {
+				continue
+			}
+			if layer == "" {
+				layer = "*.Cu"
+			}
+			at := closestPoint(a.shape, b.shape)
+			violations = append(violations, Violation{
+				Items:    [2]string{a.name, b.name},
+				Layer:    layer,
+				Position: diffPoint{X: at.X, Y: at.Y},
+				Distance: math.Round(gap*1e6) / 1e6,
+				Required: clearance,
+			})
+		}
+	}
+	return violations
+}
+
+// closestPoint returns the end of either centre line that is closest to the
+// other one, where a violation is reported.
+func closestPoint(a, b pcb.Segment) pcb.Position {
+	best, bestDistance := a.Start, math.Inf(1)
+	for _, p := range []pcb.Position{a.Start, a.End} {
+		if d := b.DistanceToPoint(p); d < bestDistance {
+			best, bestDistance = p, d
+		}
+	}
+	for _, p := range []pcb.Position{b.Start, b.End} {
+		if d := a.DistanceToPoint(p); d < bestDistance {
+			best, bestDistance = p, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const drcBase = `(kicad_pcb (version 20240108)
+	(net 0 "") (net 1 "GND") (net 2 "VCC")
+	(footprint "R_0805" (layer "F.Cu") (at 10 10)
+		(property "Reference" "R1" (at 0 -1.5) (layer "F.SilkS"))
+		(pad "1" smd rect (at -1 0) (size 1 1) (layers "F.Cu") (net 1 "GND"))
+		(pad "2" smd rect (at 1 0) (size 1 1) (layers "F.Cu") (net 2 "VCC")))
+`
+
+func TestCheckClearance(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    string // Appended to drcBase
+		expected []Violation
+	}{
+		{
+			"pads only",
+			"",
+			[]Violation{},
+		},
+		{
+			"track clear of the other net",
+			`(segment (start 9 10) (end 9 20) (width 0.2) (layer "F.Cu") (net 1))`,
+			[]Violation{},
+		},
+		{
+			"track too close to a pad",
+			`(segment (start 9 10) (end 10.25 10) (width 0.2) (layer "F.Cu") (net 1))`,
+			[]Violation{{
+				Items:    [2]string{"pad R1.2 VCC", "segment GND"},
+				Layer:    "F.Cu",
+				Position: diffPoint{X: 11, Y: 10},
+				Distance: 0.15,
+				Required: 0.2,
+			}},
+		},
+		{
+			"track on another layer than the pad",
+			`(segment (start 9 10) (end 10.25 10) (width 0.2) (layer "B.Cu") (net 1))`,
+			[]Violation{},
+		},
+		{
+			"via on a track of another net",
+			`(segment (start 9 10) (end 9 20) (width 0.2) (layer "F.Cu") (net 1))
+			(via (at 9 15) (size 0.6) (drill 0.3) (layers "F.Cu" "B.Cu") (net 2))`,
+			[]Violation{{
+				Items:    [2]string{"segment GND", "via VCC"},
+				Layer:    "F.Cu",
+				Position: diffPoint{X: 9, Y: 15},
+				Distance: -0.4,
+				Required: 0.2,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			board, err := ExprToPCB(parseBoardString(t, drcBase+tt.items+")"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			violations := CheckClearance(board, 0.2)
+
+			// Assert
+			if !reflect.DeepEqual(violations, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, violations)
+			}
+		})
+	}
+}
@@ -4,23 +4,130 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
+	"sort"
+	"strings"
 
 	"github.com/mackeper/lin_router/lexer"
 	"github.com/mackeper/lin_router/pcb"
 )
 
 type exprWithOffset struct {
-	expr     lexer.Expr
-	offset   pcb.Position
-	rotation float64
+	expr      lexer.Expr
+	offset    pcb.Position
+	rotation  float64
+	reference string // Reference designator of the enclosing footprint
+	footprint string // UUID of the enclosing footprint
 }
 
+// ExprToPCB reads the nets, footprints, pads, tracks, vias and outline of a
+// board in any supported file version, see BoardVersion. Arcs and zones are
+// not read.
 func ExprToPCB(expr lexer.Expr) (*pcb.Board, error) {
+	return exprToPCB(expr, nil)
+}
+
+// ExprToPCBRecover reads a board like ExprToPCB but leaves out the top-level
+// items it cannot read, such as a footprint with a malformed pad, instead of
+// failing. They are returned as diagnostics in file order.
+func ExprToPCBRecover(expr lexer.Expr) (*pcb.Board, []*lexer.ParseError) {
+	var diagnostics []*lexer.ParseError
+	board, _ := exprToPCB(expr, func(item lexer.Expr, err error) {
+		diagnostics = append(diagnostics, &lexer.ParseError{
+			Pos:  item.Pos,
+			Path: []string{expr.Identifier, item.Identifier},
+			Err:  err,
+		})
+	})
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Pos.Offset < diagnostics[j].Pos.Offset
+	})
+	return board, diagnostics
+}
+
+// exprToPCB reads a board, passing the top-level items it cannot read to
+// skip, or failing on the first one when skip is nil.
+func exprToPCB(expr lexer.Expr, skip func(item lexer.Expr, err error)) (*pcb.Board, error) {
 	board := pcb.NewBoard()
+	d := newDecoder(expr)
+	slog.Debug("Reading board", "version", d.version)
+
+	for _, val := range expr.Values {
+		v, ok := val.(lexer.ExprValue)
+		if !ok {
+			continue
+		}
+		var err error
+		switch item := v.Value; item.Type {
+		case lexer.ExprNet:
+			net := pcb.Net{}
+			if err = lexer.Unmarshal(item, &net); err != nil {
+				err = fmt.Errorf("failed to parse net: %w", err)
+				break
+			}
+			board.Nets = append(board.Nets, net)
+		case lexer.ExprSegment:
+			var segment pcb.Segment
+			if segment, err = d.segment(item); err != nil {
+				break
+			}
+			board.Segments = append(board.Segments, segment)
+		case lexer.ExprGrLine, lexer.ExprGrArc, lexer.ExprGrRect, lexer.ExprGrCircle, lexer.ExprGrPoly:
+			if childText(item, "layer") != "Edge.Cuts" {
+				continue
+			}
+			var shape pcb.OutlineShape
+			if shape, err = parseOutlineExpr(item); err != nil {
+				break
+			}
+			board.Outline = append(board.Outline, shape)
+		}
+		if err != nil {
+			if skip == nil {
+				return nil, err
+			}
+			skip(v.Value, err)
+		}
+	}
 
-	stack := []exprWithOffset{{expr: expr, offset: pcb.Position{X: 0, Y: 0}, rotation: 0}}
 	pads := []pcb.Pad{}
 	vias := []pcb.Via{}
+	// Items are read last first, which is the order pads have always been
+	// read in
+	for i := len(expr.Values) - 1; i >= 0; i-- {
+		v, ok := expr.Values[i].(lexer.ExprValue)
+		if !ok {
+			continue
+		}
+		copper, err := readCopper(d, v.Value)
+		if err != nil {
+			if skip == nil {
+				return nil, err
+			}
+			skip(v.Value, err)
+			continue
+		}
+		board.Footprints = append(board.Footprints, copper.footprints...)
+		pads = append(pads, copper.pads...)
+		vias = append(vias, copper.vias...)
+	}
+
+	board.Pads = pads
+	board.Vias = vias
+	return board, nil
+}
+
+// itemCopper is what readCopper finds in one top-level item of a board.
+type itemCopper struct {
+	footprints []pcb.Footprint
+	pads       []pcb.Pad
+	vias       []pcb.Via
+}
+
+// readCopper reads the footprints, pads and vias in item, with pads placed by
+// their footprint's position and rotation.
+func readCopper(d *decoder, item lexer.Expr) (itemCopper, error) {
+	copper := itemCopper{}
+	stack := []exprWithOffset{{expr: item, offset: pcb.Position{X: 0, Y: 0}, rotation: 0}}
 	for len(stack) > 0 {
 		current := stack[len(stack)-1]
 		stack = stack[:len(stack)-1]
@@ -28,80 +135,91 @@ func ExprToPCB(expr lexer.Expr) (*pcb.Board, error) {
 		slog.Debug("Processing expr", "type", current.expr.Type)
 		if current.expr.Type == lexer.ExprPad {
 			slog.Debug("Found pad expression")
-			pad, err := parsePadExpr(current.expr, current.offset, current.rotation)
+			pad, err := parsePadExpr(d, current.expr, current.offset, current.rotation)
 			if err != nil {
-				return nil, fmt.Errorf("failed to parse pad: %w", err)
+				return copper, fmt.Errorf("failed to parse pad: %w", err)
 			}
-			pads = append(pads, pad)
+			pad.Reference = current.reference
+			pad.FootprintUUID = current.footprint
+			copper.pads = append(copper.pads, pad)
 		} else if current.expr.Type == lexer.ExprVia {
 			slog.Debug("Found via expression")
-			via, err := parseViaExpr(current.expr)
+			via, err := d.via(current.expr)
 			if err != nil {
-				return nil, err
+				return copper, err
 			}
-			vias = append(vias, via)
+			copper.vias = append(copper.vias, via)
 		} else {
 			// Check if this is a footprint and extract its position and rotation
 			offset := current.offset
 			rotation := current.rotation
+			reference := current.reference
+			footprintUUID := current.footprint
 			if current.expr.Type == lexer.ExprFootprint {
 				footprintPos, footprintRot, err := extractAtPositionAndRotation(current.expr)
 				if err != nil {
-					return nil, fmt.Errorf("footprint missing position: %w", err)
+					return copper, fmt.Errorf("footprint missing position: %w", err)
 				}
 				offset = footprintPos
 				rotation = footprintRot
+				reference = d.footprintReference(current.expr)
+				footprintUUID = itemUUID(current.expr)
+				footprint := pcb.Footprint{Reference: reference, Position: offset, Rotation: rotation, UUID: itemUUID(current.expr)}
+				footprint.Name, _ = current.expr.TextAt(0)
+				footprint.Layer = childText(current.expr, "layer")
+				copper.footprints = append(copper.footprints, footprint)
 				slog.Debug("Found footprint", "offset_x", offset.X, "offset_y", offset.Y, "rotation", rotation)
 			}
 
 			for _, val := range current.expr.Values {
 				if v, ok := val.(lexer.ExprValue); ok {
-					stack = append(stack, exprWithOffset{expr: v.Value, offset: offset, rotation: rotation})
+					stack = append(stack, exprWithOffset{expr: v.Value, offset: offset, rotation: rotation, reference: reference, footprint: footprintUUID})
 				}
 			}
 		}
 	}
-
-	board.Pads = pads
-	board.Vias = vias
-	return board, nil
+	return copper, nil
 }
 
 func extractAtPositionAndRotation(expr lexer.Expr) (pcb.Position, float64, error) {
-	for _, val := range expr.Values {
-		if v, ok := val.(lexer.ExprValue); ok {
-			if v.Value.Type == lexer.ExprAt {
-				if len(v.Value.Values) < 2 {
-					return pcb.Position{}, 0, fmt.Errorf("at expression requires at least 2 values")
-				}
-				xVal, ok := v.Value.Values[0].(lexer.NumberValue)
-				if !ok {
-					return pcb.Position{}, 0, fmt.Errorf("expected NumberValue for X coordinate")
-				}
-				yVal, ok := v.Value.Values[1].(lexer.NumberValue)
-				if !ok {
-					return pcb.Position{}, 0, fmt.Errorf("expected NumberValue for Y coordinate")
-				}
+	at, err := expr.Child("at")
+	if err != nil {
+		return pcb.Position{}, 0, err
+	}
+	position, err := parseAtPosition(at)
+	if err != nil {
+		return pcb.Position{}, 0, err
+	}
+	// The rotation is optional
+	rotation, _ := at.NumberAt(2)
+	return position, rotation, nil
+}
 
-				rotation := 0.0
-				if len(v.Value.Values) >= 3 {
-					if rotVal, ok := v.Value.Values[2].(lexer.NumberValue); ok {
-						rotation = rotVal.Value
-					}
-				}
+func parseAtPosition(at lexer.Expr) (pcb.Position, error) {
+	x, err := at.NumberAt(0)
+	if err != nil {
+		return pcb.Position{}, err
+	}
+	y, err := at.NumberAt(1)
+	if err != nil {
+		return pcb.Position{}, err
+	}
+	return pcb.Position{X: x, Y: y}, nil
+}
 
-				return pcb.Position{
-					X: xVal.Value,
-					Y: yVal.Value,
-				}, rotation, nil
-			}
-		}
+// copperLayers expands the *.Cu wildcard to the layers the routers use.
+func copperLayers(layer string) []string {
+	if layer == "*.Cu" {
+		return []string{"F.Cu", "B.Cu"}
 	}
-	return pcb.Position{}, 0, fmt.Errorf("no at position found")
+	return []string{layer}
 }
 
-func parsePadExpr(expr lexer.Expr, offset pcb.Position, rotation float64) (pcb.Pad, error) {
+func parsePadExpr(d *decoder, expr lexer.Expr, offset pcb.Position, rotation float64) (pcb.Pad, error) {
 	pad := pcb.Pad{}
+	pad.Number, _ = expr.TextAt(0)
+	pad.Type, _ = expr.IdentifierAt(1)
+	pad.Shape, _ = expr.IdentifierAt(2)
 
 	for _, val := range expr.Values {
 		slog.Debug("Parsing pad sub-expression", "val", val)
@@ -110,75 +228,52 @@ func parsePadExpr(expr lexer.Expr, offset pcb.Position, rotation float64) (pcb.P
 			slog.Debug("Pad sub-expr type", "type", subExpr.Type)
 			switch subExpr.Type {
 			case lexer.ExprAt:
-				if len(subExpr.Values) < 2 {
-					return pad, fmt.Errorf("at expression requires 2 values")
-				}
-				xVal, ok := subExpr.Values[0].(lexer.NumberValue)
-				if !ok {
-					return pad, fmt.Errorf("expected NumberValue for X coordinate")
-				}
-				yVal, ok := subExpr.Values[1].(lexer.NumberValue)
-				if !ok {
-					return pad, fmt.Errorf("expected NumberValue for Y coordinate")
+				relative, err := parseAtPosition(subExpr)
+				if err != nil {
+					return pad, err
 				}
-				relX := xVal.Value
-				relY := yVal.Value
 
 				// Apply rotation transformation
-				rotatedX, rotatedY := rotatePoint(relX, relY, rotation)
+				rotatedX, rotatedY := rotatePoint(relative.X, relative.Y, rotation)
 
 				pad.Position = pcb.Position{
 					X: rotatedX + offset.X,
 					Y: rotatedY + offset.Y,
 				}
-				slog.Debug("Pad position", "rel_x", relX, "rel_y", relY, "rotation", rotation, "abs_x", pad.Position.X, "abs_y", pad.Position.Y)
-			case lexer.ExprNet:
-				if len(subExpr.Values) < 2 {
-					return pad, fmt.Errorf("net expression requires 2 values")
-				}
-				numVal, ok := subExpr.Values[0].(lexer.NumberValue)
-				if !ok {
-					return pad, fmt.Errorf("expected NumberValue for net number")
+				slog.Debug("Pad position", "rel_x", relative.X, "rel_y", relative.Y, "rotation", rotation, "abs_x", pad.Position.X, "abs_y", pad.Position.Y)
+				// Unlike the position, the angle already includes the footprint's
+				pad.Rotation, _ = subExpr.NumberAt(2)
+			case lexer.ExprSize:
+				if err := lexer.Unmarshal(subExpr, &pad.Size); err != nil {
+					return pad, err
 				}
-				nameVal, ok := subExpr.Values[1].(lexer.StringValue)
-				if !ok {
-					return pad, fmt.Errorf("expected StringValue for net name")
+			case lexer.ExprDrill:
+				// (drill 0.8) or (drill oval 0.8 1.2), whose width is used
+				for i := range subExpr.Values {
+					if diameter, err := subExpr.NumberAt(i); err == nil {
+						pad.Drill = diameter
+						break
+					}
 				}
-				pad.Net = pcb.Net{
-					Number: int(numVal.Value),
-					Name:   nameVal.Value,
+			case lexer.ExprNet:
+				net, err := d.padNet(subExpr)
+				if err != nil {
+					return pad, err
 				}
+				pad.Net = net
 			case lexer.ExprLayer:
-				if len(subExpr.Values) < 1 {
-					return pad, fmt.Errorf("layer expression requires 1 value")
-				}
-				layerVal, ok := subExpr.Values[0].(lexer.StringValue)
-				if !ok {
-					return pad, fmt.Errorf("expected StringValue for layer")
-				}
-				layer := layerVal.Value
-				if layer == "*.Cu" {
-					pad.Layers = []string{"F.Cu", "B.Cu"}
-				} else {
-					pad.Layers = []string{layer}
+				layer, err := subExpr.TextAt(0)
+				if err != nil {
+					return pad, err
 				}
+				pad.Layers = copperLayers(layer)
 			case lexer.ExprLayers:
-				for _, layerVal := range subExpr.Values {
-					var layerStr string
-					switch v := layerVal.(type) {
-					case lexer.IdentifierValue:
-						layerStr = v.Value
-					case lexer.StringValue:
-						layerStr = v.Value
-					default:
+				for i := range subExpr.Values {
+					layer, err := subExpr.TextAt(i)
+					if err != nil {
 						continue
 					}
-
-					if layerStr == "*.Cu" {
-						pad.Layers = append(pad.Layers, "F.Cu", "B.Cu")
-					} else {
-						pad.Layers = append(pad.Layers, layerStr)
-					}
+					pad.Layers = append(pad.Layers, copperLayers(layer)...)
 				}
 			}
 		}
@@ -186,32 +281,91 @@ func parsePadExpr(expr lexer.Expr, offset pcb.Position, rotation float64) (pcb.P
 	return pad, nil
 }
 
-func parseViaExpr(expr lexer.Expr) (pcb.Via, error) {
-	via := pcb.Via{}
+// parseOutlineExpr reads a gr_line, gr_arc, gr_rect, gr_circle or gr_poly.
+// KiCad 5 wrote arcs as (start centre) (end first point) (angle degrees),
+// which is converted to start, mid and end points.
+func parseOutlineExpr(expr lexer.Expr) (pcb.OutlineShape, error) {
+	shape := pcb.OutlineShape{Kind: strings.TrimPrefix(expr.Identifier, "gr_")}
+	if width, ok := expr.First("stroke/width"); ok {
+		shape.Width, _ = width.NumberAt(0)
+	} else if width, ok := expr.First("width"); ok {
+		shape.Width, _ = width.NumberAt(0)
+	}
+	point := func(e lexer.Expr) error {
+		position, err := parseAtPosition(e)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", expr.Identifier, err)
+		}
+		shape.Points = append(shape.Points, position)
+		return nil
+	}
 
-	for _, val := range expr.Values {
-		slog.Debug("Parsing via sub-expression", "val", val)
-		switch v := val.(type) {
-		case lexer.ExprValue:
-			subExpr := v.Value
-			switch subExpr.Type {
-			case lexer.ExprAt:
-				via.Position = pcb.Position{
-					X: subExpr.Values[0].(lexer.NumberValue).Value,
-					Y: subExpr.Values[1].(lexer.NumberValue).Value,
-				}
-			case lexer.ExprNet:
-				via.Net = int(subExpr.Values[0].(lexer.NumberValue).Value)
-			case lexer.ExprLayers:
-				for _, layerVal := range subExpr.Values {
-					if strVal, ok := layerVal.(lexer.StringValue); ok {
-						via.Layers = append(via.Layers, strVal.Value)
-					}
-				}
-			case lexer.ExprUUID:
-				via.UUID = subExpr.Values[0].(lexer.StringValue).Value
+	if shape.Kind == "poly" {
+		corners, _ := expr.FindAll("pts/xy")
+		for _, xy := range corners {
+			if err := point(xy); err != nil {
+				return shape, err
 			}
 		}
+		return shape, nil
+	}
+	identifiers := []string{"start", "end"}
+	switch {
+	case shape.Kind == "circle":
+		identifiers = []string{"center", "end"}
+	case shape.Kind == "arc" && len(expr.Children("mid")) > 0:
+		identifiers = []string{"start", "mid", "end"}
+	}
+	for _, identifier := range identifiers {
+		child, err := expr.Child(identifier)
+		if err != nil {
+			return shape, fmt.Errorf("failed to parse %s: %w", expr.Identifier, err)
+		}
+		if err := point(child); err != nil {
+			return shape, err
+		}
+	}
+	if shape.Kind == "arc" && len(shape.Points) == 2 {
+		angle, err := childNumber(expr, "angle")
+		if err != nil {
+			return shape, fmt.Errorf("failed to parse %s: %w", expr.Identifier, err)
+		}
+		centre, start := shape.Points[0], shape.Points[1]
+		shape.Points = []pcb.Position{start, rotateAround(start, centre, angle/2), rotateAround(start, centre, angle)}
+	}
+	return shape, nil
+}
+
+// childText returns value 0 of the first child of e named identifier, or ""
+// when there is none.
+func childText(e lexer.Expr, identifier string) string {
+	child, err := e.Child(identifier)
+	if err != nil {
+		return ""
+	}
+	text, _ := child.TextAt(0)
+	return text
+}
+
+func childNumber(e lexer.Expr, identifier string) (float64, error) {
+	child, err := e.Child(identifier)
+	if err != nil {
+		return 0, err
+	}
+	return child.NumberAt(0)
+}
+
+// rotateAround turns p about centre by degrees, clockwise on the board as
+// KiCad 5 arcs do.
+func rotateAround(p, centre pcb.Position, degrees float64) pcb.Position {
+	x, y := rotatePoint(p.X-centre.X, p.Y-centre.Y, -degrees)
+	return pcb.Position{X: centre.X + x, Y: centre.Y + y}
+}
+
+func parseViaExpr(expr lexer.Expr) (pcb.Via, error) {
+	via := pcb.Via{}
+	if err := lexer.Unmarshal(expr, &via); err != nil {
+		return via, fmt.Errorf("failed to parse via: %w", err)
 	}
 	return via, nil
 }
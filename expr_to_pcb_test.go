@@ -1,6 +1,8 @@
 package main
 
 import (
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/mackeper/lin_router/lexer"
@@ -315,3 +317,111 @@ func TestExprToPCB_FootprintMissingPosition(t *testing.T) {
 		t.Errorf("Expected nil board on error, got %v", board)
 	}
 }
+
+func TestExprToPCB_BoardModel(t *testing.T) {
+	// Arrange
+	expr := parseBoardString(t, `(kicad_pcb (version 20240108)
+	(net 0 "") (net 1 "GND")
+	(footprint "Resistor_SMD:R_0805" (layer "F.Cu") (at 10 10 90) (uuid "f1")
+		(property "Reference" "R1" (at 0 -1.5) (layer "F.SilkS"))
+		(pad "1" thru_hole oval (at 1 0 90) (size 1 1.5) (drill oval 0.6 0.8) (layers "*.Cu") (net 1 "GND")))
+	(segment (start 0 0) (end 5 0) (width 0.2) (layer "F.Cu") (net 1) (uuid "s1"))
+	(gr_line (start 0 0) (end 20 0) (stroke (width 0.1) (type default)) (layer "Edge.Cuts"))
+	(gr_arc (start 20 0) (mid 22 2) (end 20 4) (stroke (width 0.1) (type default)) (layer "Edge.Cuts"))
+	(gr_poly (pts (xy 0 0) (xy 1 0) (xy 1 1)) (stroke (width 0.05) (type default)) (layer "Edge.Cuts"))
+	(gr_line (start 0 0) (end 5 5) (stroke (width 0.1) (type default)) (layer "F.SilkS"))
+)`)
+
+	// Act
+	board, err := ExprToPCB(expr)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(board.Nets) != 2 || board.Nets[1] != (pcb.Net{Number: 1, Name: "GND"}) {
+		t.Errorf("Expected nets 0 and GND, got %v", board.Nets)
+	}
+	wantFootprint := pcb.Footprint{Reference: "R1", Name: "Resistor_SMD:R_0805", Layer: "F.Cu", Position: pcb.Position{X: 10, Y: 10}, Rotation: 90, UUID: "f1"}
+	if len(board.Footprints) != 1 || board.Footprints[0] != wantFootprint {
+		t.Errorf("Expected footprint %+v, got %+v", wantFootprint, board.Footprints)
+	}
+	validateBoard(t, board, 1)
+	pad := board.Pads[0]
+	validatePad(t, pad, 10, 9, 1, "GND", "F.Cu")
+	if pad.Number != "1" || pad.Type != "thru_hole" || pad.Shape != "oval" || pad.Size != (pcb.Size{Width: 1, Height: 1.5}) || pad.Rotation != 90 || pad.Drill != 0.6 {
+		t.Errorf("Expected pad 1 thru_hole oval 1x1.5 at 90 degrees with drill 0.6, got %+v", pad)
+	}
+	wantSegment := pcb.Segment{Start: pcb.Position{X: 0, Y: 0}, End: pcb.Position{X: 5, Y: 0}, Width: 0.2, Layer: "F.Cu", Net: 1, UUID: "s1"}
+	if len(board.Segments) != 1 || board.Segments[0] != wantSegment {
+		t.Errorf("Expected segment %+v, got %+v", wantSegment, board.Segments)
+	}
+	kinds := []string{}
+	for _, shape := range board.Outline {
+		kinds = append(kinds, shape.Kind)
+	}
+	if strings.Join(kinds, " ") != "line arc poly" {
+		t.Errorf("Expected outline line arc poly, got %v", kinds)
+	}
+	if len(board.Outline) == 3 && (len(board.Outline[1].Points) != 3 || len(board.Outline[2].Points) != 3 || board.Outline[2].Width != 0.05) {
+		t.Errorf("Expected an arc of 3 points and a triangle of width 0.05, got %+v", board.Outline)
+	}
+}
+
+func TestExprToPCB_LegacyArc(t *testing.T) {
+	// Arrange
+	expr := parseBoardString(t, `(kicad_pcb (version 20171130)
+  (gr_arc (start 0 0) (end 10 0) (angle 90) (layer Edge.Cuts) (width 0.15))
+)`)
+
+	// Act
+	board, err := ExprToPCB(expr)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(board.Outline) != 1 {
+		t.Fatalf("Expected 1 outline shape, got %d", len(board.Outline))
+	}
+	arc := board.Outline[0]
+	want := []pcb.Position{{X: 10, Y: 0}, {X: 10 * math.Sqrt2 / 2, Y: 10 * math.Sqrt2 / 2}, {X: 0, Y: 10}}
+	for i, p := range want {
+		if i >= len(arc.Points) || arc.Points[i].Distance(p) > 1e-9 {
+			t.Errorf("Expected arc points %v, got %v", want, arc.Points)
+			break
+		}
+	}
+	if arc.Width != 0.15 {
+		t.Errorf("Expected width 0.15, got %v", arc.Width)
+	}
+}
+
+func TestExprToPCBRecover_SkipsUnreadableItems(t *testing.T) {
+	// Arrange
+	expr := parseBoardString(t, ratsnestBase+`(segment (start 0 0) (end x 1) (width 0.2) (layer "F.Cu") (net 1))
+	`+badFootprint+`)`)
+
+	// Act
+	board, diagnostics := ExprToPCBRecover(expr)
+
+	// Assert
+	expected := []string{
+		"11:1: failed to parse segment: segment: end: cannot store an identifier x in float64 in kicad_pcb > segment",
+		"12:2: failed to parse pad: size: cannot store an identifier x in float64 in kicad_pcb > footprint",
+	}
+	got := []string{}
+	for _, diagnostic := range diagnostics {
+		got = append(got, diagnostic.Error())
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected diagnostics %q, got %q", expected, got)
+	}
+	if len(board.Footprints) != 2 || len(board.Pads) != 4 || len(board.Segments) != 0 {
+		t.Errorf("Expected the 2 readable footprints with 4 pads and no tracks, got %d, %d and %d",
+			len(board.Footprints), len(board.Pads), len(board.Segments))
+	}
+	if _, err := ExprToPCB(expr); err == nil {
+		t.Errorf("Expected ExprToPCB to fail on the same board")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mackeper/lin_router/lexer"
+)
+
+// maxSeedSize leaves out the largest boards in test_data, which slow the
+// fuzzer down to a few executions per second without adding coverage.
+const maxSeedSize = 64 << 10
+
+func FuzzExprToPCB(f *testing.F) {
+	paths, err := filepath.Glob("test_data/*.kicad_pcb")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		if len(data) <= maxSeedSize {
+			f.Add(string(data))
+		}
+	}
+	for _, input := range []string{
+		"(kicad_pcb (via))",
+		"(kicad_pcb (via (at 1)))",
+		"(kicad_pcb (footprint \"R\" (pad \"1\" smd (at 1 2))))",
+		"(kicad_pcb (footprint \"R\" (at 0 0) (pad \"1\" smd (at x 2) (net GND))))",
+		"(kicad_pcb (version 20241229) (pad \"1\" smd (at 0 0) (net \"GND\")))",
+		"(kicad_pcb (version 20171130) (module R (at 0 0) (fp_text reference) (pad 1 smd (at 0 0) (layer F.Cu))))",
+	} {
+		f.Add(input)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			return
+		}
+		expr, err := lexer.Parse(tokens)
+		if err != nil {
+			return
+		}
+		// Malformed boards must give an error, not a panic
+		ExprToPCB(expr)
+	})
+}
@@ -1,35 +1,155 @@
 package main
 
 import (
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 
 	"github.com/mackeper/lin_router/lexer"
 )
 
-func readToString(path string) (string, error) {
-	contents, err := os.ReadFile(path)
+func ParsePcbFile(path string) (lexer.Expr, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return lexer.Expr{}, fmt.Errorf("failed to read PCB file: %w", err)
+	}
+	defer file.Close()
+
+	tokens, err := lexer.TokenizeReader(file)
+	if err != nil {
+		return lexer.Expr{}, fmt.Errorf("failed to tokenize PCB file: %w", err)
+	}
+
+	expr, err := lexer.Parse(tokens)
 	if err != nil {
-		return "", err
+		return lexer.Expr{}, fmt.Errorf("failed to parse PCB file: %w", err)
 	}
-	return string(contents), nil
+
+	return expr, nil
 }
 
-func ParsePcbFile(path string) (lexer.Expr, error) {
-	data, err := readToString(path)
+// ParsePcbFileRecover reads a board like ParsePcbFile but reads past
+// malformed expressions, see lexer.ParseRecover. The problems found are
+// returned in file order; err is only set when the file cannot be read.
+func ParsePcbFileRecover(path string) (lexer.Expr, []*lexer.ParseError, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return lexer.Expr{}, errors.New("failed to read PCB file: " + err.Error())
+		return lexer.Expr{}, nil, fmt.Errorf("failed to read PCB file: %w", err)
 	}
+	defer file.Close()
 
-	tokens, err := lexer.Tokenize(data)
+	var diagnostics []*lexer.ParseError
+	tokens, err := lexer.TokenizeReader(file)
 	if err != nil {
-		return lexer.Expr{}, errors.New("failed to tokenize PCB file: " + err.Error())
+		var parseErr *lexer.ParseError
+		if !errors.As(err, &parseErr) {
+			return lexer.Expr{}, nil, fmt.Errorf("failed to read PCB file: %w", err)
+		}
+		// Go on with the tokens read before the error
+		diagnostics = append(diagnostics, parseErr)
 	}
 
-	expr, err := lexer.Parse(tokens)
+	expr, parseDiagnostics := lexer.ParseRecover(tokens)
+	diagnostics = append(parseDiagnostics, diagnostics...)
+	return expr, diagnostics, nil
+}
+
+// FormatPcbFile rewrites a board file in place in pcbnew's formatting,
+// keeping its line endings and final line break.
+func FormatPcbFile(path string) error {
+	expr, err := ParsePcbFile(path)
 	if err != nil {
-		return lexer.Expr{}, errors.New("failed to parse PCB file: " + err.Error())
+		return err
 	}
 
-	return expr, nil
+	return WritePcbFile(path, []byte(lexer.FormatFile(expr)), WriteOptions{})
+}
+
+// FileHash is the SHA-256 of a file's contents, to notice that it changed.
+type FileHash [sha256.Size]byte
+
+// HashFile returns the hash of the file at path. Take it before parsing the
+// file, so that any change made after it was read shows.
+func HashFile(path string) (FileHash, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FileHash{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+// ErrFileChanged is returned by WritePcbFile when the file is no longer the
+// one that was read.
+var ErrFileChanged = errors.New("file changed since it was read")
+
+// WriteOptions control how WritePcbFile replaces an existing file.
+type WriteOptions struct {
+	Backup   bool      // Keep the previous contents as path + ".bak"
+	Expected *FileHash // Refuse to write unless the file still has this hash
+}
+
+// WritePcbFile replaces the file at path with data atomically: data is
+// written to a temporary file in the same directory, which is then renamed
+// over path. Readers, and path itself after a failed write, see either the
+// old or the new board, never part of one. A new file gets mode 0644, an
+// existing one keeps its mode.
+func WritePcbFile(path string, data []byte, opts WriteOptions) error {
+	mode := fs.FileMode(0o644)
+	previous, err := os.ReadFile(path)
+	exists := err == nil
+	switch {
+	case exists:
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		mode = info.Mode().Perm()
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	if opts.Expected != nil && (!exists || sha256.Sum256(previous) != *opts.Expected) {
+		return fmt.Errorf("%s: %w", path, ErrFileChanged)
+	}
+	if opts.Backup && exists {
+		if err := writeFileAtomic(path+".bak", previous, mode); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := writeFileAtomic(path, data, mode); err != nil {
+		return fmt.Errorf("failed to write PCB file: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path once it is complete and synced.
+func writeFileAtomic(path string, data []byte, mode fs.FileMode) (err error) {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	if _, err := temp.Write(data); err != nil {
+		return err
+	}
+	if err := temp.Chmod(mode); err != nil {
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
 }
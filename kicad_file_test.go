@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mackeper/lin_router/lexer"
+)
+
+func TestFormatPcbFile_MatchesPcbnew(t *testing.T) {
+	// Arrange
+	original, err := os.ReadFile("test_data/main.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "main.kicad_pcb")
+	if err := os.WriteFile(path, original, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err = FormatPcbFile(path)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	formatted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != string(original) {
+		t.Errorf("Expected pcbnew's formatting and line endings to be reproduced exactly")
+	}
+}
+
+func TestFormatPcbFile_ReformatsGeneratorOutput(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "board.kicad_pcb")
+	input := "(kicad_pcb (version 20240108)\n  (segment (start 0.0254 1.500000) (end 2 -0.000000) (layer \"F.Cu\")))"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err := FormatPcbFile(path)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	formatted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "(kicad_pcb\n\t(version 20240108)\n\t(segment\n\t\t(start 0.0254 1.5)\n\t\t(end 2 0)\n\t\t(layer \"F.Cu\")\n\t)\n)\n"
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q, Actual: %q", expected, string(formatted))
+	}
+}
+
+func TestParsePcbFile_ReportsParseErrorPosition(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "truncated.kicad_pcb")
+	input := "(kicad_pcb\n\t(footprint \"R1\"\n\t\t(pad \"1\" smd)\n"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err := ParsePcbFile(path)
+
+	// Assert
+	var parseErr *lexer.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a *lexer.ParseError, got %v", err)
+	}
+	if parseErr.Pos.Line != 4 || parseErr.Pos.Column != 1 {
+		t.Errorf("Expected error at 4:1, got %s", parseErr.Pos)
+	}
+	expectedPath := "kicad_pcb > footprint"
+	if strings.Join(parseErr.Path, " > ") != expectedPath {
+		t.Errorf("Expected path %q, got %q", expectedPath, strings.Join(parseErr.Path, " > "))
+	}
+}
+
+func TestParsePcbFileRecover_ContinuesPastBadExpressions(t *testing.T) {
+	// Arrange
+	original, err := os.ReadFile("test_data/small_real.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := strings.Replace(string(original), "(pad ", "(pad () ", 2)
+	path := filepath.Join(t.TempDir(), "corrupted.kicad_pcb")
+	if err := os.WriteFile(path, []byte(corrupted), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	expr, diagnostics, err := ParsePcbFileRecover(path)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if expr.String() != corrupted {
+		t.Errorf("Expected the corrupted file to be written back unchanged")
+	}
+	board, err := ExprToPCB(expr)
+	if err != nil {
+		t.Fatalf("Expected the rest of the board to convert, got %v", err)
+	}
+	if len(board.Pads) == 0 {
+		t.Errorf("Expected pads to be read")
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected int
+	}{
+		{"valid", "(kicad_pcb (version 20240108))", exitOK},
+		{"bad expression", "(kicad_pcb (version 20240108) ())", exitFindings},
+		{"unterminated string first", `"abc`, exitFindings},
+		{"empty", "", exitFindings},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			path := filepath.Join(t.TempDir(), "board.kicad_pcb")
+			if err := os.WriteFile(path, []byte(tt.contents), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			code := run([]string{"check", path})
+
+			// Assert
+			if code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestRunCheck_GoesOnAfterUnreadableFile(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.kicad_pcb")
+	bad := filepath.Join(dir, "bad.kicad_pcb")
+	if err := os.WriteFile(bad, []byte("(kicad_pcb ())"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	var code int
+	stderr := captureStderr(t, func() {
+		code = run([]string{"check", missing, bad})
+	})
+
+	// Assert
+	if code != exitError {
+		t.Errorf("Expected exit code %d, got %d", exitError, code)
+	}
+	if !strings.Contains(stderr, missing) || !strings.Contains(stderr, bad+":1:13: error:") {
+		t.Errorf("Expected both files to be reported, got %q", stderr)
+	}
+}
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-output
+}
+
+func TestWritePcbFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   string // Contents of the file before writing, none when empty
+		opts       func(path string) WriteOptions
+		wantErr    error
+		wantFile   string
+		wantBackup string // Contents of path.bak, none when empty
+	}{
+		{
+			name:     "new file",
+			opts:     func(string) WriteOptions { return WriteOptions{} },
+			wantFile: "(kicad_pcb new)",
+		},
+		{
+			name:     "replace",
+			existing: "(kicad_pcb old)",
+			opts:     func(string) WriteOptions { return WriteOptions{} },
+			wantFile: "(kicad_pcb new)",
+		},
+		{
+			name:       "replace with backup",
+			existing:   "(kicad_pcb old)",
+			opts:       func(string) WriteOptions { return WriteOptions{Backup: true} },
+			wantFile:   "(kicad_pcb new)",
+			wantBackup: "(kicad_pcb old)",
+		},
+		{
+			name:     "unchanged since read",
+			existing: "(kicad_pcb old)",
+			opts: func(path string) WriteOptions {
+				hash, err := HashFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return WriteOptions{Expected: &hash}
+			},
+			wantFile: "(kicad_pcb new)",
+		},
+		{
+			name:     "changed since read",
+			existing: "(kicad_pcb old)",
+			opts: func(path string) WriteOptions {
+				hash := FileHash{}
+				return WriteOptions{Backup: true, Expected: &hash}
+			},
+			wantErr:  ErrFileChanged,
+			wantFile: "(kicad_pcb old)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			dir := t.TempDir()
+			path := filepath.Join(dir, "board.kicad_pcb")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			opts := tt.opts(path)
+
+			// Act
+			err := WritePcbFile(path, []byte("(kicad_pcb new)"), opts)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || string(data) != tt.wantFile {
+				t.Errorf("Expected the file to hold %q, got %q (%v)", tt.wantFile, data, err)
+			}
+			backup, err := os.ReadFile(path + ".bak")
+			if string(backup) != tt.wantBackup || (tt.wantBackup == "" && !errors.Is(err, os.ErrNotExist)) {
+				t.Errorf("Expected backup %q, got %q (%v)", tt.wantBackup, backup, err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantMode := os.FileMode(0o644)
+			if tt.existing != "" {
+				wantMode = 0o600
+			}
+			if info.Mode().Perm() != wantMode {
+				t.Errorf("Expected mode %v, got %v", wantMode, info.Mode().Perm())
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				if strings.HasSuffix(entry.Name(), ".tmp") {
+					t.Errorf("Expected no temporary files to be left, found %s", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestWritePcbFile_MissingDirectory(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "missing", "board.kicad_pcb")
+
+	// Act
+	err := WritePcbFile(path, []byte("(kicad_pcb)"), WriteOptions{})
+
+	// Assert
+	if err == nil {
+		t.Error("Expected an error writing into a missing directory")
+	}
+}
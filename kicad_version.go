@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+// Board file versions, the (version N) header written by each KiCad release.
+// Format changes are keyed to the release that introduced them.
+const (
+	kicad5Version = 20171130
+	kicad6Version = 20211014 // module becomes footprint, names are quoted
+	kicad7Version = 20221018
+	kicad8Version = 20240108 // uuid replaces tstamp, fp_text reference becomes a property
+	kicad9Version = 20241229 // pads may name their net without its number
+
+	// defaultVersion is assumed for trees without a (version N) header, such
+	// as ones built in code. It is the format Marshal writes.
+	defaultVersion = kicad8Version
+)
+
+var releaseVersions = map[int]int{
+	5: kicad5Version,
+	6: kicad6Version,
+	7: kicad7Version,
+	8: kicad8Version,
+	9: kicad9Version,
+}
+
+// BoardVersion returns the (version N) header of a board, or defaultVersion
+// when there is none.
+func BoardVersion(expr lexer.Expr) int {
+	header, err := expr.Child("version")
+	if err != nil {
+		return defaultVersion
+	}
+	version, err := header.NumberAt(0)
+	if err != nil {
+		return defaultVersion
+	}
+	return int(version)
+}
+
+// ParseTargetVersion reads a -target-version value, either a KiCad release
+// such as 8 or a file version such as 20240108.
+func ParseTargetVersion(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q: want a KiCad release such as 8 or a file version such as %d", s, kicad8Version)
+	}
+	if version, ok := releaseVersions[n]; ok {
+		return version, nil
+	}
+	if n < kicad5Version {
+		return 0, fmt.Errorf("unsupported version %d: the oldest supported file version is %d (KiCad 5)", n, kicad5Version)
+	}
+	return n, nil
+}
+
+// decoder reads the parts of a board that are written differently by
+// different KiCad versions.
+type decoder struct {
+	version  int
+	nets     map[string]int // Net numbers by name from the board's net table
+	netNames map[int]string // The inverse of nets
+}
+
+func newDecoder(root lexer.Expr) *decoder {
+	d := &decoder{version: BoardVersion(root), nets: make(map[string]int), netNames: make(map[int]string)}
+	for _, net := range root.Children("net") {
+		number, numErr := net.NumberAt(0)
+		name, nameErr := net.TextAt(1)
+		if numErr == nil && nameErr == nil {
+			d.nets[name] = int(number)
+			d.netNames[int(number)] = name
+		}
+	}
+	return d
+}
+
+// padNet reads the (net ...) of a pad. Pads without one are unconnected and
+// get net 0. From KiCad 9 a pad may name its net alone, (net "GND"), which is
+// looked up in the board's net table.
+func (d *decoder) padNet(net lexer.Expr) (pcb.Net, error) {
+	if d.version >= kicad9Version {
+		if name, err := net.TextAt(0); err == nil {
+			number, ok := d.nets[name]
+			if !ok {
+				return pcb.Net{}, fmt.Errorf("pad net %q is not in the board's net table", name)
+			}
+			return pcb.Net{Number: number, Name: name}, nil
+		}
+	}
+	result := pcb.Net{}
+	err := lexer.Unmarshal(net, &result)
+	return result, err
+}
+
+// footprintReference returns the reference designator of a footprint, from
+// (property "Reference" ...) since KiCad 8 and (fp_text reference ...)
+// before. Generators such as ergogen write fp_text under a current version
+// header, so it is read in every version.
+func (d *decoder) footprintReference(footprint lexer.Expr) string {
+	if d.version >= kicad8Version {
+		for _, property := range footprint.Children("property") {
+			if key, _ := property.TextAt(0); key == "Reference" {
+				reference, _ := property.TextAt(1)
+				return reference
+			}
+		}
+	}
+	for _, text := range footprint.Children("fp_text") {
+		// Older files leave numeric references unquoted, which Unmarshal reads
+		fields := struct {
+			Kind string `sexpr:",arg"`
+			Text string `sexpr:",arg"`
+		}{}
+		if err := lexer.Unmarshal(text, &fields); err == nil && fields.Kind == "reference" {
+			return fields.Text
+		}
+	}
+	return ""
+}
+
+// via reads a (via ...), whose UUID was a tstamp before KiCad 8.
+func (d *decoder) via(expr lexer.Expr) (pcb.Via, error) {
+	via, err := parseViaExpr(expr)
+	if err != nil {
+		return via, err
+	}
+	if d.version < kicad8Version {
+		via.UUID = itemUUID(expr)
+	}
+	return via, nil
+}
+
+// segment reads a (segment ...), whose UUID was a tstamp before KiCad 8.
+func (d *decoder) segment(expr lexer.Expr) (pcb.Segment, error) {
+	segment := pcb.Segment{}
+	if err := lexer.Unmarshal(expr, &segment); err != nil {
+		return segment, fmt.Errorf("failed to parse segment: %w", err)
+	}
+	if d.version < kicad8Version {
+		segment.UUID = itemUUID(expr)
+	}
+	return segment, nil
+}
+
+// itemUUID returns the (uuid ...) of a board item, or its (tstamp ...) in
+// older files.
+func itemUUID(expr lexer.Expr) string {
+	ids := struct {
+		UUID   string `sexpr:"uuid,optional"`
+		TStamp string `sexpr:"tstamp,optional"`
+	}{}
+	if err := lexer.Unmarshal(expr, &ids); err != nil || ids.UUID != "" {
+		return ids.UUID
+	}
+	return ids.TStamp
+}
+
+// encodeItem rewrites a segment or via built by lexer.Marshal, which is in
+// the current format, the way a board of the given version writes it.
+func encodeItem(item *lexer.Expr, version int) {
+	lexer.WalkFunc(item, func(c *lexer.Cursor) bool {
+		e := c.Expr()
+		if version < kicad8Version && e.Type == lexer.ExprUUID {
+			renameIdentifier(e, "tstamp")
+			unquoteValues(e)
+		}
+		if version < kicad6Version && (e.Type == lexer.ExprLayer || e.Type == lexer.ExprLayers) {
+			unquoteValues(e)
+		}
+		return true
+	}, nil)
+}
+
+// formatChange is a difference between a release and the one before it.
+type formatChange struct {
+	version int
+	upgrade func(c *lexer.Cursor)
+}
+
+// formatChanges are applied in order by UpgradeBoard.
+var formatChanges = []formatChange{
+	{kicad6Version, func(c *lexer.Cursor) {
+		e := c.Expr()
+		switch {
+		case e.Identifier == "module":
+			renameIdentifier(e, "footprint")
+			if len(e.Values) > 0 {
+				quoteValue(e, 0)
+			}
+		case e.Type == lexer.ExprLayer || e.Type == lexer.ExprLayers:
+			quoteValues(e)
+		case e.Type == lexer.ExprNet && len(e.Values) > 1:
+			quoteValue(e, 1)
+		case e.Type == lexer.ExprPad && len(e.Values) > 0:
+			quoteValue(e, 0)
+		case c.Parent() != nil && c.Parent().Expr().Type == lexer.ExprLayers && c.Parent().Parent() != nil:
+			// Entries of the board's layer table, e.g. (0 F.Cu signal)
+			quoteValues(e)
+		}
+	}},
+	{kicad8Version, func(c *lexer.Cursor) {
+		e := c.Expr()
+		switch {
+		case e.Identifier == "tstamp":
+			renameIdentifier(e, "uuid")
+			quoteValues(e)
+		case e.Type == lexer.ExprFpText && len(e.Values) > 1:
+			kind, _ := e.IdentifierAt(0)
+			if kind != "reference" && kind != "value" {
+				return
+			}
+			renameIdentifier(e, "property")
+			e.Values[0] = lexer.StringValue{Value: map[string]string{"reference": "Reference", "value": "Value"}[kind]}
+			quoteValue(e, 1)
+		}
+	}},
+}
+
+// UpgradeBoard rewrites a board in the format of a newer version and sets
+// its (version N) header. Only the differences the router reads and writes
+// are converted; KiCad reads the rest of an older file either way.
+func UpgradeBoard(expr *lexer.Expr, target int) error {
+	current := BoardVersion(*expr)
+	if target < current {
+		return fmt.Errorf("cannot convert version %d to the older version %d", current, target)
+	}
+	for _, change := range formatChanges {
+		if change.version <= current || change.version > target {
+			continue
+		}
+		lexer.WalkFunc(expr, func(c *lexer.Cursor) bool {
+			change.upgrade(c)
+			return true
+		}, nil)
+	}
+
+	version := lexer.NumberValue{Value: float64(target)}
+	for i, val := range expr.Values {
+		if header, ok := val.(lexer.ExprValue); ok && header.Value.Type == lexer.ExprVersion {
+			header.Value.Values = []lexer.Value{version}
+			expr.Values[i] = header
+			return nil
+		}
+	}
+	expr.InsertValue(0, lexer.ExprValue{Value: lexer.Expr{
+		Type:       lexer.ExprVersion,
+		Identifier: "version",
+		Values:     []lexer.Value{version},
+	}})
+	return nil
+}
+
+func renameIdentifier(e *lexer.Expr, identifier string) {
+	e.Identifier = identifier
+	e.Type = lexer.IdentifierToExprType(identifier)
+}
+
+// quoteValue turns value i of e into a string when it is an unquoted name.
+func quoteValue(e *lexer.Expr, i int) {
+	switch v := e.Values[i].(type) {
+	case lexer.IdentifierValue:
+		e.Values[i] = lexer.StringValue{Value: v.Value}
+	case lexer.NumberValue:
+		e.Values[i] = lexer.StringValue{Value: v.String()}
+	}
+}
+
+// quoteValues quotes the unquoted names among the values of e, leaving flags
+// such as signal in (0 F.Cu signal) and numbers as they are.
+func quoteValues(e *lexer.Expr) {
+	for i, val := range e.Values {
+		if id, ok := val.(lexer.IdentifierValue); ok && !lexer.IdentifierToExprType(id.Value).IsFlag() {
+			e.Values[i] = lexer.StringValue{Value: id.Value}
+		}
+	}
+}
+
+// unquoteValues writes the strings among the values of e without quotes.
+func unquoteValues(e *lexer.Expr) {
+	for i, val := range e.Values {
+		if str, ok := val.(lexer.StringValue); ok {
+			e.Values[i] = lexer.IdentifierValue{Value: str.Value}
+		}
+	}
+}
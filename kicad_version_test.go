@@ -0,0 +1,277 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+func parseBoardString(t *testing.T, source string) lexer.Expr {
+	t.Helper()
+	tokens, err := lexer.Tokenize(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := lexer.Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expr
+}
+
+func TestBoardVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected int
+	}{
+		{"kicad 5", "(kicad_pcb (version 20171130) (host pcbnew 5.1.9))", kicad5Version},
+		{"kicad 8", "(kicad_pcb (version 20240108) (generator \"pcbnew\"))", kicad8Version},
+		{"no header", "(kicad_pcb (net 0 \"\"))", defaultVersion},
+		{"not a number", "(kicad_pcb (version latest))", defaultVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			expr := parseBoardString(t, tt.source)
+
+			// Act
+			version := BoardVersion(expr)
+
+			// Assert
+			if version != tt.expected {
+				t.Errorf("Expected version %d, got %d", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestParseTargetVersion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{"5", kicad5Version, false},
+		{"8", kicad8Version, false},
+		{"20221018", kicad7Version, false},
+		{"20230620", 20230620, false},
+		{"4", 0, true},
+		{"20150101", 0, true},
+		{"latest", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			// Act
+			version, err := ParseTargetVersion(tt.input)
+
+			// Assert
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if version != tt.expected {
+				t.Errorf("Expected version %d, got %d", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestExprToPCB_ReadsEveryVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		reference string
+		net       pcb.Net
+		viaUUID   string
+	}{
+		{
+			"kicad 5",
+			`(kicad_pcb (version 20171130) (net 1 GND)
+				(module R_0805 (layer F.Cu) (at 10 10)
+					(fp_text reference R1 (at 0 -1.5) (layer F.SilkS))
+					(pad 1 smd rect (at -1 0) (size 1 1) (layers F.Cu) (net 1 GND)))
+				(via (at 12 10) (size 0.8) (drill 0.4) (layers F.Cu B.Cu) (net 1) (tstamp 5C3F1A2C)))`,
+			"R1",
+			pcb.Net{Number: 1, Name: "GND"},
+			"5C3F1A2C",
+		},
+		{
+			"kicad 7",
+			`(kicad_pcb (version 20221018) (net 1 "GND")
+				(footprint "R_0805" (layer "F.Cu") (at 10 10)
+					(fp_text reference "R1" (at 0 -1.5) (layer "F.SilkS"))
+					(pad "1" smd rect (at -1 0) (size 1 1) (layers "F.Cu") (net 1 "GND")))
+				(via (at 12 10) (size 0.8) (drill 0.4) (layers "F.Cu" "B.Cu") (net 1) (tstamp 0a3e2b1c-0000-4000-8000-000000000001)))`,
+			"R1",
+			pcb.Net{Number: 1, Name: "GND"},
+			"0a3e2b1c-0000-4000-8000-000000000001",
+		},
+		{
+			"kicad 8",
+			`(kicad_pcb (version 20240108) (net 1 "GND")
+				(footprint "R_0805" (layer "F.Cu") (at 10 10)
+					(property "Reference" "R1" (at 0 -1.5) (layer "F.SilkS"))
+					(pad "1" smd rect (at -1 0) (size 1 1) (layers "F.Cu") (net 1 "GND")))
+				(via (at 12 10) (size 0.8) (drill 0.4) (layers "F.Cu" "B.Cu") (net 1) (uuid "0a3e2b1c-0000-4000-8000-000000000001")))`,
+			"R1",
+			pcb.Net{Number: 1, Name: "GND"},
+			"0a3e2b1c-0000-4000-8000-000000000001",
+		},
+		{
+			"kicad 9 net by name",
+			`(kicad_pcb (version 20241229) (net 1 "GND")
+				(footprint "R_0805" (layer "F.Cu") (at 10 10)
+					(property "Reference" "R1" (at 0 -1.5) (layer "F.SilkS"))
+					(pad "1" smd rect (at -1 0) (size 1 1) (layers "F.Cu") (net "GND")))
+				(via (at 12 10) (size 0.8) (drill 0.4) (layers "F.Cu" "B.Cu") (net 1) (uuid "0a3e2b1c-0000-4000-8000-000000000001")))`,
+			"R1",
+			pcb.Net{Number: 1, Name: "GND"},
+			"0a3e2b1c-0000-4000-8000-000000000001",
+		},
+		{
+			"kicad 9 net-less pad",
+			`(kicad_pcb (version 20241229)
+				(footprint "MountingHole" (layer "F.Cu") (at 10 10)
+					(property "Reference" "H1" (at 0 -1.5) (layer "F.SilkS"))
+					(pad "" np_thru_hole circle (at -1 0) (size 1 1) (layers "*.Cu")))
+				(via (at 12 10) (size 0.8) (drill 0.4) (layers "F.Cu" "B.Cu") (uuid "0a3e2b1c-0000-4000-8000-000000000001")))`,
+			"H1",
+			pcb.Net{},
+			"0a3e2b1c-0000-4000-8000-000000000001",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			expr := parseBoardString(t, tt.source)
+
+			// Act
+			board, err := ExprToPCB(expr)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(board.Pads) != 1 || len(board.Vias) != 1 {
+				t.Fatalf("Expected 1 pad and 1 via, got %d and %d", len(board.Pads), len(board.Vias))
+			}
+			validatePad(t, board.Pads[0], 9, 10, tt.net.Number, tt.net.Name, "F.Cu")
+			if board.Pads[0].Reference != tt.reference {
+				t.Errorf("Expected reference %q, got %q", tt.reference, board.Pads[0].Reference)
+			}
+			if board.Vias[0].UUID != tt.viaUUID {
+				t.Errorf("Expected via UUID %q, got %q", tt.viaUUID, board.Vias[0].UUID)
+			}
+		})
+	}
+}
+
+func TestExprToPCB_UnknownNetName(t *testing.T) {
+	// Arrange
+	expr := parseBoardString(t, `(kicad_pcb (version 20241229) (pad "1" smd rect (at 0 0) (layers "F.Cu") (net "VCC")))`)
+
+	// Act
+	_, err := ExprToPCB(expr)
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), `"VCC"`) {
+		t.Errorf("Expected an error naming the net, got %v", err)
+	}
+}
+
+func TestAddSegmentsToExpr_WritesInputVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		expected []string
+	}{
+		{"kicad 5", "20171130", []string{"(layer F.Cu)", "(tstamp seg-1)", "(layers F.Cu B.Cu)", "(tstamp via-1)"}},
+		{"kicad 7", "20221018", []string{`(layer "F.Cu")`, "(tstamp seg-1)", `(layers "F.Cu" "B.Cu")`, "(tstamp via-1)"}},
+		{"kicad 8", "20240108", []string{`(layer "F.Cu")`, `(uuid "seg-1")`, `(layers "F.Cu" "B.Cu")`, `(uuid "via-1")`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			expr := parseBoardString(t, "(kicad_pcb (version "+tt.version+"))")
+			board := pcb.NewBoard()
+			board.AddSegment(pcb.Segment{End: pcb.Position{X: 1}, Width: 0.2, Layer: "F.Cu", Net: 1, UUID: "seg-1"})
+			board.AddVia(pcb.Via{Layers: []string{"F.Cu", "B.Cu"}, Net: 1, UUID: "via-1"})
+
+			// Act
+			result, err := AddSegmentsToExpr(board, &expr)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			output := result.String()
+			for _, want := range tt.expected {
+				if !strings.Contains(output, want) {
+					t.Errorf("Expected %s in output:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestUpgradeBoard(t *testing.T) {
+	// Arrange
+	expr, err := ParsePcbFile("test_data/legacy_v5.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err = UpgradeBoard(&expr, kicad8Version)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	output := expr.String()
+	for _, want := range []string{
+		"(version 20240108) (host pcbnew 5.1.9)",
+		`(0 "F.Cu" signal)`,
+		`(net 1 "GND")`,
+		`(footprint "R_0805" (layer "F.Cu") (tedit 5B307E3C) (uuid "5C3F1A2B")`,
+		`(property "Reference" "R1" (at 0 -1.5) (layer "F.SilkS")`,
+		`(property "Value" "10k" (at 0 1.5) (layer "F.Fab"))`,
+		`(pad "1" smd rect (at -1 0) (size 1 1) (layers "F.Cu" "F.Paste" "F.Mask")`,
+		`(net 1) (uuid "5C3F1A2C"))`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %s in output:\n%s", want, output)
+		}
+	}
+
+	// The upgraded board reads like one saved by KiCad 8
+	board, err := ExprToPCB(expr)
+	if err != nil {
+		t.Fatalf("Expected no error reading the upgraded board, got %v", err)
+	}
+	if board.Pads[0].Reference != "R1" || board.Vias[0].UUID != "5C3F1A2C" {
+		t.Errorf("Expected reference R1 and via UUID 5C3F1A2C, got %q and %q", board.Pads[0].Reference, board.Vias[0].UUID)
+	}
+}
+
+func TestUpgradeBoard_RefusesDowngrade(t *testing.T) {
+	// Arrange
+	expr := parseBoardString(t, "(kicad_pcb (version 20240108))")
+
+	// Act
+	err := UpgradeBoard(&expr, kicad6Version)
+
+	// Assert
+	if err == nil {
+		t.Error("Expected an error converting to an older version")
+	}
+	if version := BoardVersion(expr); version != kicad8Version {
+		t.Errorf("Expected the version to stay %d, got %d", kicad8Version, version)
+	}
+}
@@ -0,0 +1,85 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError describes malformed input to Tokenize or Parse.
+type ParseError struct {
+	Pos      Position
+	Expected string   // What the grammar allows at Pos, e.g. "')'"
+	Got      string   // What was found instead, e.g. "end of input"
+	Path     []string // Identifiers of the enclosing expressions, outermost first
+	Excerpt  string   // Source line with a caret under Pos, empty when the source is unknown
+	Err      error    // Underlying cause, if any, and the message when Expected is empty
+}
+
+func (e *ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Message()
+}
+
+// Message describes the error without its position.
+func (e *ParseError) Message() string {
+	msg := fmt.Sprintf("expected %s, got %s", e.Expected, e.Got)
+	if e.Expected == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if len(e.Path) > 0 {
+		msg += " in " + strings.Join(e.Path, " > ")
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// describeToken names a token for error messages.
+func describeToken(token Token) string {
+	switch token.Type {
+	case OPEN_PAREN:
+		return "'('"
+	case CLOSE_PAREN:
+		return "')'"
+	case EOF:
+		return "end of input"
+	case STRING:
+		return "string " + quote(token.Value)
+	case NUMBER:
+		return "number " + token.Value
+	case HEX:
+		return "hexadecimal " + token.Value
+	default:
+		return "identifier " + token.Value
+	}
+}
+
+// excerpt returns line followed by a caret under column, in the style of
+//
+//	12 | 	(pad "1" smd
+//	   | 	         ^
+func excerpt(line string, pos Position) string {
+	line = strings.TrimRight(line, "\r\n")
+	number := fmt.Sprint(pos.Line)
+	var caret strings.Builder
+	for _, ch := range line[:min(max(pos.Column-1, 0), len(line))] {
+		// Keep tabs so the caret lines up however tabs are displayed
+		if ch == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	return fmt.Sprintf("%s | %s\n%s | %s^", number, line, strings.Repeat(" ", len(number)), caret.String())
+}
+
+// sourceLines rebuilds the lines of the source tokens were read from.
+func sourceLines(tokens []Token) []string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(token.Leading)
+		sb.WriteString(token.Raw)
+	}
+	return strings.Split(sb.String(), "\n")
+}
@@ -0,0 +1,206 @@
+package lexer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantPos  Position
+		expected string
+		got      string
+		path     []string
+	}{
+		{"missing open paren", "kicad_pcb", Position{0, 1, 1}, "'('", "identifier kicad_pcb", nil},
+		{"missing identifier", "(\n  )", Position{4, 2, 3}, "identifier", "')'", nil},
+		{"unclosed root", "(kicad_pcb (version 1)", Position{22, 1, 23}, "')'", "end of input", []string{"kicad_pcb"}},
+		{"unclosed nested", "(kicad_pcb\n\t(footprint \"R1\"\n\t\t(pad \"1\" smd)\n)", Position{45, 4, 2}, "')'", "end of input", []string{"kicad_pcb"}},
+		{"missing nested identifier", "(kicad_pcb (footprint (pad ())))", Position{28, 1, 29}, "identifier", "')'", []string{"kicad_pcb", "footprint", "pad"}},
+		{"trailing tokens", "(a) (b)", Position{4, 1, 5}, "end of input", "'('", nil},
+		{"unterminated string", "(net 1 \"GND)", Position{7, 1, 8}, "closing '\"'", "end of input", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			tokens, err := Tokenize(tt.input)
+			if err == nil {
+				_, err = Parse(tokens)
+			}
+
+			// Assert
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got error %v, want a *ParseError", err)
+			}
+			if parseErr.Pos != tt.wantPos {
+				t.Errorf("got position %+v, want %+v", parseErr.Pos, tt.wantPos)
+			}
+			if parseErr.Expected != tt.expected || parseErr.Got != tt.got {
+				t.Errorf("got expected %s, got %s; want expected %s, got %s", parseErr.Expected, parseErr.Got, tt.expected, tt.got)
+			}
+			if !reflect.DeepEqual(parseErr.Path, tt.path) {
+				t.Errorf("got path %v, want %v", parseErr.Path, tt.path)
+			}
+		})
+	}
+}
+
+func TestParseErrorMessage(t *testing.T) {
+	// Arrange
+	input := "(kicad_pcb\n\t(footprint \"R1\"\n\t\t(pad \"1\" smd (at 1 2 (size 1 1)\n"
+	tokens, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err = Parse(tokens)
+
+	// Assert
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("got error %v, want a *ParseError", err)
+	}
+	wantMessage := "4:1: expected ')', got end of input in kicad_pcb > footprint > pad > at"
+	if err.Error() != wantMessage {
+		t.Errorf("got message %q, want %q", err.Error(), wantMessage)
+	}
+}
+
+func TestParseErrorExcerpt(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			"caret keeps tabs",
+			"(kicad_pcb\r\n\t(net 1 \"GND\" )\r\n\t(net 2 ())\r\n)",
+			"3 | \t(net 2 ())\n  | \t        ^",
+		},
+		{
+			"caret after utf-8",
+			"(descr \"Östling\" ())",
+			"1 | (descr \"Östling\" ())\n  |                   ^",
+		},
+		{
+			"multi-line string",
+			"(a\n  (descr \"first\nsecond",
+			"2 |   (descr \"first\n  |          ^",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			tokens, err := Tokenize(tt.input)
+			if err == nil {
+				_, err = Parse(tokens)
+			}
+
+			// Assert
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got error %v, want a *ParseError", err)
+			}
+			if parseErr.Excerpt != tt.want {
+				t.Errorf("got excerpt\n%s\nwant\n%s", parseErr.Excerpt, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecover(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantOutput  string
+		wantErrors  []string
+		wantUnknown int // ExprError nodes in the tree
+	}{
+		{
+			name:       "valid input",
+			input:      "(kicad_pcb (net 1 \"GND\"))\n",
+			wantOutput: "(kicad_pcb (net 1 \"GND\"))\n",
+		},
+		{
+			name:        "bad nodes are kept verbatim",
+			input:       "(kicad_pcb\n\t(footprint \"R1\" (pad \"1\" ()))\n\t(footprint \"R2\" (\"\" (x)))\n\t(net 1 \"GND\")\n)",
+			wantOutput:  "(kicad_pcb\n\t(footprint \"R1\" (pad \"1\" ()))\n\t(footprint \"R2\" (\"\" (x)))\n\t(net 1 \"GND\")\n)",
+			wantErrors:  []string{"2:28: expected identifier, got ')' in kicad_pcb > footprint > pad"},
+			wantUnknown: 1,
+		},
+		{
+			name:        "unclosed expressions are closed",
+			input:       "(kicad_pcb\n\t(segment (start 0 0) (end (\n",
+			wantOutput:  "(kicad_pcb\n\t(segment (start 0 0) (end ())))\n",
+			wantErrors:  []string{"3:1: expected identifier, got end of input in kicad_pcb > segment > end"},
+			wantUnknown: 1,
+		},
+		{
+			name:       "trailing tokens are kept",
+			input:      "(a 1) (b 2)\n",
+			wantOutput: "(a 1) (b 2)\n",
+			wantErrors: []string{"1:7: expected end of input, got '('"},
+		},
+		{
+			name:        "several problems",
+			input:       "(a (1 x) (b (() y)) (c 3)",
+			wantOutput:  "(a (1 x) (b (() y)) (c 3))",
+			wantErrors:  []string{"1:14: expected identifier, got '(' in a > b", "1:26: expected ')', got end of input in a"},
+			wantUnknown: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			tokens, err := Tokenize(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			expr, diagnostics := ParseRecover(tokens)
+
+			// Assert
+			gotErrors := []string{}
+			for _, diagnostic := range diagnostics {
+				gotErrors = append(gotErrors, diagnostic.Error())
+				if diagnostic.Excerpt == "" {
+					t.Errorf("diagnostic %q has no excerpt", diagnostic)
+				}
+			}
+			if len(tt.wantErrors) == 0 {
+				tt.wantErrors = []string{}
+			}
+			if !reflect.DeepEqual(gotErrors, tt.wantErrors) {
+				t.Errorf("got diagnostics %q, want %q", gotErrors, tt.wantErrors)
+			}
+			if got := expr.String(); got != tt.wantOutput {
+				t.Errorf("got output %q, want %q", got, tt.wantOutput)
+			}
+			if got := countErrorNodes(expr); got != tt.wantUnknown {
+				t.Errorf("got %d error nodes, want %d", got, tt.wantUnknown)
+			}
+		})
+	}
+}
+
+func countErrorNodes(e Expr) int {
+	count := 0
+	if e.Type == ExprError {
+		count++
+	}
+	for _, val := range e.Values {
+		if exprVal, ok := val.(ExprValue); ok {
+			count += countErrorNodes(exprVal.Value)
+		}
+	}
+	return count
+}
@@ -0,0 +1,100 @@
+package lexer
+
+import (
+	"strings"
+)
+
+// unescape resolves the escape sequences KiCad accepts in quoted strings:
+// \" \\ \a \b \f \n \r \t \v, \xNN with one or two hex digits and \NNN with
+// up to three octal digits. Unknown escapes are kept as written.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case '"', '\\':
+			sb.WriteByte(s[i])
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'v':
+			sb.WriteByte('\v')
+		case 'x':
+			value, n := 0, 0
+			for ; n < 2 && i+1+n < len(s) && hexDigit(s[i+1+n]) >= 0; n++ {
+				value = value*16 + hexDigit(s[i+1+n])
+			}
+			if n == 0 {
+				sb.WriteString(`\x`)
+				continue
+			}
+			sb.WriteByte(byte(value))
+			i += n
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			value, n := 0, 0
+			for ; n < 3 && i+n < len(s) && '0' <= s[i+n] && s[i+n] <= '7'; n++ {
+				value = value*8 + int(s[i+n]-'0')
+			}
+			sb.WriteByte(byte(value))
+			i += n - 1
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+func hexDigit(ch byte) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		return int(ch-'a') + 10
+	case 'A' <= ch && ch <= 'F':
+		return int(ch-'A') + 10
+	default:
+		return -1
+	}
+}
+
+// quote returns s as a quoted string the way pcbnew writes it: quotes,
+// backslashes and line breaks are escaped, everything else is kept verbatim.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(s[i])
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
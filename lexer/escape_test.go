@@ -0,0 +1,81 @@
+package lexer
+
+import (
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "GND", `"GND"`},
+		{"empty", "", `""`},
+		{"quote", `12" ruler`, `"12\" ruler"`},
+		{"backslash", `C:\lib`, `"C:\\lib"`},
+		{"line breaks", "a\r\nb", `"a\r\nb"`},
+		{"tab kept", "a\tb", "\"a\tb\""},
+		{"utf-8 kept", "Marcus Östling", `"Marcus Östling"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := StringValue{Value: tt.input}.String()
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteRoundtrip(t *testing.T) {
+	inputs := []string{"", `"`, `\`, `\"`, "\\n", "a\nb", "\x00\x7f", "Östling", `ends with \`}
+
+	for _, input := range inputs {
+		// Arrange
+		expr := Expr{Identifier: "descr", Values: []Value{StringValue{Value: input}}}
+
+		// Act
+		tokens, err := Tokenize(expr.String())
+		if err != nil {
+			t.Fatalf("%q: %v", input, err)
+		}
+		parsed, err := Parse(tokens)
+		if err != nil {
+			t.Fatalf("%q: %v", input, err)
+		}
+
+		// Assert
+		if got := parsed.Values[0].(StringValue).Value; got != input {
+			t.Errorf("got %q, want %q", got, input)
+		}
+	}
+}
+
+func TestParseKeepsEscapedStringText(t *testing.T) {
+	// Arrange
+	input := `(footprint (descr "2.54mm \"header\"\x21 \\ \101") (tags "a\tb"))`
+	tokens, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	descr := expr.Values[0].(ExprValue).Value.Values[0].(StringValue).Value
+	if want := `2.54mm "header"! \ A`; descr != want {
+		t.Errorf("got %q, want %q", descr, want)
+	}
+	if got := expr.String(); got != input {
+		t.Errorf("got %s, want %s", got, input)
+	}
+}
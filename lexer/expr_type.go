@@ -1,96 +1,47 @@
 package lexer
 
+//go:generate go run ./internal/gentokens -spec tokens.txt -o expr_type_gen.go
+
+// ExprType identifies the KiCad token heading an expression. The constants
+// are generated from tokens.txt.
 type ExprType int
 
 const (
-	ExprUnknown ExprType = iota
-	ExprKicadPcb
-	ExprFootprint
-	ExprPad
-	ExprSegment
-	ExprVia
-	ExprNet
-	ExprLayer
-	ExprGrLine
-	ExprGrArc
-	ExprAt
-	ExprStart
-	ExprEnd
-	ExprWidth
-	ExprLayers
-	ExprUUID
+	ExprUnknown ExprType = 0
+	ExprError   ExprType = -1 // Source the recovering parser could not read, see ParseRecover
+)
+
+// tokenKind tells where a token may appear, see tokens.txt.
+type tokenKind uint8
+
+const (
+	kindNode tokenKind = 1 << iota // Heads a list, e.g. (at 1 2)
+	kindFlag                       // Bare among the values of a list, e.g. smd
 )
 
 func (et ExprType) String() string {
-	switch et {
-	case ExprKicadPcb:
-		return "kicad_pcb"
-	case ExprFootprint:
-		return "footprint"
-	case ExprPad:
-		return "pad"
-	case ExprSegment:
-		return "segment"
-	case ExprVia:
-		return "via"
-	case ExprNet:
-		return "net"
-	case ExprLayer:
-		return "layer"
-	case ExprGrLine:
-		return "gr_line"
-	case ExprGrArc:
-		return "gr_arc"
-	case ExprAt:
-		return "at"
-	case ExprStart:
-		return "start"
-	case ExprEnd:
-		return "end"
-	case ExprWidth:
-		return "width"
-	case ExprLayers:
-		return "layers"
-	case ExprUUID:
-		return "uuid"
-	default:
-		return "unknown"
+	if et > 0 && int(et) < len(exprTypeNames) {
+		return exprTypeNames[et]
+	}
+	if et == ExprError {
+		return "error"
 	}
+	return "unknown"
 }
 
+// IsNode reports whether the token can head a list.
+func (et ExprType) IsNode() bool {
+	return et > 0 && int(et) < len(exprTypeKinds) && exprTypeKinds[et]&kindNode != 0
+}
+
+// IsFlag reports whether the token can appear bare among the values of a
+// list, such as smd in (pad "1" smd ...) or locked in older files.
+func (et ExprType) IsFlag() bool {
+	return et > 0 && int(et) < len(exprTypeKinds) && exprTypeKinds[et]&kindFlag != 0
+}
+
+// IdentifierToExprType returns the token for identifier, or ExprUnknown.
+// Older identifiers map to their current token, e.g. module to ExprFootprint.
 func IdentifierToExprType(identifier string) ExprType {
-	switch identifier {
-	case "kicad_pcb":
-		return ExprKicadPcb
-	case "footprint", "module":
-		return ExprFootprint
-	case "pad":
-		return ExprPad
-	case "segment":
-		return ExprSegment
-	case "via":
-		return ExprVia
-	case "net":
-		return ExprNet
-	case "layer":
-		return ExprLayer
-	case "gr_line":
-		return ExprGrLine
-	case "gr_arc":
-		return ExprGrArc
-	case "at":
-		return ExprAt
-	case "start":
-		return ExprStart
-	case "end":
-		return ExprEnd
-	case "width":
-		return ExprWidth
-	case "layers":
-		return ExprLayers
-	case "uuid":
-		return ExprUUID
-	default:
-		return ExprUnknown
-	}
+	return identifierExprTypes[identifier]
 }
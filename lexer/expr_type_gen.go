@@ -0,0 +1,1068 @@
+// Code generated by gentokens from tokens.txt; DO NOT EDIT.
+
+package lexer
+
+const (
+	ExprKicadPcb ExprType = iota + 1
+	ExprVersion
+	ExprGenerator
+	ExprGeneratorVersion
+	ExprGeneral
+	ExprThickness
+	ExprLegacyTeardrops
+	ExprPaper
+	ExprPortrait
+	ExprTitleBlock
+	ExprTitle
+	ExprDate
+	ExprRev
+	ExprCompany
+	ExprComment
+	ExprLayers
+	ExprLayer
+	ExprSignal
+	ExprPower
+	ExprMixed
+	ExprJumper
+	ExprUser
+	ExprStackup
+	ExprDielectricConstraints
+	ExprCopperFinish
+	ExprEdgeConnector
+	ExprCastellatedPads
+	ExprEdgePlating
+	ExprEpsilonR
+	ExprLossTangent
+	ExprMaterial
+	ExprColor
+	ExprAddsublayer
+	ExprSetup
+	ExprPadToMaskClearance
+	ExprSolderMaskMinWidth
+	ExprPadToPasteClearance
+	ExprPadToPasteClearanceRatio
+	ExprAllowSoldermaskBridgesInFootprints
+	ExprAuxAxisOrigin
+	ExprGridOrigin
+	ExprPcbplotparams
+	ExprLayerselection
+	ExprPlotOnAllLayersSelection
+	ExprDisableapertmacros
+	ExprUsegerberextensions
+	ExprUsegerberattributes
+	ExprUsegerberadvancedattributes
+	ExprCreategerberjobfile
+	ExprDashedLineDashRatio
+	ExprDashedLineGapRatio
+	ExprSvgprecision
+	ExprSvguseinch
+	ExprExcludeedgelayer
+	ExprLinewidth
+	ExprPlotframeref
+	ExprViasonmask
+	ExprMode
+	ExprUseauxorigin
+	ExprHpglpennumber
+	ExprHpglpenspeed
+	ExprHpglpendiameter
+	ExprPdfFrontFpPropertyPopups
+	ExprPdfBackFpPropertyPopups
+	ExprDxfpolygonmode
+	ExprDxfimperialunits
+	ExprDxfusepcbnewfont
+	ExprPsnegative
+	ExprPsa4output
+	ExprPlotreference
+	ExprPlotvalue
+	ExprPlotfptext
+	ExprPlotinvisibletext
+	ExprSketchpadsonfab
+	ExprSubtractmaskfromsilk
+	ExprOutputformat
+	ExprMirror
+	ExprDrillshape
+	ExprScaleselection
+	ExprOutputdirectory
+	ExprNet
+	ExprNetClass
+	ExprAddNet
+	ExprClearance
+	ExprTraceWidth
+	ExprViaDia
+	ExprViaDrill
+	ExprUviaDia
+	ExprUviaDrill
+	ExprDiffPairWidth
+	ExprDiffPairGap
+	ExprProperty
+	ExprFootprint
+	ExprLocked
+	ExprUnlocked
+	ExprPlaced
+	ExprTedit
+	ExprTstamp
+	ExprUUID
+	ExprAt
+	ExprDescr
+	ExprTags
+	ExprPath
+	ExprSheetname
+	ExprSheetfile
+	ExprAttr
+	ExprSmd
+	ExprThroughHole
+	ExprBoardOnly
+	ExprExcludeFromPosFiles
+	ExprExcludeFromBom
+	ExprAllowMissingCourtyard
+	ExprDnp
+	ExprFpText
+	ExprReference
+	ExprValueToken
+	ExprFpLine
+	ExprFpRect
+	ExprFpCircle
+	ExprFpArc
+	ExprFpPoly
+	ExprFpCurve
+	ExprFpTextBox
+	ExprModel
+	ExprOffset
+	ExprScale
+	ExprRotate
+	ExprXyz
+	ExprZoneConnect
+	ExprSolderMaskMargin
+	ExprSolderPasteMargin
+	ExprSolderPasteRatio
+	ExprSolderPasteMarginRatio
+	ExprAutoplaceCost90
+	ExprAutoplaceCost180
+	ExprPrivateLayers
+	ExprNetTiePadGroups
+	ExprPad
+	ExprThruHole
+	ExprNpThruHole
+	ExprConnect
+	ExprCircle
+	ExprRect
+	ExprOval
+	ExprRoundrect
+	ExprTrapezoid
+	ExprCustom
+	ExprDrill
+	ExprSize
+	ExprRectDelta
+	ExprRoundrectRratio
+	ExprChamferRatio
+	ExprChamfer
+	ExprTopLeft
+	ExprTopRight
+	ExprBottomLeft
+	ExprBottomRight
+	ExprPinfunction
+	ExprPintype
+	ExprDieLength
+	ExprRemoveUnusedLayers
+	ExprKeepEndLayers
+	ExprThermalWidth
+	ExprThermalGap
+	ExprThermalBridgeWidth
+	ExprThermalBridgeAngle
+	ExprOptions
+	ExprAnchor
+	ExprPrimitives
+	ExprTeardrops
+	ExprGrLine
+	ExprGrArc
+	ExprGrCircle
+	ExprGrRect
+	ExprGrPoly
+	ExprGrCurve
+	ExprGrText
+	ExprGrTextBox
+	ExprStart
+	ExprMid
+	ExprEnd
+	ExprCenter
+	ExprWidth
+	ExprAngle
+	ExprStroke
+	ExprTypeToken
+	ExprSolid
+	ExprDash
+	ExprDot
+	ExprDashDot
+	ExprDashDotDot
+	ExprDefault
+	ExprFill
+	ExprNone
+	ExprPts
+	ExprXy
+	ExprArc
+	ExprEffects
+	ExprFont
+	ExprBold
+	ExprItalic
+	ExprJustify
+	ExprLeft
+	ExprRight
+	ExprTop
+	ExprBottom
+	ExprHide
+	ExprKnockout
+	ExprRenderCache
+	ExprImage
+	ExprData
+	ExprTarget
+	ExprDimension
+	ExprFormat
+	ExprPrefix
+	ExprSuffix
+	ExprUnits
+	ExprUnitsFormat
+	ExprPrecision
+	ExprOverrideValue
+	ExprSuppressZeros
+	ExprStyle
+	ExprArrowLength
+	ExprTextPositionMode
+	ExprExtensionHeight
+	ExprExtensionOffset
+	ExprKeepTextAligned
+	ExprHeight
+	ExprOrientation
+	ExprLeaderLength
+	ExprSegment
+	ExprVia
+	ExprBlind
+	ExprMicro
+	ExprFree
+	ExprZoneLayerConnections
+	ExprZone
+	ExprName
+	ExprHatch
+	ExprPriority
+	ExprConnectPads
+	ExprMinThickness
+	ExprFilledAreasThickness
+	ExprKeepout
+	ExprTracks
+	ExprVias
+	ExprPads
+	ExprCopperpour
+	ExprFootprints
+	ExprAllowed
+	ExprNotAllowed
+	ExprSmoothing
+	ExprRadius
+	ExprIslandRemovalMode
+	ExprIslandAreaMin
+	ExprPolygon
+	ExprFilledPolygon
+	ExprFillSegments
+	ExprIsland
+	ExprGroup
+	ExprMembers
+	ExprID
+	ExprGenerated
+)
+
+var exprTypeNames = [...]string{
+	ExprKicadPcb:                           "kicad_pcb",
+	ExprVersion:                            "version",
+	ExprGenerator:                          "generator",
+	ExprGeneratorVersion:                   "generator_version",
+	ExprGeneral:                            "general",
+	ExprThickness:                          "thickness",
+	ExprLegacyTeardrops:                    "legacy_teardrops",
+	ExprPaper:                              "paper",
+	ExprPortrait:                           "portrait",
+	ExprTitleBlock:                         "title_block",
+	ExprTitle:                              "title",
+	ExprDate:                               "date",
+	ExprRev:                                "rev",
+	ExprCompany:                            "company",
+	ExprComment:                            "comment",
+	ExprLayers:                             "layers",
+	ExprLayer:                              "layer",
+	ExprSignal:                             "signal",
+	ExprPower:                              "power",
+	ExprMixed:                              "mixed",
+	ExprJumper:                             "jumper",
+	ExprUser:                               "user",
+	ExprStackup:                            "stackup",
+	ExprDielectricConstraints:              "dielectric_constraints",
+	ExprCopperFinish:                       "copper_finish",
+	ExprEdgeConnector:                      "edge_connector",
+	ExprCastellatedPads:                    "castellated_pads",
+	ExprEdgePlating:                        "edge_plating",
+	ExprEpsilonR:                           "epsilon_r",
+	ExprLossTangent:                        "loss_tangent",
+	ExprMaterial:                           "material",
+	ExprColor:                              "color",
+	ExprAddsublayer:                        "addsublayer",
+	ExprSetup:                              "setup",
+	ExprPadToMaskClearance:                 "pad_to_mask_clearance",
+	ExprSolderMaskMinWidth:                 "solder_mask_min_width",
+	ExprPadToPasteClearance:                "pad_to_paste_clearance",
+	ExprPadToPasteClearanceRatio:           "pad_to_paste_clearance_ratio",
+	ExprAllowSoldermaskBridgesInFootprints: "allow_soldermask_bridges_in_footprints",
+	ExprAuxAxisOrigin:                      "aux_axis_origin",
+	ExprGridOrigin:                         "grid_origin",
+	ExprPcbplotparams:                      "pcbplotparams",
+	ExprLayerselection:                     "layerselection",
+	ExprPlotOnAllLayersSelection:           "plot_on_all_layers_selection",
+	ExprDisableapertmacros:                 "disableapertmacros",
+	ExprUsegerberextensions:                "usegerberextensions",
+	ExprUsegerberattributes:                "usegerberattributes",
+	ExprUsegerberadvancedattributes:        "usegerberadvancedattributes",
+	ExprCreategerberjobfile:                "creategerberjobfile",
+	ExprDashedLineDashRatio:                "dashed_line_dash_ratio",
+	ExprDashedLineGapRatio:                 "dashed_line_gap_ratio",
+	ExprSvgprecision:                       "svgprecision",
+	ExprSvguseinch:                         "svguseinch",
+	ExprExcludeedgelayer:                   "excludeedgelayer",
+	ExprLinewidth:                          "linewidth",
+	ExprPlotframeref:                       "plotframeref",
+	ExprViasonmask:                         "viasonmask",
+	ExprMode:                               "mode",
+	ExprUseauxorigin:                       "useauxorigin",
+	ExprHpglpennumber:                      "hpglpennumber",
+	ExprHpglpenspeed:                       "hpglpenspeed",
+	ExprHpglpendiameter:                    "hpglpendiameter",
+	ExprPdfFrontFpPropertyPopups:           "pdf_front_fp_property_popups",
+	ExprPdfBackFpPropertyPopups:            "pdf_back_fp_property_popups",
+	ExprDxfpolygonmode:                     "dxfpolygonmode",
+	ExprDxfimperialunits:                   "dxfimperialunits",
+	ExprDxfusepcbnewfont:                   "dxfusepcbnewfont",
+	ExprPsnegative:                         "psnegative",
+	ExprPsa4output:                         "psa4output",
+	ExprPlotreference:                      "plotreference",
+	ExprPlotvalue:                          "plotvalue",
+	ExprPlotfptext:                         "plotfptext",
+	ExprPlotinvisibletext:                  "plotinvisibletext",
+	ExprSketchpadsonfab:                    "sketchpadsonfab",
+	ExprSubtractmaskfromsilk:               "subtractmaskfromsilk",
+	ExprOutputformat:                       "outputformat",
+	ExprMirror:                             "mirror",
+	ExprDrillshape:                         "drillshape",
+	ExprScaleselection:                     "scaleselection",
+	ExprOutputdirectory:                    "outputdirectory",
+	ExprNet:                                "net",
+	ExprNetClass:                           "net_class",
+	ExprAddNet:                             "add_net",
+	ExprClearance:                          "clearance",
+	ExprTraceWidth:                         "trace_width",
+	ExprViaDia:                             "via_dia",
+	ExprViaDrill:                           "via_drill",
+	ExprUviaDia:                            "uvia_dia",
+	ExprUviaDrill:                          "uvia_drill",
+	ExprDiffPairWidth:                      "diff_pair_width",
+	ExprDiffPairGap:                        "diff_pair_gap",
+	ExprProperty:                           "property",
+	ExprFootprint:                          "footprint",
+	ExprLocked:                             "locked",
+	ExprUnlocked:                           "unlocked",
+	ExprPlaced:                             "placed",
+	ExprTedit:                              "tedit",
+	ExprTstamp:                             "tstamp",
+	ExprUUID:                               "uuid",
+	ExprAt:                                 "at",
+	ExprDescr:                              "descr",
+	ExprTags:                               "tags",
+	ExprPath:                               "path",
+	ExprSheetname:                          "sheetname",
+	ExprSheetfile:                          "sheetfile",
+	ExprAttr:                               "attr",
+	ExprSmd:                                "smd",
+	ExprThroughHole:                        "through_hole",
+	ExprBoardOnly:                          "board_only",
+	ExprExcludeFromPosFiles:                "exclude_from_pos_files",
+	ExprExcludeFromBom:                     "exclude_from_bom",
+	ExprAllowMissingCourtyard:              "allow_missing_courtyard",
+	ExprDnp:                                "dnp",
+	ExprFpText:                             "fp_text",
+	ExprReference:                          "reference",
+	ExprValueToken:                         "value",
+	ExprFpLine:                             "fp_line",
+	ExprFpRect:                             "fp_rect",
+	ExprFpCircle:                           "fp_circle",
+	ExprFpArc:                              "fp_arc",
+	ExprFpPoly:                             "fp_poly",
+	ExprFpCurve:                            "fp_curve",
+	ExprFpTextBox:                          "fp_text_box",
+	ExprModel:                              "model",
+	ExprOffset:                             "offset",
+	ExprScale:                              "scale",
+	ExprRotate:                             "rotate",
+	ExprXyz:                                "xyz",
+	ExprZoneConnect:                        "zone_connect",
+	ExprSolderMaskMargin:                   "solder_mask_margin",
+	ExprSolderPasteMargin:                  "solder_paste_margin",
+	ExprSolderPasteRatio:                   "solder_paste_ratio",
+	ExprSolderPasteMarginRatio:             "solder_paste_margin_ratio",
+	ExprAutoplaceCost90:                    "autoplace_cost90",
+	ExprAutoplaceCost180:                   "autoplace_cost180",
+	ExprPrivateLayers:                      "private_layers",
+	ExprNetTiePadGroups:                    "net_tie_pad_groups",
+	ExprPad:                                "pad",
+	ExprThruHole:                           "thru_hole",
+	ExprNpThruHole:                         "np_thru_hole",
+	ExprConnect:                            "connect",
+	ExprCircle:                             "circle",
+	ExprRect:                               "rect",
+	ExprOval:                               "oval",
+	ExprRoundrect:                          "roundrect",
+	ExprTrapezoid:                          "trapezoid",
+	ExprCustom:                             "custom",
+	ExprDrill:                              "drill",
+	ExprSize:                               "size",
+	ExprRectDelta:                          "rect_delta",
+	ExprRoundrectRratio:                    "roundrect_rratio",
+	ExprChamferRatio:                       "chamfer_ratio",
+	ExprChamfer:                            "chamfer",
+	ExprTopLeft:                            "top_left",
+	ExprTopRight:                           "top_right",
+	ExprBottomLeft:                         "bottom_left",
+	ExprBottomRight:                        "bottom_right",
+	ExprPinfunction:                        "pinfunction",
+	ExprPintype:                            "pintype",
+	ExprDieLength:                          "die_length",
+	ExprRemoveUnusedLayers:                 "remove_unused_layers",
+	ExprKeepEndLayers:                      "keep_end_layers",
+	ExprThermalWidth:                       "thermal_width",
+	ExprThermalGap:                         "thermal_gap",
+	ExprThermalBridgeWidth:                 "thermal_bridge_width",
+	ExprThermalBridgeAngle:                 "thermal_bridge_angle",
+	ExprOptions:                            "options",
+	ExprAnchor:                             "anchor",
+	ExprPrimitives:                         "primitives",
+	ExprTeardrops:                          "teardrops",
+	ExprGrLine:                             "gr_line",
+	ExprGrArc:                              "gr_arc",
+	ExprGrCircle:                           "gr_circle",
+	ExprGrRect:                             "gr_rect",
+	ExprGrPoly:                             "gr_poly",
+	ExprGrCurve:                            "gr_curve",
+	ExprGrText:                             "gr_text",
+	ExprGrTextBox:                          "gr_text_box",
+	ExprStart:                              "start",
+	ExprMid:                                "mid",
+	ExprEnd:                                "end",
+	ExprCenter:                             "center",
+	ExprWidth:                              "width",
+	ExprAngle:                              "angle",
+	ExprStroke:                             "stroke",
+	ExprTypeToken:                          "type",
+	ExprSolid:                              "solid",
+	ExprDash:                               "dash",
+	ExprDot:                                "dot",
+	ExprDashDot:                            "dash_dot",
+	ExprDashDotDot:                         "dash_dot_dot",
+	ExprDefault:                            "default",
+	ExprFill:                               "fill",
+	ExprNone:                               "none",
+	ExprPts:                                "pts",
+	ExprXy:                                 "xy",
+	ExprArc:                                "arc",
+	ExprEffects:                            "effects",
+	ExprFont:                               "font",
+	ExprBold:                               "bold",
+	ExprItalic:                             "italic",
+	ExprJustify:                            "justify",
+	ExprLeft:                               "left",
+	ExprRight:                              "right",
+	ExprTop:                                "top",
+	ExprBottom:                             "bottom",
+	ExprHide:                               "hide",
+	ExprKnockout:                           "knockout",
+	ExprRenderCache:                        "render_cache",
+	ExprImage:                              "image",
+	ExprData:                               "data",
+	ExprTarget:                             "target",
+	ExprDimension:                          "dimension",
+	ExprFormat:                             "format",
+	ExprPrefix:                             "prefix",
+	ExprSuffix:                             "suffix",
+	ExprUnits:                              "units",
+	ExprUnitsFormat:                        "units_format",
+	ExprPrecision:                          "precision",
+	ExprOverrideValue:                      "override_value",
+	ExprSuppressZeros:                      "suppress_zeros",
+	ExprStyle:                              "style",
+	ExprArrowLength:                        "arrow_length",
+	ExprTextPositionMode:                   "text_position_mode",
+	ExprExtensionHeight:                    "extension_height",
+	ExprExtensionOffset:                    "extension_offset",
+	ExprKeepTextAligned:                    "keep_text_aligned",
+	ExprHeight:                             "height",
+	ExprOrientation:                        "orientation",
+	ExprLeaderLength:                       "leader_length",
+	ExprSegment:                            "segment",
+	ExprVia:                                "via",
+	ExprBlind:                              "blind",
+	ExprMicro:                              "micro",
+	ExprFree:                               "free",
+	ExprZoneLayerConnections:               "zone_layer_connections",
+	ExprZone:                               "zone",
+	ExprName:                               "name",
+	ExprHatch:                              "hatch",
+	ExprPriority:                           "priority",
+	ExprConnectPads:                        "connect_pads",
+	ExprMinThickness:                       "min_thickness",
+	ExprFilledAreasThickness:               "filled_areas_thickness",
+	ExprKeepout:                            "keepout",
+	ExprTracks:                             "tracks",
+	ExprVias:                               "vias",
+	ExprPads:                               "pads",
+	ExprCopperpour:                         "copperpour",
+	ExprFootprints:                         "footprints",
+	ExprAllowed:                            "allowed",
+	ExprNotAllowed:                         "not_allowed",
+	ExprSmoothing:                          "smoothing",
+	ExprRadius:                             "radius",
+	ExprIslandRemovalMode:                  "island_removal_mode",
+	ExprIslandAreaMin:                      "island_area_min",
+	ExprPolygon:                            "polygon",
+	ExprFilledPolygon:                      "filled_polygon",
+	ExprFillSegments:                       "fill_segments",
+	ExprIsland:                             "island",
+	ExprGroup:                              "group",
+	ExprMembers:                            "members",
+	ExprID:                                 "id",
+	ExprGenerated:                          "generated",
+}
+
+var exprTypeKinds = [...]tokenKind{
+	ExprKicadPcb:                           kindNode,
+	ExprVersion:                            kindNode,
+	ExprGenerator:                          kindNode,
+	ExprGeneratorVersion:                   kindNode,
+	ExprGeneral:                            kindNode,
+	ExprThickness:                          kindNode,
+	ExprLegacyTeardrops:                    kindNode,
+	ExprPaper:                              kindNode,
+	ExprPortrait:                           kindFlag,
+	ExprTitleBlock:                         kindNode,
+	ExprTitle:                              kindNode,
+	ExprDate:                               kindNode,
+	ExprRev:                                kindNode,
+	ExprCompany:                            kindNode,
+	ExprComment:                            kindNode,
+	ExprLayers:                             kindNode,
+	ExprLayer:                              kindNode,
+	ExprSignal:                             kindFlag,
+	ExprPower:                              kindFlag,
+	ExprMixed:                              kindFlag,
+	ExprJumper:                             kindFlag,
+	ExprUser:                               kindFlag,
+	ExprStackup:                            kindNode,
+	ExprDielectricConstraints:              kindNode,
+	ExprCopperFinish:                       kindNode,
+	ExprEdgeConnector:                      kindNode,
+	ExprCastellatedPads:                    kindNode,
+	ExprEdgePlating:                        kindNode,
+	ExprEpsilonR:                           kindNode,
+	ExprLossTangent:                        kindNode,
+	ExprMaterial:                           kindNode,
+	ExprColor:                              kindNode,
+	ExprAddsublayer:                        kindFlag,
+	ExprSetup:                              kindNode,
+	ExprPadToMaskClearance:                 kindNode,
+	ExprSolderMaskMinWidth:                 kindNode,
+	ExprPadToPasteClearance:                kindNode,
+	ExprPadToPasteClearanceRatio:           kindNode,
+	ExprAllowSoldermaskBridgesInFootprints: kindNode,
+	ExprAuxAxisOrigin:                      kindNode,
+	ExprGridOrigin:                         kindNode,
+	ExprPcbplotparams:                      kindNode,
+	ExprLayerselection:                     kindNode,
+	ExprPlotOnAllLayersSelection:           kindNode,
+	ExprDisableapertmacros:                 kindNode,
+	ExprUsegerberextensions:                kindNode,
+	ExprUsegerberattributes:                kindNode,
+	ExprUsegerberadvancedattributes:        kindNode,
+	ExprCreategerberjobfile:                kindNode,
+	ExprDashedLineDashRatio:                kindNode,
+	ExprDashedLineGapRatio:                 kindNode,
+	ExprSvgprecision:                       kindNode,
+	ExprSvguseinch:                         kindNode,
+	ExprExcludeedgelayer:                   kindNode,
+	ExprLinewidth:                          kindNode,
+	ExprPlotframeref:                       kindNode,
+	ExprViasonmask:                         kindNode,
+	ExprMode:                               kindNode,
+	ExprUseauxorigin:                       kindNode,
+	ExprHpglpennumber:                      kindNode,
+	ExprHpglpenspeed:                       kindNode,
+	ExprHpglpendiameter:                    kindNode,
+	ExprPdfFrontFpPropertyPopups:           kindNode,
+	ExprPdfBackFpPropertyPopups:            kindNode,
+	ExprDxfpolygonmode:                     kindNode,
+	ExprDxfimperialunits:                   kindNode,
+	ExprDxfusepcbnewfont:                   kindNode,
+	ExprPsnegative:                         kindNode,
+	ExprPsa4output:                         kindNode,
+	ExprPlotreference:                      kindNode,
+	ExprPlotvalue:                          kindNode,
+	ExprPlotfptext:                         kindNode,
+	ExprPlotinvisibletext:                  kindNode,
+	ExprSketchpadsonfab:                    kindNode,
+	ExprSubtractmaskfromsilk:               kindNode,
+	ExprOutputformat:                       kindNode,
+	ExprMirror:                             kindNode | kindFlag,
+	ExprDrillshape:                         kindNode,
+	ExprScaleselection:                     kindNode,
+	ExprOutputdirectory:                    kindNode,
+	ExprNet:                                kindNode,
+	ExprNetClass:                           kindNode,
+	ExprAddNet:                             kindNode,
+	ExprClearance:                          kindNode,
+	ExprTraceWidth:                         kindNode,
+	ExprViaDia:                             kindNode,
+	ExprViaDrill:                           kindNode,
+	ExprUviaDia:                            kindNode,
+	ExprUviaDrill:                          kindNode,
+	ExprDiffPairWidth:                      kindNode,
+	ExprDiffPairGap:                        kindNode,
+	ExprProperty:                           kindNode,
+	ExprFootprint:                          kindNode,
+	ExprLocked:                             kindNode | kindFlag,
+	ExprUnlocked:                           kindNode | kindFlag,
+	ExprPlaced:                             kindNode | kindFlag,
+	ExprTedit:                              kindNode,
+	ExprTstamp:                             kindNode,
+	ExprUUID:                               kindNode,
+	ExprAt:                                 kindNode,
+	ExprDescr:                              kindNode,
+	ExprTags:                               kindNode,
+	ExprPath:                               kindNode,
+	ExprSheetname:                          kindNode,
+	ExprSheetfile:                          kindNode,
+	ExprAttr:                               kindNode,
+	ExprSmd:                                kindFlag,
+	ExprThroughHole:                        kindFlag,
+	ExprBoardOnly:                          kindFlag,
+	ExprExcludeFromPosFiles:                kindFlag,
+	ExprExcludeFromBom:                     kindFlag,
+	ExprAllowMissingCourtyard:              kindFlag,
+	ExprDnp:                                kindNode | kindFlag,
+	ExprFpText:                             kindNode,
+	ExprReference:                          kindFlag,
+	ExprValueToken:                         kindFlag,
+	ExprFpLine:                             kindNode,
+	ExprFpRect:                             kindNode,
+	ExprFpCircle:                           kindNode,
+	ExprFpArc:                              kindNode,
+	ExprFpPoly:                             kindNode,
+	ExprFpCurve:                            kindNode,
+	ExprFpTextBox:                          kindNode,
+	ExprModel:                              kindNode,
+	ExprOffset:                             kindNode,
+	ExprScale:                              kindNode,
+	ExprRotate:                             kindNode,
+	ExprXyz:                                kindNode,
+	ExprZoneConnect:                        kindNode,
+	ExprSolderMaskMargin:                   kindNode,
+	ExprSolderPasteMargin:                  kindNode,
+	ExprSolderPasteRatio:                   kindNode,
+	ExprSolderPasteMarginRatio:             kindNode,
+	ExprAutoplaceCost90:                    kindNode,
+	ExprAutoplaceCost180:                   kindNode,
+	ExprPrivateLayers:                      kindNode,
+	ExprNetTiePadGroups:                    kindNode,
+	ExprPad:                                kindNode,
+	ExprThruHole:                           kindFlag,
+	ExprNpThruHole:                         kindFlag,
+	ExprConnect:                            kindFlag,
+	ExprCircle:                             kindFlag,
+	ExprRect:                               kindFlag,
+	ExprOval:                               kindFlag,
+	ExprRoundrect:                          kindFlag,
+	ExprTrapezoid:                          kindFlag,
+	ExprCustom:                             kindFlag,
+	ExprDrill:                              kindNode,
+	ExprSize:                               kindNode,
+	ExprRectDelta:                          kindNode,
+	ExprRoundrectRratio:                    kindNode,
+	ExprChamferRatio:                       kindNode,
+	ExprChamfer:                            kindNode,
+	ExprTopLeft:                            kindFlag,
+	ExprTopRight:                           kindFlag,
+	ExprBottomLeft:                         kindFlag,
+	ExprBottomRight:                        kindFlag,
+	ExprPinfunction:                        kindNode,
+	ExprPintype:                            kindNode,
+	ExprDieLength:                          kindNode,
+	ExprRemoveUnusedLayers:                 kindNode | kindFlag,
+	ExprKeepEndLayers:                      kindNode | kindFlag,
+	ExprThermalWidth:                       kindNode,
+	ExprThermalGap:                         kindNode,
+	ExprThermalBridgeWidth:                 kindNode,
+	ExprThermalBridgeAngle:                 kindNode,
+	ExprOptions:                            kindNode,
+	ExprAnchor:                             kindNode,
+	ExprPrimitives:                         kindNode,
+	ExprTeardrops:                          kindNode,
+	ExprGrLine:                             kindNode,
+	ExprGrArc:                              kindNode,
+	ExprGrCircle:                           kindNode,
+	ExprGrRect:                             kindNode,
+	ExprGrPoly:                             kindNode,
+	ExprGrCurve:                            kindNode,
+	ExprGrText:                             kindNode,
+	ExprGrTextBox:                          kindNode,
+	ExprStart:                              kindNode,
+	ExprMid:                                kindNode,
+	ExprEnd:                                kindNode,
+	ExprCenter:                             kindNode,
+	ExprWidth:                              kindNode,
+	ExprAngle:                              kindNode,
+	ExprStroke:                             kindNode,
+	ExprTypeToken:                          kindNode,
+	ExprSolid:                              kindFlag,
+	ExprDash:                               kindFlag,
+	ExprDot:                                kindFlag,
+	ExprDashDot:                            kindFlag,
+	ExprDashDotDot:                         kindFlag,
+	ExprDefault:                            kindFlag,
+	ExprFill:                               kindNode | kindFlag,
+	ExprNone:                               kindFlag,
+	ExprPts:                                kindNode,
+	ExprXy:                                 kindNode,
+	ExprArc:                                kindNode,
+	ExprEffects:                            kindNode,
+	ExprFont:                               kindNode,
+	ExprBold:                               kindNode | kindFlag,
+	ExprItalic:                             kindNode | kindFlag,
+	ExprJustify:                            kindNode,
+	ExprLeft:                               kindFlag,
+	ExprRight:                              kindFlag,
+	ExprTop:                                kindFlag,
+	ExprBottom:                             kindFlag,
+	ExprHide:                               kindNode | kindFlag,
+	ExprKnockout:                           kindNode | kindFlag,
+	ExprRenderCache:                        kindNode,
+	ExprImage:                              kindNode,
+	ExprData:                               kindNode,
+	ExprTarget:                             kindNode,
+	ExprDimension:                          kindNode,
+	ExprFormat:                             kindNode,
+	ExprPrefix:                             kindNode,
+	ExprSuffix:                             kindNode,
+	ExprUnits:                              kindNode,
+	ExprUnitsFormat:                        kindNode,
+	ExprPrecision:                          kindNode,
+	ExprOverrideValue:                      kindNode,
+	ExprSuppressZeros:                      kindNode,
+	ExprStyle:                              kindNode,
+	ExprArrowLength:                        kindNode,
+	ExprTextPositionMode:                   kindNode,
+	ExprExtensionHeight:                    kindNode,
+	ExprExtensionOffset:                    kindNode,
+	ExprKeepTextAligned:                    kindNode,
+	ExprHeight:                             kindNode,
+	ExprOrientation:                        kindNode,
+	ExprLeaderLength:                       kindNode,
+	ExprSegment:                            kindNode,
+	ExprVia:                                kindNode,
+	ExprBlind:                              kindFlag,
+	ExprMicro:                              kindFlag,
+	ExprFree:                               kindNode | kindFlag,
+	ExprZoneLayerConnections:               kindNode,
+	ExprZone:                               kindNode,
+	ExprName:                               kindNode,
+	ExprHatch:                              kindNode,
+	ExprPriority:                           kindNode,
+	ExprConnectPads:                        kindNode,
+	ExprMinThickness:                       kindNode,
+	ExprFilledAreasThickness:               kindNode,
+	ExprKeepout:                            kindNode,
+	ExprTracks:                             kindNode,
+	ExprVias:                               kindNode,
+	ExprPads:                               kindNode,
+	ExprCopperpour:                         kindNode,
+	ExprFootprints:                         kindNode,
+	ExprAllowed:                            kindFlag,
+	ExprNotAllowed:                         kindFlag,
+	ExprSmoothing:                          kindNode,
+	ExprRadius:                             kindNode,
+	ExprIslandRemovalMode:                  kindNode,
+	ExprIslandAreaMin:                      kindNode,
+	ExprPolygon:                            kindNode,
+	ExprFilledPolygon:                      kindNode,
+	ExprFillSegments:                       kindNode,
+	ExprIsland:                             kindFlag,
+	ExprGroup:                              kindNode,
+	ExprMembers:                            kindNode,
+	ExprID:                                 kindNode,
+	ExprGenerated:                          kindNode,
+}
+
+var identifierExprTypes = map[string]ExprType{
+	"kicad_pcb":                              ExprKicadPcb,
+	"version":                                ExprVersion,
+	"generator":                              ExprGenerator,
+	"generator_version":                      ExprGeneratorVersion,
+	"general":                                ExprGeneral,
+	"thickness":                              ExprThickness,
+	"legacy_teardrops":                       ExprLegacyTeardrops,
+	"paper":                                  ExprPaper,
+	"portrait":                               ExprPortrait,
+	"title_block":                            ExprTitleBlock,
+	"title":                                  ExprTitle,
+	"date":                                   ExprDate,
+	"rev":                                    ExprRev,
+	"company":                                ExprCompany,
+	"comment":                                ExprComment,
+	"layers":                                 ExprLayers,
+	"layer":                                  ExprLayer,
+	"signal":                                 ExprSignal,
+	"power":                                  ExprPower,
+	"mixed":                                  ExprMixed,
+	"jumper":                                 ExprJumper,
+	"user":                                   ExprUser,
+	"stackup":                                ExprStackup,
+	"dielectric_constraints":                 ExprDielectricConstraints,
+	"copper_finish":                          ExprCopperFinish,
+	"edge_connector":                         ExprEdgeConnector,
+	"castellated_pads":                       ExprCastellatedPads,
+	"edge_plating":                           ExprEdgePlating,
+	"epsilon_r":                              ExprEpsilonR,
+	"loss_tangent":                           ExprLossTangent,
+	"material":                               ExprMaterial,
+	"color":                                  ExprColor,
+	"addsublayer":                            ExprAddsublayer,
+	"setup":                                  ExprSetup,
+	"pad_to_mask_clearance":                  ExprPadToMaskClearance,
+	"solder_mask_min_width":                  ExprSolderMaskMinWidth,
+	"pad_to_paste_clearance":                 ExprPadToPasteClearance,
+	"pad_to_paste_clearance_ratio":           ExprPadToPasteClearanceRatio,
+	"allow_soldermask_bridges_in_footprints": ExprAllowSoldermaskBridgesInFootprints,
+	"aux_axis_origin":                        ExprAuxAxisOrigin,
+	"grid_origin":                            ExprGridOrigin,
+	"pcbplotparams":                          ExprPcbplotparams,
+	"layerselection":                         ExprLayerselection,
+	"plot_on_all_layers_selection":           ExprPlotOnAllLayersSelection,
+	"disableapertmacros":                     ExprDisableapertmacros,
+	"usegerberextensions":                    ExprUsegerberextensions,
+	"usegerberattributes":                    ExprUsegerberattributes,
+	"usegerberadvancedattributes":            ExprUsegerberadvancedattributes,
+	"creategerberjobfile":                    ExprCreategerberjobfile,
+	"dashed_line_dash_ratio":                 ExprDashedLineDashRatio,
+	"dashed_line_gap_ratio":                  ExprDashedLineGapRatio,
+	"svgprecision":                           ExprSvgprecision,
+	"svguseinch":                             ExprSvguseinch,
+	"excludeedgelayer":                       ExprExcludeedgelayer,
+	"linewidth":                              ExprLinewidth,
+	"plotframeref":                           ExprPlotframeref,
+	"viasonmask":                             ExprViasonmask,
+	"mode":                                   ExprMode,
+	"useauxorigin":                           ExprUseauxorigin,
+	"hpglpennumber":                          ExprHpglpennumber,
+	"hpglpenspeed":                           ExprHpglpenspeed,
+	"hpglpendiameter":                        ExprHpglpendiameter,
+	"pdf_front_fp_property_popups":           ExprPdfFrontFpPropertyPopups,
+	"pdf_back_fp_property_popups":            ExprPdfBackFpPropertyPopups,
+	"dxfpolygonmode":                         ExprDxfpolygonmode,
+	"dxfimperialunits":                       ExprDxfimperialunits,
+	"dxfusepcbnewfont":                       ExprDxfusepcbnewfont,
+	"psnegative":                             ExprPsnegative,
+	"psa4output":                             ExprPsa4output,
+	"plotreference":                          ExprPlotreference,
+	"plotvalue":                              ExprPlotvalue,
+	"plotfptext":                             ExprPlotfptext,
+	"plotinvisibletext":                      ExprPlotinvisibletext,
+	"sketchpadsonfab":                        ExprSketchpadsonfab,
+	"subtractmaskfromsilk":                   ExprSubtractmaskfromsilk,
+	"outputformat":                           ExprOutputformat,
+	"mirror":                                 ExprMirror,
+	"drillshape":                             ExprDrillshape,
+	"scaleselection":                         ExprScaleselection,
+	"outputdirectory":                        ExprOutputdirectory,
+	"net":                                    ExprNet,
+	"net_class":                              ExprNetClass,
+	"add_net":                                ExprAddNet,
+	"clearance":                              ExprClearance,
+	"trace_width":                            ExprTraceWidth,
+	"via_dia":                                ExprViaDia,
+	"via_drill":                              ExprViaDrill,
+	"uvia_dia":                               ExprUviaDia,
+	"uvia_drill":                             ExprUviaDrill,
+	"diff_pair_width":                        ExprDiffPairWidth,
+	"diff_pair_gap":                          ExprDiffPairGap,
+	"property":                               ExprProperty,
+	"footprint":                              ExprFootprint,
+	"module":                                 ExprFootprint,
+	"locked":                                 ExprLocked,
+	"unlocked":                               ExprUnlocked,
+	"placed":                                 ExprPlaced,
+	"tedit":                                  ExprTedit,
+	"tstamp":                                 ExprTstamp,
+	"uuid":                                   ExprUUID,
+	"at":                                     ExprAt,
+	"descr":                                  ExprDescr,
+	"tags":                                   ExprTags,
+	"path":                                   ExprPath,
+	"sheetname":                              ExprSheetname,
+	"sheetfile":                              ExprSheetfile,
+	"attr":                                   ExprAttr,
+	"smd":                                    ExprSmd,
+	"through_hole":                           ExprThroughHole,
+	"board_only":                             ExprBoardOnly,
+	"exclude_from_pos_files":                 ExprExcludeFromPosFiles,
+	"exclude_from_bom":                       ExprExcludeFromBom,
+	"allow_missing_courtyard":                ExprAllowMissingCourtyard,
+	"dnp":                                    ExprDnp,
+	"fp_text":                                ExprFpText,
+	"reference":                              ExprReference,
+	"value":                                  ExprValueToken,
+	"fp_line":                                ExprFpLine,
+	"fp_rect":                                ExprFpRect,
+	"fp_circle":                              ExprFpCircle,
+	"fp_arc":                                 ExprFpArc,
+	"fp_poly":                                ExprFpPoly,
+	"fp_curve":                               ExprFpCurve,
+	"fp_text_box":                            ExprFpTextBox,
+	"model":                                  ExprModel,
+	"offset":                                 ExprOffset,
+	"scale":                                  ExprScale,
+	"rotate":                                 ExprRotate,
+	"xyz":                                    ExprXyz,
+	"zone_connect":                           ExprZoneConnect,
+	"solder_mask_margin":                     ExprSolderMaskMargin,
+	"solder_paste_margin":                    ExprSolderPasteMargin,
+	"solder_paste_ratio":                     ExprSolderPasteRatio,
+	"solder_paste_margin_ratio":              ExprSolderPasteMarginRatio,
+	"autoplace_cost90":                       ExprAutoplaceCost90,
+	"autoplace_cost180":                      ExprAutoplaceCost180,
+	"private_layers":                         ExprPrivateLayers,
+	"net_tie_pad_groups":                     ExprNetTiePadGroups,
+	"pad":                                    ExprPad,
+	"thru_hole":                              ExprThruHole,
+	"np_thru_hole":                           ExprNpThruHole,
+	"connect":                                ExprConnect,
+	"circle":                                 ExprCircle,
+	"rect":                                   ExprRect,
+	"oval":                                   ExprOval,
+	"roundrect":                              ExprRoundrect,
+	"trapezoid":                              ExprTrapezoid,
+	"custom":                                 ExprCustom,
+	"drill":                                  ExprDrill,
+	"size":                                   ExprSize,
+	"rect_delta":                             ExprRectDelta,
+	"roundrect_rratio":                       ExprRoundrectRratio,
+	"chamfer_ratio":                          ExprChamferRatio,
+	"chamfer":                                ExprChamfer,
+	"top_left":                               ExprTopLeft,
+	"top_right":                              ExprTopRight,
+	"bottom_left":                            ExprBottomLeft,
+	"bottom_right":                           ExprBottomRight,
+	"pinfunction":                            ExprPinfunction,
+	"pintype":                                ExprPintype,
+	"die_length":                             ExprDieLength,
+	"remove_unused_layers":                   ExprRemoveUnusedLayers,
+	"keep_end_layers":                        ExprKeepEndLayers,
+	"thermal_width":                          ExprThermalWidth,
+	"thermal_gap":                            ExprThermalGap,
+	"thermal_bridge_width":                   ExprThermalBridgeWidth,
+	"thermal_bridge_angle":                   ExprThermalBridgeAngle,
+	"options":                                ExprOptions,
+	"anchor":                                 ExprAnchor,
+	"primitives":                             ExprPrimitives,
+	"teardrops":                              ExprTeardrops,
+	"gr_line":                                ExprGrLine,
+	"gr_arc":                                 ExprGrArc,
+	"gr_circle":                              ExprGrCircle,
+	"gr_rect":                                ExprGrRect,
+	"gr_poly":                                ExprGrPoly,
+	"gr_curve":                               ExprGrCurve,
+	"gr_text":                                ExprGrText,
+	"gr_text_box":                            ExprGrTextBox,
+	"start":                                  ExprStart,
+	"mid":                                    ExprMid,
+	"end":                                    ExprEnd,
+	"center":                                 ExprCenter,
+	"width":                                  ExprWidth,
+	"angle":                                  ExprAngle,
+	"stroke":                                 ExprStroke,
+	"type":                                   ExprTypeToken,
+	"solid":                                  ExprSolid,
+	"dash":                                   ExprDash,
+	"dot":                                    ExprDot,
+	"dash_dot":                               ExprDashDot,
+	"dash_dot_dot":                           ExprDashDotDot,
+	"default":                                ExprDefault,
+	"fill":                                   ExprFill,
+	"none":                                   ExprNone,
+	"pts":                                    ExprPts,
+	"xy":                                     ExprXy,
+	"arc":                                    ExprArc,
+	"effects":                                ExprEffects,
+	"font":                                   ExprFont,
+	"bold":                                   ExprBold,
+	"italic":                                 ExprItalic,
+	"justify":                                ExprJustify,
+	"left":                                   ExprLeft,
+	"right":                                  ExprRight,
+	"top":                                    ExprTop,
+	"bottom":                                 ExprBottom,
+	"hide":                                   ExprHide,
+	"knockout":                               ExprKnockout,
+	"render_cache":                           ExprRenderCache,
+	"image":                                  ExprImage,
+	"data":                                   ExprData,
+	"target":                                 ExprTarget,
+	"dimension":                              ExprDimension,
+	"format":                                 ExprFormat,
+	"prefix":                                 ExprPrefix,
+	"suffix":                                 ExprSuffix,
+	"units":                                  ExprUnits,
+	"units_format":                           ExprUnitsFormat,
+	"precision":                              ExprPrecision,
+	"override_value":                         ExprOverrideValue,
+	"suppress_zeros":                         ExprSuppressZeros,
+	"style":                                  ExprStyle,
+	"arrow_length":                           ExprArrowLength,
+	"text_position_mode":                     ExprTextPositionMode,
+	"extension_height":                       ExprExtensionHeight,
+	"extension_offset":                       ExprExtensionOffset,
+	"keep_text_aligned":                      ExprKeepTextAligned,
+	"height":                                 ExprHeight,
+	"orientation":                            ExprOrientation,
+	"leader_length":                          ExprLeaderLength,
+	"segment":                                ExprSegment,
+	"via":                                    ExprVia,
+	"blind":                                  ExprBlind,
+	"micro":                                  ExprMicro,
+	"free":                                   ExprFree,
+	"zone_layer_connections":                 ExprZoneLayerConnections,
+	"zone":                                   ExprZone,
+	"name":                                   ExprName,
+	"hatch":                                  ExprHatch,
+	"priority":                               ExprPriority,
+	"connect_pads":                           ExprConnectPads,
+	"min_thickness":                          ExprMinThickness,
+	"filled_areas_thickness":                 ExprFilledAreasThickness,
+	"keepout":                                ExprKeepout,
+	"tracks":                                 ExprTracks,
+	"vias":                                   ExprVias,
+	"pads":                                   ExprPads,
+	"copperpour":                             ExprCopperpour,
+	"footprints":                             ExprFootprints,
+	"allowed":                                ExprAllowed,
+	"not_allowed":                            ExprNotAllowed,
+	"smoothing":                              ExprSmoothing,
+	"radius":                                 ExprRadius,
+	"island_removal_mode":                    ExprIslandRemovalMode,
+	"island_area_min":                        ExprIslandAreaMin,
+	"polygon":                                ExprPolygon,
+	"filled_polygon":                         ExprFilledPolygon,
+	"fill_segments":                          ExprFillSegments,
+	"island":                                 ExprIsland,
+	"group":                                  ExprGroup,
+	"members":                                ExprMembers,
+	"id":                                     ExprID,
+	"generated":                              ExprGenerated,
+}
@@ -17,6 +17,12 @@ func TestIdentifierToExprType(t *testing.T) {
 		{"gr_line", ExprGrLine},
 		{"gr_arc", ExprGrArc},
 		{"at", ExprAt},
+		{"module", ExprFootprint},
+		{"zone", ExprZone},
+		{"fp_line", ExprFpLine},
+		{"property", ExprProperty},
+		{"net_class", ExprNetClass},
+		{"type", ExprTypeToken},
 		{"unknown_type", ExprUnknown},
 		{"", ExprUnknown},
 	}
@@ -46,7 +52,11 @@ func TestExprTypeString(t *testing.T) {
 		{ExprGrLine, "gr_line"},
 		{ExprGrArc, "gr_arc"},
 		{ExprAt, "at"},
+		{ExprGrRect, "gr_rect"},
+		{ExprUUID, "uuid"},
+		{ExprValueToken, "value"},
 		{ExprUnknown, "unknown"},
+		{ExprError, "error"},
 	}
 
 	for _, tt := range tests {
@@ -59,6 +69,32 @@ func TestExprTypeString(t *testing.T) {
 	}
 }
 
+func TestExprTypeKind(t *testing.T) {
+	tests := []struct {
+		exprType ExprType
+		node     bool
+		flag     bool
+	}{
+		{ExprPad, true, false},
+		{ExprSmd, false, true},
+		{ExprLocked, true, true},
+		{ExprHide, true, true},
+		{ExprUnknown, false, false},
+		{ExprError, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exprType.String(), func(t *testing.T) {
+			if got := tt.exprType.IsNode(); got != tt.node {
+				t.Errorf("IsNode: expected %v, got %v", tt.node, got)
+			}
+			if got := tt.exprType.IsFlag(); got != tt.flag {
+				t.Errorf("IsFlag: expected %v, got %v", tt.flag, got)
+			}
+		})
+	}
+}
+
 func TestParseWithExprType(t *testing.T) {
 	tests := []struct {
 		name         string
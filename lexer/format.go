@@ -0,0 +1,202 @@
+package lexer
+
+import (
+	"strconv"
+	"strings"
+)
+
+type lineBreak int
+
+const (
+	breakNested lineBreak = iota // Nested lists on their own lines, atoms on the head line
+	breakNever                   // Everything on one line
+	breakPacked                  // Nested lists share lines up to maxPackedLineLength
+)
+
+// lineBreakRules lists the identifiers that pcbnew does not lay out with the
+// default rule, which puts every nested list on its own line and keeps lists
+// of atoms such as (at x y) on one line.
+var lineBreakRules = map[string]lineBreak{
+	"pts": breakPacked,
+}
+
+const maxPackedLineLength = 99
+
+// fixedDecimalRules lists the identifiers whose numbers pcbnew always writes
+// with six decimals, e.g. (dashed_line_dash_ratio 12.000000).
+var fixedDecimalRules = map[string]bool{
+	"dashed_line_dash_ratio": true,
+	"dashed_line_gap_ratio":  true,
+	"hpglpendiameter":        true,
+}
+
+// FormatNumber formats a number like pcbnew: at most six decimals with
+// trailing zeros removed.
+func FormatNumber(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 6, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// Format returns e laid out the way pcbnew 8 writes board files: tab
+// indentation, one nested list per line and KiCad number formatting. Source
+// formatting recorded in e.Layout is ignored, apart from its line ending.
+func Format(e Expr) string {
+	var sb strings.Builder
+	formatExpr(&sb, e, "", "\t", LineEnding(e))
+	return sb.String()
+}
+
+// FormatFile returns Format(e) followed by a line break, for writing e as a
+// whole file. The line break is the one the source had after e, if any.
+func FormatFile(e Expr) string {
+	newline := LineEnding(e)
+	if e.Layout != nil {
+		if after, ok := firstLineEnding(e.Layout.After); ok {
+			newline = after
+		}
+	}
+	return Format(e) + newline
+}
+
+// LineEnding returns the line ending of the source e was parsed from, "\r\n"
+// or "\n", judged by its first line break. Expressions without a recorded
+// layout or line break get "\n".
+func LineEnding(e Expr) string {
+	if newline, ok := lineEnding(e); ok {
+		return newline
+	}
+	return "\n"
+}
+
+// lineEnding looks for the first line break in the trivia of e, in source
+// order, and reports whether there is one.
+func lineEnding(e Expr) (string, bool) {
+	l := e.Layout
+	if l == nil {
+		return "", false
+	}
+	trivia := []string{l.Before, l.Open}
+	for i, gap := range l.Gaps {
+		trivia = append(trivia, gap)
+		if newline, ok := firstLineEnding(trivia...); ok {
+			return newline, true
+		}
+		trivia = trivia[:0]
+		if i < len(e.Values) {
+			if exprVal, ok := e.Values[i].(ExprValue); ok {
+				if newline, ok := lineEnding(exprVal.Value); ok {
+					return newline, true
+				}
+			}
+		}
+	}
+	return firstLineEnding(append(trivia, l.Raw, l.After)...)
+}
+
+// firstLineEnding returns the line ending of the first line break in texts.
+func firstLineEnding(texts ...string) (string, bool) {
+	for _, text := range texts {
+		if i := strings.IndexByte(text, '\n'); i > 0 && text[i-1] == '\r' {
+			return "\r\n", true
+		} else if i >= 0 {
+			return "\n", true
+		}
+	}
+	return "", false
+}
+
+// FormatInline returns e on a single line, e.g. (pad "1" smd (at 1 0)),
+// which suits listing expressions one per line.
+func FormatInline(e Expr) string {
+	var sb strings.Builder
+	formatInline(&sb, e)
+	return sb.String()
+}
+
+func formatInline(sb *strings.Builder, e Expr) {
+	if e.Type == ExprError && e.Layout != nil {
+		sb.WriteString(strings.Join(strings.Fields(e.Layout.Raw), " "))
+		return
+	}
+	sb.WriteString("(")
+	sb.WriteString(e.Identifier)
+	for _, val := range e.Values {
+		sb.WriteString(" ")
+		if exprVal, ok := val.(ExprValue); ok {
+			formatInline(sb, exprVal.Value)
+		} else {
+			sb.WriteString(val.String())
+		}
+	}
+	sb.WriteString(")")
+}
+
+func lineBreakFor(e Expr) lineBreak {
+	if rule, ok := lineBreakRules[e.Identifier]; ok {
+		return rule
+	}
+	for _, val := range e.Values {
+		if _, ok := val.(ExprValue); ok {
+			return breakNested
+		}
+	}
+	return breakNever
+}
+
+func formatExpr(sb *strings.Builder, e Expr, indent, unit, newline string) {
+	if e.Type == ExprError && e.Layout != nil {
+		// Unreadable source is kept as it was
+		sb.WriteString(e.Layout.Raw)
+		return
+	}
+	sb.WriteString("(")
+	sb.WriteString(e.Identifier)
+
+	rule := lineBreakFor(e)
+	if rule == breakNever {
+		for _, val := range e.Values {
+			sb.WriteString(" ")
+			if exprVal, ok := val.(ExprValue); ok {
+				formatExpr(sb, exprVal.Value, indent, unit, newline)
+			} else if num, ok := val.(NumberValue); ok && fixedDecimalRules[e.Identifier] {
+				sb.WriteString(strconv.FormatFloat(num.Value, 'f', 6, 64))
+			} else {
+				sb.WriteString(val.String())
+			}
+		}
+		sb.WriteString(")")
+		return
+	}
+
+	childIndent := indent + unit
+	lineLength := 0
+	for _, val := range e.Values {
+		exprVal, ok := val.(ExprValue)
+		if !ok {
+			sb.WriteString(" ")
+			sb.WriteString(val.String())
+			continue
+		}
+
+		var child strings.Builder
+		formatExpr(&child, exprVal.Value, childIndent, unit, newline)
+		text := child.String()
+		if rule == breakPacked && lineLength > 0 && lineLength+1+len(text) <= maxPackedLineLength {
+			sb.WriteString(" ")
+			lineLength += 1 + len(text)
+		} else {
+			sb.WriteString(newline)
+			sb.WriteString(childIndent)
+			lineLength = len(childIndent) + len(text)
+		}
+		sb.WriteString(text)
+	}
+	sb.WriteString(newline)
+	sb.WriteString(indent)
+	sb.WriteString(")")
+}
@@ -0,0 +1,120 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{0, "0"},
+		{42, "42"},
+		{-1.5, "-1.5"},
+		{0.0254, "0.0254"},
+		{3.14159265, "3.141593"},
+		{100.05328, "100.05328"},
+		{-0.0000001, "0"},
+		{1e-6, "0.000001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			result := FormatNumber(tt.value)
+			if result != tt.expected {
+				t.Errorf("Expected: %s, Actual: %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"atoms stay on one line",
+			"(at 1.000000 2.5 90)",
+			"(at 1 2.5 90)",
+		},
+		{
+			"nested lists break lines",
+			"(segment (start 0 0) (end 1 1) (net 1))",
+			"(segment\n\t(start 0 0)\n\t(end 1 1)\n\t(net 1)\n)",
+		},
+		{
+			"atoms after the identifier stay on the head line",
+			`(pad "1" smd rect (at 0 0) (layers "F.Cu" "F.Mask"))`,
+			"(pad \"1\" smd rect\n\t(at 0 0)\n\t(layers \"F.Cu\" \"F.Mask\")\n)",
+		},
+		{
+			"deep nesting indents with tabs",
+			"(effects (font (size 1 1)))",
+			"(effects\n\t(font\n\t\t(size 1 1)\n\t)\n)",
+		},
+		{
+			"fixed decimals",
+			"(pcbplotparams (dashed_line_dash_ratio 12) (svgprecision 4))",
+			"(pcbplotparams\n\t(dashed_line_dash_ratio 12.000000)\n\t(svgprecision 4)\n)",
+		},
+		{
+			"keeps CRLF line endings",
+			"(segment\r\n  (start 0 0) (end 1 1))",
+			"(segment\r\n\t(start 0 0)\r\n\t(end 1 1)\r\n)",
+		},
+		{
+			"line ending of a nested list",
+			"(segment (start 0 0) (net\r\n 1))",
+			"(segment\r\n\t(start 0 0)\r\n\t(net 1)\r\n)",
+		},
+		{
+			"polygon points are packed",
+			"(pts " + strings.Repeat("(xy 100.5 200.25) ", 6) + ")",
+			"(pts\n\t" + strings.Repeat("(xy 100.5 200.25) ", 4) + "(xy 100.5 200.25)\n\t(xy 100.5 200.25)\n)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.input)
+			if err != nil {
+				t.Fatalf("Tokenize failed: %v", err)
+			}
+			expr, err := Parse(tokens)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			result := Format(expr)
+
+			if result != tt.expected {
+				t.Errorf("Expected: %q, Actual: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatInline(t *testing.T) {
+	// Arrange
+	tokens, err := Tokenize("(pad \"1\" smd\n\t(at 1.50 0)\n\t(net 1 \"GND\")\n)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	got := FormatInline(expr)
+
+	// Assert
+	want := `(pad "1" smd (at 1.5 0) (net 1 "GND"))`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
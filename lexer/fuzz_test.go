@@ -0,0 +1,148 @@
+package lexer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// maxSeedSize leaves out the largest boards in test_data, which slow the
+// fuzzer down to a few executions per second without adding coverage.
+const maxSeedSize = 64 << 10
+
+// addSeedCorpus seeds f with the boards in test_data and a few small inputs
+// around the edges of the grammar.
+func addSeedCorpus(f *testing.F) {
+	paths, err := filepath.Glob("../test_data/*.kicad_pcb")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		if len(data) <= maxSeedSize {
+			f.Add(string(data))
+		}
+	}
+	for _, input := range []string{
+		"",
+		"(",
+		")",
+		"()",
+		"(a",
+		"(a))",
+		`(a "unterminated`,
+		`"unterminated`,
+		`(a "\`,
+		"(0 F.Cu signal)",
+		`("quoted head" 1)`,
+		"(at +0.5 .5 -.5 1e-3 2.5E+2 5E412345)",
+		"(layerselection 0x00010fc_ffffffff 0x 0xyz)",
+		"(pad \"1\" smd (at 1 2) (net 1 \"GND\") (layers \"F.Cu\"))",
+	} {
+		f.Add(input)
+	}
+}
+
+func FuzzTokenize(f *testing.F) {
+	addSeedCorpus(f)
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens, err := Tokenize(input)
+		// Tokens cover the input without gaps, and only whitespace is trivia
+		var sb strings.Builder
+		for _, token := range tokens {
+			if strings.TrimLeft(token.Leading, " \t\r\n") != "" {
+				t.Errorf("token %q has trivia %q, want whitespace", token.Raw, token.Leading)
+			}
+			sb.WriteString(token.Leading)
+			sb.WriteString(token.Raw)
+		}
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got %T %v, want a *ParseError", err, err)
+			}
+			// Up to the error, the input is tokens and whitespace
+			read := sb.String()
+			if !strings.HasPrefix(input, read) || parseErr.Pos.Offset < len(read) ||
+				strings.TrimLeft(input[len(read):parseErr.Pos.Offset], " \t\r\n") != "" {
+				t.Errorf("tokens spell %q before an error at offset %d of %q", read, parseErr.Pos.Offset, input)
+			}
+			return
+		}
+
+		if len(tokens) == 0 || tokens[len(tokens)-1].Type != EOF {
+			t.Fatal("the last token is not EOF")
+		}
+		if sb.String() != input {
+			t.Errorf("tokens spell %q, want %q", sb.String(), input)
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	addSeedCorpus(f)
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens, err := Tokenize(input)
+		if err != nil {
+			return
+		}
+		types := make([]TokenType, len(tokens))
+		for i, token := range tokens {
+			types[i] = token.Type
+		}
+
+		expr, err := Parse(tokens)
+		for i, token := range tokens {
+			if token.Type != types[i] {
+				t.Fatalf("Parse changed token %d from %v to %v", i, types[i], token.Type)
+			}
+		}
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got %T %v, want a *ParseError", err, err)
+			}
+		} else {
+			if got := expr.String(); got != input {
+				t.Errorf("round trip gave %q, want %q", got, input)
+			}
+			_ = Format(expr)
+			_ = FormatInline(expr)
+		}
+
+		recovered, diagnostics := ParseRecover(tokens)
+		if (err == nil) != (len(diagnostics) == 0) {
+			t.Errorf("Parse error %v but ParseRecover found %d problems", err, len(diagnostics))
+		}
+		_ = recovered.String()
+		_ = Format(recovered)
+	})
+}
+
+// FuzzParseRecover reads input the way lin_router check does: whatever the
+// tokenizer returned before an error is still parsed, so ParseRecover also
+// sees empty token slices and slices without an EOF token.
+func FuzzParseRecover(f *testing.F) {
+	addSeedCorpus(f)
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens, err := Tokenize(input)
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got %T %v, want a *ParseError", err, err)
+			}
+		}
+
+		recovered, diagnostics := ParseRecover(tokens)
+		if err == nil && len(diagnostics) == 0 && recovered.String() != input {
+			t.Errorf("round trip gave %q, want %q", recovered.String(), input)
+		}
+		_ = Format(recovered)
+		_ = FormatInline(recovered)
+	})
+}
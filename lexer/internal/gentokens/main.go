@@ -0,0 +1,179 @@
+// Command gentokens generates the ExprType constants and lookup tables of
+// package lexer from the token specification in tokens.txt.
+//
+//	go run ./internal/gentokens -spec tokens.txt -o expr_type_gen.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"strings"
+)
+
+type token struct {
+	identifier string
+	kind       string // node, flag or both
+	aliases    []string
+	name       string // Go constant name without the Expr prefix
+}
+
+// initialisms are written in upper case in constant names, as Go does.
+var initialisms = map[string]string{
+	"id":   "ID",
+	"uuid": "UUID",
+}
+
+// constantName returns the Go name for identifier, e.g. GrLine for gr_line.
+func constantName(identifier string) string {
+	var sb strings.Builder
+	for _, part := range strings.Split(identifier, "_") {
+		if initialism, ok := initialisms[part]; ok {
+			sb.WriteString(initialism)
+		} else if part != "" {
+			sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return sb.String()
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if !(ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' || ch == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSpec reads the token specification, see tokens.txt for the format.
+func parseSpec(r io.Reader) ([]token, error) {
+	tokens := []token{}
+	seen := map[string]int{}  // Identifiers and aliases -> line
+	names := map[string]int{} // Constant names -> line
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing kind for %s", line, fields[0])
+		}
+
+		tok := token{identifier: fields[0], kind: fields[1]}
+		switch tok.kind {
+		case "node", "flag", "both":
+		default:
+			return nil, fmt.Errorf("line %d: unknown kind %q, want node, flag or both", line, tok.kind)
+		}
+		for _, field := range fields[2:] {
+			if name, ok := strings.CutPrefix(field, "name="); ok {
+				tok.name = name
+			} else {
+				tok.aliases = append(tok.aliases, field)
+			}
+		}
+		if tok.name == "" {
+			tok.name = constantName(tok.identifier)
+		}
+
+		for _, identifier := range append([]string{tok.identifier}, tok.aliases...) {
+			if !isIdentifier(identifier) {
+				return nil, fmt.Errorf("line %d: invalid identifier %q", line, identifier)
+			}
+			if previous, ok := seen[identifier]; ok {
+				return nil, fmt.Errorf("line %d: %s is already defined on line %d", line, identifier, previous)
+			}
+			seen[identifier] = line
+		}
+		if previous, ok := names[tok.name]; ok {
+			return nil, fmt.Errorf("line %d: constant Expr%s is already defined on line %d", line, tok.name, previous)
+		}
+		names[tok.name] = line
+		tokens = append(tokens, tok)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// generate returns the formatted Go source for tokens.
+func generate(tokens []token, spec string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gentokens from %s; DO NOT EDIT.\n\n", spec)
+	buf.WriteString("package lexer\n\n")
+
+	buf.WriteString("const (\n")
+	for i, tok := range tokens {
+		if i == 0 {
+			fmt.Fprintf(&buf, "Expr%s ExprType = iota + 1\n", tok.name)
+		} else {
+			fmt.Fprintf(&buf, "Expr%s\n", tok.name)
+		}
+	}
+	buf.WriteString(")\n\n")
+
+	buf.WriteString("var exprTypeNames = [...]string{\n")
+	for _, tok := range tokens {
+		fmt.Fprintf(&buf, "Expr%s: %q,\n", tok.name, tok.identifier)
+	}
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("var exprTypeKinds = [...]tokenKind{\n")
+	for _, tok := range tokens {
+		kind := map[string]string{"node": "kindNode", "flag": "kindFlag", "both": "kindNode | kindFlag"}[tok.kind]
+		fmt.Fprintf(&buf, "Expr%s: %s,\n", tok.name, kind)
+	}
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("var identifierExprTypes = map[string]ExprType{\n")
+	for _, tok := range tokens {
+		fmt.Fprintf(&buf, "%q: Expr%s,\n", tok.identifier, tok.name)
+		for _, alias := range tok.aliases {
+			fmt.Fprintf(&buf, "%q: Expr%s,\n", alias, tok.name)
+		}
+	}
+	buf.WriteString("}\n")
+
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	specPath := flag.String("spec", "tokens.txt", "token specification to read")
+	outPath := flag.String("o", "expr_type_gen.go", "Go file to write")
+	flag.Parse()
+
+	if err := run(*specPath, *outPath); err != nil {
+		fmt.Fprintln(os.Stderr, "gentokens:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, outPath string) error {
+	file, err := os.Open(specPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tokens, err := parseSpec(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", specPath, err)
+	}
+	src, err := generate(tokens, specPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, src, 0644)
+}
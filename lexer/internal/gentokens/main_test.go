@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGeneratedFileIsUpToDate(t *testing.T) {
+	// Arrange
+	spec, err := os.Open("../../tokens.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spec.Close()
+	want, err := os.ReadFile("../../expr_type_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	tokens, err := parseSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := generate(tokens, "tokens.txt")
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Error("expr_type_gen.go is out of date, run go generate ./lexer")
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []token
+		wantErr string
+	}{
+		{
+			"comments and aliases",
+			"# header\n\nfootprint node module # old name\ngr_line node\n",
+			[]token{
+				{identifier: "footprint", kind: "node", aliases: []string{"module"}, name: "Footprint"},
+				{identifier: "gr_line", kind: "node", name: "GrLine"},
+			},
+			"",
+		},
+		{
+			"initialisms and names",
+			"uuid node\ntype node name=TypeToken\n",
+			[]token{
+				{identifier: "uuid", kind: "node", name: "UUID"},
+				{identifier: "type", kind: "node", name: "TypeToken"},
+			},
+			"",
+		},
+		{"missing kind", "pad\n", nil, "line 1: missing kind"},
+		{"unknown kind", "pad list\n", nil, "unknown kind"},
+		{"duplicate", "pad node\n\npad flag\n", nil, "line 3: pad is already defined on line 1"},
+		{"alias clash", "module node\nfootprint node module\n", nil, "module is already defined"},
+		{"name clash", "gr_line node\ngrline node name=GrLine\n", nil, "constant ExprGrLine is already defined"},
+		{"invalid identifier", "Pad node\n", nil, "invalid identifier"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got, err := parseSpec(strings.NewReader(tt.spec))
+
+			// Assert
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d tokens, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].identifier != tt.want[i].identifier || got[i].kind != tt.want[i].kind ||
+					got[i].name != tt.want[i].name || strings.Join(got[i].aliases, ",") != strings.Join(tt.want[i].aliases, ",") {
+					t.Errorf("token %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
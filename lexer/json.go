@@ -0,0 +1,125 @@
+package lexer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonNode is an Expr or one of its values in JSON. Every node has a type:
+//
+//	{"type": "expr", "identifier": "at", "pos": {...}, "values": [...]}
+//	{"type": "number", "value": 1.5}
+//	{"type": "string", "value": "GND"}
+//	{"type": "identifier", "value": "smd"}
+type jsonNode struct {
+	Type       string        `json:"type"`
+	Identifier string        `json:"identifier,omitempty"`
+	Pos        *jsonPosition `json:"pos,omitempty"` // Missing for expressions built in code
+	Values     []jsonNode    `json:"values,omitempty"`
+	Value      any           `json:"value,omitempty"` // float64 for numbers, string otherwise
+}
+
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// MarshalJSON writes the expression as a tree of typed nodes, see jsonNode.
+// Source formatting is left out; malformed expressions kept by ParseRecover
+// cannot be written.
+func (e Expr) MarshalJSON() ([]byte, error) {
+	node, err := exprToJSON(e)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(node)
+}
+
+// UnmarshalJSON reads an expression written by MarshalJSON. The result has no
+// Layout, so it is written in pcbnew's style.
+func (e *Expr) UnmarshalJSON(data []byte) error {
+	var node jsonNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	if node.Type != "expr" {
+		return fmt.Errorf("json: expected a node of type expr, got %q", node.Type)
+	}
+	expr, err := exprFromJSON(node)
+	if err != nil {
+		return err
+	}
+	*e = expr
+	return nil
+}
+
+func exprToJSON(e Expr) (jsonNode, error) {
+	if e.Type == ExprError {
+		return jsonNode{}, fmt.Errorf("json: cannot encode the malformed expression at %s", e.Pos)
+	}
+	node := jsonNode{Type: "expr", Identifier: e.Identifier, Values: []jsonNode{}}
+	if e.Pos != (Position{}) {
+		node.Pos = &jsonPosition{Offset: e.Pos.Offset, Line: e.Pos.Line, Column: e.Pos.Column}
+	}
+	for _, val := range e.Values {
+		switch v := val.(type) {
+		case ExprValue:
+			child, err := exprToJSON(v.Value)
+			if err != nil {
+				return jsonNode{}, err
+			}
+			node.Values = append(node.Values, child)
+		case NumberValue:
+			node.Values = append(node.Values, jsonNode{Type: "number", Value: v.Value})
+		case StringValue:
+			node.Values = append(node.Values, jsonNode{Type: "string", Value: v.Value})
+		case IdentifierValue:
+			node.Values = append(node.Values, jsonNode{Type: "identifier", Value: v.Value})
+		}
+	}
+	return node, nil
+}
+
+func exprFromJSON(node jsonNode) (Expr, error) {
+	if node.Identifier == "" {
+		return Expr{}, fmt.Errorf("json: expr without an identifier")
+	}
+	e := Expr{
+		Type:       IdentifierToExprType(node.Identifier),
+		Identifier: node.Identifier,
+		Values:     make([]Value, 0, len(node.Values)),
+	}
+	if node.Pos != nil {
+		e.Pos = Position{Offset: node.Pos.Offset, Line: node.Pos.Line, Column: node.Pos.Column}
+	}
+	for i, child := range node.Values {
+		switch child.Type {
+		case "expr":
+			nested, err := exprFromJSON(child)
+			if err != nil {
+				return Expr{}, err
+			}
+			e.Values = append(e.Values, ExprValue{Value: nested})
+		case "number":
+			n, ok := child.Value.(float64)
+			if !ok {
+				return Expr{}, fmt.Errorf("json: value %d of (%s) is not a number", i, node.Identifier)
+			}
+			e.Values = append(e.Values, NumberValue{Value: n})
+		case "string", "identifier":
+			s, ok := child.Value.(string)
+			if !ok {
+				return Expr{}, fmt.Errorf("json: value %d of (%s) is not a string", i, node.Identifier)
+			}
+			if child.Type == "string" {
+				e.Values = append(e.Values, StringValue{Value: s})
+			} else {
+				e.Values = append(e.Values, IdentifierValue{Value: s})
+			}
+		default:
+			return Expr{}, fmt.Errorf("json: value %d of (%s) has unknown type %q", i, node.Identifier, child.Type)
+		}
+	}
+	return e, nil
+}
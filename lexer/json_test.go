@@ -0,0 +1,130 @@
+package lexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExprJSON(t *testing.T) {
+	// Arrange
+	tokens, err := Tokenize(`(pad "1" smd (at -1 0.5) (layers F.Cu))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	data, err := json.Marshal(expr)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := `{"type":"expr","identifier":"pad","pos":{"offset":0,"line":1,"column":1},"values":[` +
+		`{"type":"string","value":"1"},` +
+		`{"type":"identifier","value":"smd"},` +
+		`{"type":"expr","identifier":"at","pos":{"offset":13,"line":1,"column":14},"values":[{"type":"number","value":-1},{"type":"number","value":0.5}]},` +
+		`{"type":"expr","identifier":"layers","pos":{"offset":25,"line":1,"column":26},"values":[{"type":"identifier","value":"F.Cu"}]}]}`
+	if string(data) != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, data)
+	}
+}
+
+func TestExprJSON_RoundTripsTestData(t *testing.T) {
+	paths, err := filepath.Glob("../test_data/*.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			// Arrange
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := Tokenize(string(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expr, err := Parse(tokens)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(expr)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			// Act
+			var imported Expr
+			err = json.Unmarshal(data, &imported)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			again, err := json.Marshal(imported)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !bytes.Equal(data, again) {
+				t.Error("Expected JSON -> Expr -> JSON to give the same JSON")
+			}
+			if Format(imported) != strings.ReplaceAll(Format(expr), "\r\n", "\n") {
+				t.Error("Expected the imported tree to format like the parsed one")
+			}
+		})
+	}
+}
+
+func TestExprJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"not an expr", `{"type":"number","value":1}`, `expected a node of type expr, got "number"`},
+		{"missing identifier", `{"type":"expr","values":[]}`, "expr without an identifier"},
+		{"string as number", `{"type":"expr","identifier":"at","values":[{"type":"number","value":"1"}]}`, "value 0 of (at) is not a number"},
+		{"unknown type", `{"type":"expr","identifier":"at","values":[{"type":"bool","value":true}]}`, `value 0 of (at) has unknown type "bool"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var expr Expr
+
+			// Act
+			err := json.Unmarshal([]byte(tt.input), &expr)
+
+			// Assert
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestExprJSON_MalformedExpression(t *testing.T) {
+	// Arrange
+	tokens, err := Tokenize("(kicad_pcb (pad \"1\" ()))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, _ := ParseRecover(tokens)
+
+	// Act
+	_, err = json.Marshal(expr)
+
+	// Assert
+	if err == nil {
+		t.Error("Expected an error for a malformed expression")
+	}
+}
@@ -0,0 +1,148 @@
+package lexer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Layout records the source text around an Expr that the tree itself does not
+// hold, so that an unmodified tree is written back byte for byte. Parse sets
+// it on every expression it reads from tokens produced by Tokenize.
+type Layout struct {
+	Before  string   // Trivia before '(' of the root expression
+	Open    string   // Trivia between '(' and the identifier
+	Head    string   // Source text of the identifier
+	Gaps    []string // Gaps[i] precedes Values[i], the last gap precedes ')'
+	Lexemes []string // Source text of scalar Values[i], empty for nested exprs
+	After   string   // Trivia after ')' of the root expression
+	Raw     string   // Source text of an ExprError node, which has no values
+}
+
+// aligned reports whether the layout still describes every value of e. When
+// values have been appended since parsing, the extra values get fresh
+// formatting and the last gap still precedes ')'.
+func (l *Layout) aligned(e Expr) bool {
+	return len(l.Gaps) == len(e.Values)+1
+}
+
+// writeLayout writes e with its source formatting. Nodes added since parsing
+// are indented by unit per level, the indentation of the enclosing source
+// unless e's own gaps show another.
+func (e Expr) writeLayout(sb *strings.Builder, unit string) {
+	l := e.Layout
+	if u := l.indentUnit(); u != "" {
+		unit = u
+	}
+	sb.WriteString(l.Before)
+	if e.Type == ExprError {
+		sb.WriteString(l.Raw)
+		sb.WriteString(l.After)
+		return
+	}
+	sb.WriteString("(")
+	sb.WriteString(l.Open)
+	if l.Head != "" && lexemeMatches(l.Head, IdentifierValue{Value: e.Identifier}) {
+		sb.WriteString(l.Head)
+	} else {
+		sb.WriteString(e.Identifier)
+	}
+
+	original := len(l.Gaps) - 1
+	if l.aligned(e) {
+		original = len(e.Values)
+	}
+	for i, val := range e.Values {
+		gap := l.freshGap()
+		if i < original {
+			gap = l.Gaps[i]
+		}
+		sb.WriteString(gap)
+
+		if exprVal, ok := val.(ExprValue); ok {
+			if exprVal.Value.Layout != nil {
+				exprVal.Value.writeLayout(sb, unit)
+			} else {
+				// Indent new nodes like the line they start on
+				indent, _ := lineIndent(gap)
+				newline := "\n"
+				if strings.Contains(gap, "\r\n") {
+					newline = "\r\n"
+				}
+				formatExpr(sb, exprVal.Value, indent, unit, newline)
+			}
+			continue
+		}
+		if i < original && i < len(l.Lexemes) && l.Lexemes[i] != "" && lexemeMatches(l.Lexemes[i], val) {
+			sb.WriteString(l.Lexemes[i])
+		} else {
+			sb.WriteString(val.String())
+		}
+	}
+
+	if len(l.Gaps) > 0 {
+		sb.WriteString(l.Gaps[len(l.Gaps)-1])
+	}
+	sb.WriteString(")")
+	sb.WriteString(l.After)
+}
+
+// indentUnit returns what one level of nesting adds to the indentation in the
+// source, e.g. "\t" or "  ", judged by how much deeper than the closing ')'
+// the values of the expression are indented. It returns "" when the values
+// are not on lines of their own.
+func (l *Layout) indentUnit() string {
+	if len(l.Gaps) < 2 {
+		return ""
+	}
+	closing, ok := lineIndent(l.Gaps[len(l.Gaps)-1])
+	if !ok {
+		return ""
+	}
+	for _, gap := range l.Gaps[:len(l.Gaps)-1] {
+		if indent, ok := lineIndent(gap); ok && len(indent) > len(closing) && strings.HasPrefix(indent, closing) {
+			return indent[len(closing):]
+		}
+	}
+	return ""
+}
+
+// lineIndent returns the whitespace after the last line break of gap, and
+// whether there is one.
+func lineIndent(gap string) (string, bool) {
+	lineStart := strings.LastIndex(gap, "\n")
+	if lineStart < 0 {
+		return "", false
+	}
+	return gap[lineStart+1:], true
+}
+
+// freshGap returns the separator for a value added after parsing, reusing the
+// line break and indentation of the last original value on its own line.
+func (l *Layout) freshGap() string {
+	for i := len(l.Gaps) - 2; i >= 0; i-- {
+		if strings.ContainsAny(l.Gaps[i], "\n") {
+			return l.Gaps[i]
+		}
+	}
+	return " "
+}
+
+// lexemeMatches reports whether the source text still denotes val, so it
+// can be written instead of val's default formatting.
+func lexemeMatches(lexeme string, val Value) bool {
+	switch v := val.(type) {
+	case NumberValue:
+		n, err := strconv.ParseFloat(lexeme, 64)
+		return err == nil && n == v.Value
+	case StringValue:
+		return isQuoted(lexeme) && unescape(lexeme[1:len(lexeme)-1]) == v.Value
+	case IdentifierValue:
+		return lexeme == v.Value || (isQuoted(lexeme) && unescape(lexeme[1:len(lexeme)-1]) == v.Value)
+	default:
+		return false
+	}
+}
+
+func isQuoted(lexeme string) bool {
+	return len(lexeme) >= 2 && lexeme[0] == '"' && lexeme[len(lexeme)-1] == '"'
+}
@@ -1,8 +1,12 @@
 package lexer
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
-	"regexp"
+	"io"
+	"io/fs"
+	"strings"
 )
 
 func isWhitespace(ch byte) bool {
@@ -13,81 +17,345 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func isPositiveDigit(ch byte) bool {
-	return '1' <= ch && ch <= '9'
-}
-
 func isLetter(ch byte) bool {
 	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ch == '_'
 }
 
-var numberRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
+// isValidNumber reports whether b is a number in a KiCad file:
+//
+//	number   = [ "+" | "-" ] mantissa [ exponent ]
+//	mantissa = digits [ "." [ digits ] ] | "." digits
+//	exponent = ( "e" | "E" ) [ "+" | "-" ] digits
+//
+// e.g. 42, -2.5, +0.5, .5, 1. and 1e-3. An exponent without a sign needs a
+// decimal point in the mantissa, since 5E41 and the like are also hexadecimal
+// timestamps, e.g. the (tedit 5E412345) of KiCad 5 footprints, and are read
+// as identifiers.
+func isValidNumber(b []byte) bool {
+	digits := func(i int) int {
+		for i < len(b) && isDigit(b[i]) {
+			i++
+		}
+		return i
+	}
+	i := 0
+	if i < len(b) && (b[i] == '-' || b[i] == '+') {
+		i++
+	}
+	start := i
+	i = digits(i)
+	hasDigits := i > start
+	hasPoint := false
+	if i < len(b) && b[i] == '.' {
+		hasPoint = true
+		fraction := i + 1
+		i = digits(fraction)
+		hasDigits = hasDigits || i > fraction
+	}
+	if !hasDigits {
+		return false
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		signed := i < len(b) && (b[i] == '-' || b[i] == '+')
+		if signed {
+			i++
+		} else if !hasPoint {
+			return false
+		}
+		exponent := i
+		if i = digits(exponent); i == exponent {
+			return false
+		}
+	}
+	return i == len(b)
+}
+
+// isValidHex reports whether b is a hexadecimal literal such as the layer
+// mask 0x00010fc_ffffffff, whose digit groups are separated by underscores.
+func isValidHex(b []byte) bool {
+	if len(b) < 3 || b[0] != '0' || (b[1] != 'x' && b[1] != 'X') {
+		return false
+	}
+	for _, ch := range b[2:] {
+		if !isHexDigit(ch) && ch != '_' {
+			return false
+		}
+	}
+	return true
+}
 
-func isValidNumber(s string) bool {
-	return numberRegex.MatchString(s)
+// isNumberStart reports whether a token starting with ch, followed by next,
+// is read as a number if it turns out to be a valid one.
+func isNumberStart(ch byte, next []byte) bool {
+	if isDigit(ch) {
+		return true
+	}
+	if (ch == '-' || ch == '+' || ch == '.') && len(next) > 1 {
+		return isDigit(next[1]) || (ch != '.' && next[1] == '.')
+	}
+	return false
 }
 
 func isIdentifierChar(ch byte) bool {
 	return isLetter(ch) || isDigit(ch) || ch == '-' || ch == '.' || ch == ':' || ch == '*'
 }
 
-func readNextToken(data string, pos int) (Token, int, error) {
-	for pos < len(data) {
+// isSymbolChar reports whether ch can be part of a number or identifier in a
+// board file, which is every byte but whitespace, parentheses, quotes and
+// control characters. KiCad 5 writes net names such as +VIN and /SDA
+// unquoted.
+func isSymbolChar(ch byte) bool {
+	return !isWhitespace(ch) && ch != '(' && ch != ')' && ch != '"' && ch >= ' ' && ch != 0x7f
+}
+
+// Position is a location in the source text.
+type Position struct {
+	Offset int // Byte offset, starting at 0
+	Line   int // Starting at 1
+	Column int // Byte column, starting at 1
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Scanner reads tokens one at a time from an io.Reader.
+type Scanner struct {
+	r       *bufio.Reader
+	pos     Position
+	trivia  []byte
+	lexeme  []byte
+	line    []byte            // Current source line up to pos, for error excerpts
+	strings map[string]string // Interned trivia and lexemes, most of which repeat
+	err     error             // First read error other than io.EOF
+	done    bool
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:       bufio.NewReaderSize(r, 64*1024),
+		pos:     Position{Line: 1, Column: 1},
+		strings: make(map[string]string),
+	}
+}
+
+// peek returns up to n bytes without reading them, none after a read error.
+func (s *Scanner) peek(n int) []byte {
+	if s.err != nil {
+		return nil
+	}
+	b, err := s.r.Peek(n)
+	s.setErr(err)
+	return b
+}
+
+func (s *Scanner) advance(into *[]byte) byte {
+	ch, err := s.r.ReadByte()
+	s.setErr(err)
+	*into = append(*into, ch)
+	s.pos.Offset++
+	if ch == '\n' {
+		s.pos.Line++
+		s.pos.Column = 1
+		s.line = s.line[:0]
+	} else {
+		s.pos.Column++
+		s.line = append(s.line, ch)
+	}
+	return ch
+}
+
+func (s *Scanner) setErr(err error) {
+	if err != nil && !errors.Is(err, io.EOF) && s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *Scanner) intern(b []byte) string {
+	if len(b) > 32 {
+		return string(b)
+	}
+	if str, ok := s.strings[string(b)]; ok {
+		return str
+	}
+	str := string(b)
+	s.strings[str] = str
+	return str
+}
+
+// Next returns the next token. After the input is exhausted it returns an EOF
+// token carrying the trailing whitespace, followed by io.EOF. An error reading
+// the input ends the tokens and is returned as it is. Input that is not a
+// token or whitespace, such as an unterminated string or a control character
+// outside strings, gives a *ParseError.
+func (s *Scanner) Next() (Token, error) {
+	if s.done {
+		return Token{}, io.EOF
+	}
+	token, err := s.next()
+	if s.err != nil {
+		s.done = true
+		return Token{}, s.err
+	}
+	return token, err
+}
+
+func (s *Scanner) next() (Token, error) {
+	s.trivia = s.trivia[:0]
+	s.lexeme = s.lexeme[:0]
+
+	for {
+		next := s.peek(2)
+		if len(next) == 0 {
+			s.done = true
+			return s.token(EOF, s.pos), nil
+		}
+		ch := next[0]
+		hasNext := len(next) > 1
+		start := s.pos
+
 		switch {
-		case isWhitespace(data[pos]):
-			pos++
-			continue
-		case data[pos] == '(':
-			return Token{Type: OPEN_PAREN, Value: "("}, pos + 1, nil
-		case data[pos] == ')':
-			return Token{Type: CLOSE_PAREN, Value: ")"}, pos + 1, nil
-		case data[pos] == '"':
-			value := ""
-			pos++
-			for data[pos] != '"' {
-				value += string(data[pos])
-				pos++
+		case isWhitespace(ch):
+			s.advance(&s.trivia)
+		case ch == '(':
+			s.advance(&s.lexeme)
+			return s.token(OPEN_PAREN, start), nil
+		case ch == ')':
+			s.advance(&s.lexeme)
+			return s.token(CLOSE_PAREN, start), nil
+		case ch == '"':
+			var firstLine []byte // Set when the string spans lines, for the error excerpt
+			escaped := false
+			s.advance(&s.lexeme)
+			for {
+				next := s.peek(1)
+				if len(next) == 0 {
+					if firstLine == nil {
+						firstLine = s.line
+					}
+					return Token{}, &ParseError{
+						Pos:      start,
+						Expected: "closing '\"'",
+						Got:      "end of input",
+						Excerpt:  excerpt(string(firstLine), start),
+						Err:      io.ErrUnexpectedEOF,
+					}
+				}
+				if next[0] == '\n' && firstLine == nil {
+					firstLine = append([]byte{}, s.line...)
+				}
+				ch := s.advance(&s.lexeme)
+				if escaped {
+					// The escaped character never ends the string
+					escaped = false
+					continue
+				}
+				if ch == '"' {
+					break
+				}
+				escaped = ch == '\\'
+			}
+			return s.token(STRING, start), nil
+		case ch == '0' && hasNext && (next[1] == 'x' || next[1] == 'X'):
+			// Hexadecimal literals such as layer masks, 0x00010fc_ffffffff
+			for b := s.peek(1); len(b) > 0 && isSymbolChar(b[0]); b = s.peek(1) {
+				s.advance(&s.lexeme)
+			}
+			if isValidHex(s.lexeme) {
+				return s.token(HEX, start), nil
 			}
-			return Token{Type: STRING, Value: value}, pos + 1, nil
-		case isPositiveDigit(data[pos]) || // positive number, e.g. 42
-			(data[pos] == '-' && pos+1 < len(data) && isDigit(data[pos+1])) || // negative number, e.g. -42
-			(data[pos] == '0' && pos+1 < len(data) && data[pos+1] != 'x'): // E.g. 0.5 or just 0
-			value := ""
-			for pos < len(data) && !isWhitespace(data[pos]) && data[pos] != ')' {
-				value += string(data[pos])
-				pos++
+			return s.token(IDENTIFIER, start), nil
+		case isNumberStart(ch, next): // E.g. 42, -2.5, +0.5, .5 or 1e-3
+			for b := s.peek(1); len(b) > 0 && isSymbolChar(b[0]); b = s.peek(1) {
+				s.advance(&s.lexeme)
 			}
-			if isValidNumber(value) {
-				return Token{Type: NUMBER, Value: value}, pos, nil
+			if isValidNumber(s.lexeme) {
+				return s.token(NUMBER, start), nil
 			}
-			return Token{Type: IDENTIFIER, Value: value}, pos, nil
-		case isLetter(data[pos]) ||
-			data[pos] == '*' || // layer wildcards like *.Cu
-			(data[pos] == '0' && pos+1 < len(data) && data[pos+1] == 'x'): // handle hex identifiers like 0x1A2B
-			value := ""
-			for pos < len(data) && isIdentifierChar(data[pos]) {
-				value += string(data[pos])
-				pos++
+			// E.g. hexadecimal timestamps such as 5DD50112 or net names such as +5V
+			return s.token(IDENTIFIER, start), nil
+		case isSymbolChar(ch): // E.g. F.Cu, *.Cu, Lib:Part or /SDA
+			for b := s.peek(1); len(b) > 0 && isSymbolChar(b[0]); b = s.peek(1) {
+				s.advance(&s.lexeme)
 			}
-			return Token{Type: IDENTIFIER, Value: value}, pos, nil
+			return s.token(IDENTIFIER, start), nil
 		default:
-			pos++
+			return Token{}, &ParseError{
+				Pos:      start,
+				Expected: "a token",
+				Got:      fmt.Sprintf("control character 0x%02x", ch),
+				Excerpt:  excerpt(string(s.line), start),
+			}
 		}
 	}
-	return Token{}, pos + 1, fmt.Errorf("no more tokens at position %d", pos)
 }
 
-func Tokenize(data string) ([]Token, error) {
-	tokens := []Token{}
-	pos := 0
-	for pos < len(data) {
-		token, new_pos, err := readNextToken(data, pos)
+func (s *Scanner) token(tokenType TokenType, start Position) Token {
+	raw := s.intern(s.lexeme)
+	value := raw
+	if tokenType == STRING {
+		value = unescape(raw[1 : len(raw)-1])
+	}
+	return Token{
+		Type:    tokenType,
+		Value:   value,
+		Raw:     raw,
+		Leading: s.intern(s.trivia),
+		Pos:     start,
+	}
+}
+
+// bytesPerToken is roughly the average token length, whitespace included, of
+// the boards in test_data. It sizes the token slice up front.
+const bytesPerToken = 5
+
+func sizeHint(r io.Reader) int {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return r.Len()
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if info, err := r.Stat(); err == nil {
+			return int(info.Size())
+		}
+	}
+	return 0
+}
+
+// TokenizeReader reads all tokens from r. The last token is always EOF,
+// carrying the trailing whitespace of the input.
+func TokenizeReader(r io.Reader) ([]Token, error) {
+	scanner := NewScanner(r)
+	tokens := make([]Token, 0, sizeHint(r)/bytesPerToken)
+	for {
+		token, err := scanner.Next()
+		if errors.Is(err, io.EOF) {
+			return tokens, nil
+		}
 		if err != nil {
-			break
+			return tokens, err
 		}
-		pos = new_pos
 		tokens = append(tokens, token)
 	}
+}
 
-	return tokens, nil
+// Tokenize splits data into tokens. The last token is always EOF, carrying
+// the trailing whitespace of the input.
+func Tokenize(data string) ([]Token, error) {
+	return TokenizeReader(strings.NewReader(data))
+}
+
+// readNextToken returns the first token of data at or after pos and the
+// position just after it.
+func readNextToken(data string, pos int) (Token, int, error) {
+	scanner := NewScanner(strings.NewReader(data[pos:]))
+	token, err := scanner.Next()
+	if err != nil {
+		return Token{}, pos, err
+	}
+	return token, pos + scanner.pos.Offset, nil
 }
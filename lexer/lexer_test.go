@@ -1,7 +1,15 @@
 package lexer
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestReadNextToken(t *testing.T) {
@@ -18,12 +26,21 @@ func TestReadNextToken(t *testing.T) {
 		{"number", "123 ", 0, NUMBER, "123"},
 		{"negative number", "-45.67 ", 0, NUMBER, "-45.67"},
 		{"string", `"test string"`, 0, STRING, "test string"},
+		{"utf-8 string", `"Marcus Östling"`, 0, STRING, "Marcus Östling"},
+		{"escaped quote", `"12\" ruler"`, 0, STRING, `12" ruler`},
+		{"escaped backslash before quote", `"C:\\" `, 0, STRING, `C:\`},
+		{"escaped newline and tab", `"a\nb\tc"`, 0, STRING, "a\nb\tc"},
+		{"hex escape", `"\x41\x4a"`, 0, STRING, "AJ"},
+		{"octal escape", `"\101"`, 0, STRING, "A"},
+		{"unknown escape kept", `"\q"`, 0, STRING, `\q`},
 		{"close paren", ")", 0, CLOSE_PAREN, ")"},
-		// Hex numbers (should be identifiers)
-		{"hex number", "0x0000020 ", 0, IDENTIFIER, "0x0000020"},
-		{"hex with underscore", "0x0000020_7ffffffe ", 0, IDENTIFIER, "0x0000020_7ffffffe"},
-		{"hex uppercase", "0xABCD ", 0, IDENTIFIER, "0xABCD"},
-		{"hex lowercase", "0xabcd ", 0, IDENTIFIER, "0xabcd"},
+		// Hex literals
+		{"hex number", "0x0000020 ", 0, HEX, "0x0000020"},
+		{"hex with underscore", "0x0000020_7ffffffe ", 0, HEX, "0x0000020_7ffffffe"},
+		{"hex layer mask", "0x00010fc_ffffffff)", 0, HEX, "0x00010fc_ffffffff"},
+		{"hex uppercase", "0xABCD ", 0, HEX, "0xABCD"},
+		{"hex lowercase", "0xabcd ", 0, HEX, "0xabcd"},
+		{"not hex", "0xyz ", 0, IDENTIFIER, "0xyz"},
 		// Regular numbers
 		{"positive number", "42 ", 0, NUMBER, "42"},
 		{"decimal number", "3.14 ", 0, NUMBER, "3.14"},
@@ -31,6 +48,16 @@ func TestReadNextToken(t *testing.T) {
 		{"0 decimal", "0.5 ", 0, NUMBER, "0.5"},
 		{"-0 decimal", "-0.5 ", 0, NUMBER, "-0.5"},
 		{"Just 0", "0 ", 0, NUMBER, "0"},
+		{"leading plus", "+0.5 ", 0, NUMBER, "+0.5"},
+		{"leading point", ".5 ", 0, NUMBER, ".5"},
+		{"negative leading point", "-.5)", 0, NUMBER, "-.5"},
+		{"trailing point", "1. ", 0, NUMBER, "1."},
+		{"negative exponent", "1e-3 ", 0, NUMBER, "1e-3"},
+		{"positive exponent", "2.5E+2 ", 0, NUMBER, "2.5E+2"},
+		{"unsigned exponent after point", "1.5e3 ", 0, NUMBER, "1.5e3"},
+		{"unsigned exponent is a timestamp", "5E412345 ", 0, IDENTIFIER, "5E412345"},
+		{"exponent without digits", "1e- ", 0, IDENTIFIER, "1e-"},
+		{"net name with plus", "+5V ", 0, IDENTIFIER, "+5V"},
 		// Hex identifiers starting with digit
 		{"hex id tedit", "5DD50112 ", 0, IDENTIFIER, "5DD50112"},
 		{"hex id 5B", "5B307E4C ", 0, IDENTIFIER, "5B307E4C"},
@@ -38,6 +65,9 @@ func TestReadNextToken(t *testing.T) {
 		{"layer wildcard", "*.Cu ", 0, IDENTIFIER, "*.Cu"},
 		{"layer with dot", "F.Cu ", 0, IDENTIFIER, "F.Cu"},
 		{"module with colon", "Lib:Part_123 ", 0, IDENTIFIER, "Lib:Part_123"},
+		{"net name with slash", "/SDA)", 0, IDENTIFIER, "/SDA"},
+		{"net name with plus", "+VIN)", 0, IDENTIFIER, "+VIN"},
+		{"non-ascii identifier", "Ω1 ", 0, IDENTIFIER, "Ω1"},
 		// Pos > 0
 		{"open paren,pos 2", "(h (", 2, OPEN_PAREN, "("},
 	}
@@ -57,3 +87,210 @@ func TestReadNextToken(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "(kicad_pcb\r\n\t(version 20240108)\n  (net 1 \"GND\"))"
+	want := []struct {
+		value string
+		pos   Position
+	}{
+		{"(", Position{Offset: 0, Line: 1, Column: 1}},
+		{"kicad_pcb", Position{Offset: 1, Line: 1, Column: 2}},
+		{"(", Position{Offset: 13, Line: 2, Column: 2}},
+		{"version", Position{Offset: 14, Line: 2, Column: 3}},
+		{"20240108", Position{Offset: 22, Line: 2, Column: 11}},
+		{")", Position{Offset: 30, Line: 2, Column: 19}},
+		{"(", Position{Offset: 34, Line: 3, Column: 3}},
+		{"net", Position{Offset: 35, Line: 3, Column: 4}},
+		{"1", Position{Offset: 39, Line: 3, Column: 8}},
+		{"GND", Position{Offset: 41, Line: 3, Column: 10}},
+		{")", Position{Offset: 46, Line: 3, Column: 15}},
+		{")", Position{Offset: 47, Line: 3, Column: 16}},
+		{"", Position{Offset: 48, Line: 3, Column: 17}},
+	}
+
+	tokens, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, w := range want {
+		if tokens[i].Value != w.value || tokens[i].Pos != w.pos {
+			t.Errorf("token %d: got %q at %+v, want %q at %+v", i, tokens[i].Value, tokens[i].Pos, w.value, w.pos)
+		}
+		if input[tokens[i].Pos.Offset:tokens[i].Pos.Offset+len(tokens[i].Raw)] != tokens[i].Raw {
+			t.Errorf("token %d: offset %d does not point at %q", i, tokens[i].Pos.Offset, tokens[i].Raw)
+		}
+	}
+}
+
+func TestTokenizeReaderMatchesTokenize(t *testing.T) {
+	data, err := os.ReadFile("../test_data/main.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromString, err := Tokenize(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A reader returning one byte at a time crosses every buffer boundary
+	fromReader, err := TokenizeReader(iotest.OneByteReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromString, fromReader) {
+		t.Error("TokenizeReader and Tokenize disagree")
+	}
+}
+
+func TestTokenizeReaderReturnsReadErrors(t *testing.T) {
+	readErr := errors.New("read failed")
+	tests := []struct {
+		name  string
+		input string // Read before the error
+	}{
+		{"between tokens", "(kicad_pcb (version 20240108) "},
+		{"inside a string", `(kicad_pcb (net 1 "GN`},
+		{"at the start", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			r := io.MultiReader(strings.NewReader(tt.input), iotest.ErrReader(readErr))
+
+			// Act
+			_, err := TokenizeReader(iotest.OneByteReader(r))
+
+			// Assert
+			if !errors.Is(err, readErr) {
+				t.Errorf("Expected error %v, got %v", readErr, err)
+			}
+		})
+	}
+}
+
+func TestTokenizeUnterminatedString(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no closing quote", `(net 1 "GND`},
+		{"escaped closing quote", `(net 1 "GND\"`},
+		{"trailing backslash", `(net 1 "GND\`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Tokenize(tt.input)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("got error %v, want %v", err, io.ErrUnexpectedEOF)
+			}
+		})
+	}
+}
+
+func TestTokenizeControlCharacter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"nul", "(net 1\x00 GND)", "1:7: expected a token, got control character 0x00"},
+		{"form feed", "(a)\n\f(b)", "2:1: expected a token, got control character 0x0c"},
+		{"inside identifier", "(net GN\x7fD)", "1:8: expected a token, got control character 0x7f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Tokenize(tt.input)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got error %v, want a *ParseError", err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got error %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeUnquotedNetNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"leading plus", "(net 1 +VIN)", []string{"(", "net", "1", "+VIN", ")", ""}},
+		{"hierarchical", "(net 2 /SDA)", []string{"(", "net", "2", "/SDA", ")", ""}},
+		{"sheet path", "(net 3 /Power/+3V3)", []string{"(", "net", "3", "/Power/+3V3", ")", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(tokens))
+			for i, token := range tokens {
+				got[i] = token.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got tokens %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func benchmarkBoards(b *testing.B) map[string][]byte {
+	paths, err := filepath.Glob("../test_data/*.kicad_pcb")
+	if err != nil || len(paths) == 0 {
+		b.Fatal("Expected test_data boards")
+	}
+	boards := map[string][]byte{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		boards[filepath.Base(path)] = data
+	}
+	return boards
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	for name, data := range benchmarkBoards(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := TokenizeReader(bytes.NewReader(data)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for name, data := range benchmarkBoards(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tokens, err := TokenizeReader(bytes.NewReader(data))
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := Parse(tokens); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type fieldSpec struct {
+	index    int
+	name     string // Identifier of the child node, empty for args
+	arg      bool
+	flag     bool
+	optional bool
+	repeated bool
+	ident    bool
+}
+
+var fieldSpecCache sync.Map // reflect.Type -> []fieldSpec
+
+func fieldSpecs(t reflect.Type) ([]fieldSpec, error) {
+	if specs, ok := fieldSpecCache.Load(t); ok {
+		return specs.([]fieldSpec), nil
+	}
+
+	specs := []fieldSpec{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("sexpr")
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		spec := fieldSpec{index: i, name: parts[0]}
+		for _, option := range parts[1:] {
+			switch option {
+			case "arg":
+				spec.arg = true
+			case "flag":
+				spec.flag = true
+			case "optional":
+				spec.optional = true
+			case "repeated":
+				spec.repeated = true
+			case "ident":
+				spec.ident = true
+			default:
+				return nil, fmt.Errorf("%s.%s: unknown sexpr option %q", t, field.Name, option)
+			}
+		}
+		switch {
+		case spec.arg && spec.name != "":
+			return nil, fmt.Errorf("%s.%s: an arg cannot have a name", t, field.Name)
+		case !spec.arg && spec.name == "":
+			return nil, fmt.Errorf("%s.%s: missing node name", t, field.Name)
+		case spec.flag && field.Type.Kind() != reflect.Bool:
+			return nil, fmt.Errorf("%s.%s: a flag must be a bool", t, field.Name)
+		case spec.repeated && field.Type.Kind() != reflect.Slice:
+			return nil, fmt.Errorf("%s.%s: a repeated field must be a slice", t, field.Name)
+		}
+		specs = append(specs, spec)
+	}
+
+	fieldSpecCache.Store(t, specs)
+	return specs, nil
+}
+
+// Marshal returns the expression (identifier ...) describing v, which is a
+// struct, a pointer to one or a scalar. Marshal and Unmarshal convert
+// between Go values and expressions using the `sexpr` struct tag. Fields are
+// written in declaration order, fields without the tag are skipped. The tag
+// names the field's child node, followed by options:
+//
+//	Start  Position `sexpr:"start"`             child node (start x y)
+//	X      float64  `sexpr:",arg"`              positional value of the node itself
+//	Pins   []string `sexpr:",arg"`              all remaining positional values
+//	Locked bool     `sexpr:"locked,flag"`       bare identifier locked when true
+//	UUID   string   `sexpr:"uuid,optional"`     left out when empty, may be missing
+//	Pads   []Pad    `sexpr:"pad,repeated"`      one (pad ...) node per element
+//	Layers []string `sexpr:"layers"`            one node (layers a b c)
+//	Type   string   `sexpr:",arg,ident"`        written unquoted, e.g. smd
+//
+// Strings are written quoted unless tagged ident and read from strings,
+// identifiers and numbers alike. Numbers become NumberValue and bools are written as yes
+// or no. A struct or slice field names a node holding its values; a scalar
+// field names a node holding that single value, e.g. (width 0.25).
+func Marshal(identifier string, v any) (Expr, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return Expr{}, errors.New("marshal: nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	return marshalNode(identifier, rv, false)
+}
+
+func marshalNode(identifier string, rv reflect.Value, ident bool) (Expr, error) {
+	e := Expr{
+		Type:       IdentifierToExprType(identifier),
+		Identifier: identifier,
+		Values:     []Value{},
+	}
+	if rv.Kind() != reflect.Struct {
+		values, err := marshalScalars(rv, ident)
+		if err != nil {
+			return Expr{}, fmt.Errorf("%s: %w", identifier, err)
+		}
+		e.Values = values
+		return e, nil
+	}
+
+	specs, err := fieldSpecs(rv.Type())
+	if err != nil {
+		return Expr{}, err
+	}
+	for _, spec := range specs {
+		field := rv.Field(spec.index)
+		if spec.optional && field.IsZero() {
+			continue
+		}
+		switch {
+		case spec.flag:
+			if field.Bool() {
+				e.Values = append(e.Values, IdentifierValue{Value: spec.name})
+			}
+		case spec.arg:
+			values, err := marshalScalars(field, spec.ident)
+			if err != nil {
+				return Expr{}, fmt.Errorf("%s: %w", identifier, err)
+			}
+			e.Values = append(e.Values, values...)
+		case spec.repeated:
+			for i := 0; i < field.Len(); i++ {
+				child, err := marshalNode(spec.name, field.Index(i), spec.ident)
+				if err != nil {
+					return Expr{}, fmt.Errorf("%s: %w", identifier, err)
+				}
+				e.Values = append(e.Values, ExprValue{Value: child})
+			}
+		default:
+			child, err := marshalNode(spec.name, field, spec.ident)
+			if err != nil {
+				return Expr{}, fmt.Errorf("%s: %w", identifier, err)
+			}
+			e.Values = append(e.Values, ExprValue{Value: child})
+		}
+	}
+	return e, nil
+}
+
+// marshalScalars returns the values of a scalar, or of each element of a
+// slice of scalars.
+func marshalScalars(rv reflect.Value, ident bool) ([]Value, error) {
+	if rv.Kind() != reflect.Slice {
+		val, err := marshalScalar(rv, ident)
+		if err != nil {
+			return nil, err
+		}
+		return []Value{val}, nil
+	}
+	values := make([]Value, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		val, err := marshalScalar(rv.Index(i), ident)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, val)
+	}
+	return values, nil
+}
+
+func marshalScalar(rv reflect.Value, ident bool) (Value, error) {
+	switch rv.Kind() {
+	case reflect.String:
+		if ident {
+			return IdentifierValue{Value: rv.String()}, nil
+		}
+		return StringValue{Value: rv.String()}, nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return IdentifierValue{Value: "yes"}, nil
+		}
+		return IdentifierValue{Value: "no"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NumberValue{Value: float64(rv.Int())}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return NumberValue{Value: float64(rv.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return NumberValue{Value: rv.Float()}, nil
+	default:
+		return nil, fmt.Errorf("cannot marshal %s", rv.Type())
+	}
+}
+
+// Unmarshal stores the values of e in v, which must be a non-nil pointer.
+// Children and values without a matching field are ignored.
+func Unmarshal(e Expr, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("unmarshal: need a non-nil pointer, got %T", v)
+	}
+	return unmarshalNode(e, rv.Elem())
+}
+
+func unmarshalNode(e Expr, rv reflect.Value) error {
+	if rv.Kind() != reflect.Struct {
+		if err := unmarshalScalars(scalarValues(e, nil), rv); err != nil {
+			return fmt.Errorf("%s: %w", e.Identifier, err)
+		}
+		return nil
+	}
+
+	specs, err := fieldSpecs(rv.Type())
+	if err != nil {
+		return err
+	}
+	flags := map[string]bool{}
+	for _, spec := range specs {
+		if spec.flag {
+			flags[spec.name] = true
+		}
+	}
+
+	args := scalarValues(e, flags)
+	next := 0 // Index of the next positional value
+	for _, spec := range specs {
+		field := rv.Field(spec.index)
+		switch {
+		case spec.flag:
+			field.SetBool(hasFlag(e, spec.name))
+		case spec.arg:
+			if next == len(args) {
+				if spec.optional || field.Kind() == reflect.Slice {
+					continue
+				}
+				return fmt.Errorf("%s has no value %d: %w", e.Identifier, next, ErrNotFound)
+			}
+			end := next + 1
+			if field.Kind() == reflect.Slice {
+				end = len(args)
+			}
+			if err := unmarshalScalars(args[next:end], field); err != nil {
+				return fmt.Errorf("%s: %w", e.Identifier, err)
+			}
+			next = end
+		case spec.repeated:
+			children := e.Children(spec.name)
+			slice := reflect.MakeSlice(field.Type(), len(children), len(children))
+			for i, child := range children {
+				if err := unmarshalNode(child, slice.Index(i)); err != nil {
+					return fmt.Errorf("%s: %w", e.Identifier, err)
+				}
+			}
+			field.Set(slice)
+		default:
+			child, err := e.Child(spec.name)
+			if err != nil {
+				if spec.optional {
+					continue
+				}
+				return err
+			}
+			if err := unmarshalNode(child, field); err != nil {
+				return fmt.Errorf("%s: %w", e.Identifier, err)
+			}
+		}
+	}
+	return nil
+}
+
+// scalarValues returns the values of e that are not nested expressions or
+// one of flags.
+func scalarValues(e Expr, flags map[string]bool) []Value {
+	values := []Value{}
+	for _, val := range e.Values {
+		switch v := val.(type) {
+		case ExprValue:
+			continue
+		case IdentifierValue:
+			if flags[v.Value] {
+				continue
+			}
+		}
+		values = append(values, val)
+	}
+	return values
+}
+
+func hasFlag(e Expr, name string) bool {
+	for _, val := range e.Values {
+		if id, ok := val.(IdentifierValue); ok && id.Value == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalScalars stores values in a scalar, which takes the first value, or
+// in a slice of scalars.
+func unmarshalScalars(values []Value, rv reflect.Value) error {
+	if rv.Kind() != reflect.Slice {
+		if len(values) == 0 {
+			return fmt.Errorf("missing value: %w", ErrNotFound)
+		}
+		return unmarshalScalar(values[0], rv)
+	}
+	slice := reflect.MakeSlice(rv.Type(), len(values), len(values))
+	for i, val := range values {
+		if err := unmarshalScalar(val, slice.Index(i)); err != nil {
+			return err
+		}
+	}
+	rv.Set(slice)
+	return nil
+}
+
+func unmarshalScalar(val Value, rv reflect.Value) error {
+	mismatch := func() error {
+		return fmt.Errorf("cannot store %s %s in %s", valueKind(val), val, rv.Type())
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		switch v := val.(type) {
+		case StringValue:
+			rv.SetString(v.Value)
+		case IdentifierValue:
+			rv.SetString(v.Value)
+		case NumberValue:
+			// Unquoted names such as pad numbers in older files
+			rv.SetString(v.String())
+		default:
+			return mismatch()
+		}
+	case reflect.Bool:
+		id, ok := val.(IdentifierValue)
+		if !ok || (id.Value != "yes" && id.Value != "no") {
+			return mismatch()
+		}
+		rv.SetBool(id.Value == "yes")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num, ok := val.(NumberValue)
+		if !ok || num.Value != float64(int64(num.Value)) {
+			return mismatch()
+		}
+		rv.SetInt(int64(num.Value))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		num, ok := val.(NumberValue)
+		if !ok || num.Value < 0 || num.Value != float64(uint64(num.Value)) {
+			return mismatch()
+		}
+		rv.SetUint(uint64(num.Value))
+	case reflect.Float32, reflect.Float64:
+		num, ok := val.(NumberValue)
+		if !ok {
+			return mismatch()
+		}
+		rv.SetFloat(num.Value)
+	default:
+		return fmt.Errorf("cannot unmarshal into %s", rv.Type())
+	}
+	return nil
+}
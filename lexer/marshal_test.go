@@ -0,0 +1,212 @@
+package lexer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testPoint struct {
+	X float64 `sexpr:",arg"`
+	Y float64 `sexpr:",arg"`
+}
+
+type testPad struct {
+	Number string    `sexpr:",arg"`
+	Kind   string    `sexpr:",arg,ident"`
+	Locked bool      `sexpr:"locked,flag"`
+	At     testPoint `sexpr:"at"`
+	Layers []string  `sexpr:"layers"`
+	Net    int       `sexpr:"net,optional"`
+	Hidden bool      `sexpr:"hide,optional"`
+	Notes  string
+}
+
+type testFootprint struct {
+	Name string    `sexpr:",arg"`
+	At   testPoint `sexpr:"at"`
+	Tags []string  `sexpr:",arg,ident"`
+	Pads []testPad `sexpr:"pad,repeated"`
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name       string
+		identifier string
+		value      any
+		want       string
+	}{
+		{
+			"scalar fields and flags",
+			"pad",
+			testPad{Number: "1", Kind: "smd", Locked: true, At: testPoint{1.5, -2}, Layers: []string{"F.Cu", "F.Mask"}, Net: 3, Hidden: true},
+			`(pad "1" smd locked (at 1.5 -2) (layers "F.Cu" "F.Mask") (net 3) (hide yes))`,
+		},
+		{
+			"optional fields left out",
+			"pad",
+			&testPad{Number: "2", Kind: "thru_hole", Layers: []string{}},
+			`(pad "2" thru_hole (at 0 0) (layers))`,
+		},
+		{
+			"repeated nodes and trailing args",
+			"footprint",
+			testFootprint{Name: "R1", Tags: []string{"a", "b"}, Pads: []testPad{{Number: "1", Kind: "smd"}, {Number: "2", Kind: "smd"}}},
+			`(footprint "R1" (at 0 0) a b (pad "1" smd (at 0 0) (layers)) (pad "2" smd (at 0 0) (layers)))`,
+		},
+		{"scalar", "width", 0.25, "(width 0.25)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			expr, err := Marshal(tt.identifier, tt.value)
+
+			// Assert
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := FormatInline(expr); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalSetsExprType(t *testing.T) {
+	expr, err := Marshal("pad", testPad{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.Type != ExprPad {
+		t.Errorf("got type %v, want %v", expr.Type, ExprPad)
+	}
+	at := expr.Values[2].(ExprValue).Value
+	if at.Type != ExprAt {
+		t.Errorf("got child type %v, want %v", at.Type, ExprAt)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	// Arrange
+	input := `(footprint "R1" (at 10 20) smd_tag
+		(pad 1 smd locked (at -1 0) (layers F.Cu "F.Mask") (net 3 "GND") (uuid "u1"))
+		(pad "2" thru_hole (at 1 0) (layers "*.Cu") (hide no)))`
+	tokens, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	var footprint testFootprint
+	err = Unmarshal(expr, &footprint)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testFootprint{
+		Name: "R1",
+		At:   testPoint{10, 20},
+		Tags: []string{"smd_tag"},
+		Pads: []testPad{
+			{Number: "1", Kind: "smd", Locked: true, At: testPoint{-1, 0}, Layers: []string{"F.Cu", "F.Mask"}},
+			{Number: "2", Kind: "thru_hole", At: testPoint{1, 0}, Layers: []string{"*.Cu"}},
+		},
+	}
+	// (net 3 "GND") holds two values, only the first fits an int
+	want.Pads[0].Net = 3
+	if !reflect.DeepEqual(footprint, want) {
+		t.Errorf("got %+v\nwant %+v", footprint, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		notFound bool
+	}{
+		{"missing child", `(pad "1" smd (layers))`, true},
+		{"missing arg", `(pad "1" (at 0 0) (layers))`, true},
+		{"wrong value type", `(pad "1" smd (at 0 "x") (layers))`, false},
+		{"fractional int", `(pad "1" smd (at 0 0) (layers) (net 1.5))`, false},
+		{"bad bool", `(pad "1" smd (at 0 0) (layers) (hide maybe))`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expr, err := Parse(tokens)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var pad testPad
+			err = Unmarshal(expr, &pad)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrNotFound) != tt.notFound {
+				t.Errorf("got %v, want ErrNotFound: %v", err, tt.notFound)
+			}
+		})
+	}
+}
+
+func TestMarshalUnmarshalRoundtrip(t *testing.T) {
+	// Arrange
+	pad := testPad{Number: "A1", Kind: "smd", At: testPoint{0.125, -3}, Layers: []string{"B.Cu"}, Net: 7, Hidden: true}
+
+	// Act
+	expr, err := Marshal("pad", pad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := Tokenize(expr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got testPad
+	err = Unmarshal(parsed, &got)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, pad) {
+		t.Errorf("got %+v, want %+v", got, pad)
+	}
+}
+
+func TestMarshalInvalidTags(t *testing.T) {
+	type badOption struct {
+		X int `sexpr:"x,sometimes"`
+	}
+	type badFlag struct {
+		X int `sexpr:"x,flag"`
+	}
+	type namedArg struct {
+		X int `sexpr:"x,arg"`
+	}
+
+	for _, value := range []any{badOption{}, badFlag{}, namedArg{}} {
+		if _, err := Marshal("bad", value); err == nil {
+			t.Errorf("Marshal(%T): expected an error", value)
+		}
+	}
+	if err := Unmarshal(Expr{}, testPad{}); err == nil {
+		t.Error("Unmarshal into a non-pointer: expected an error")
+	}
+}
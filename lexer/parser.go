@@ -6,7 +6,6 @@ package lexer
 
 import (
 	"errors"
-	"fmt"
 	"strconv"
 	"strings"
 )
@@ -36,22 +35,15 @@ func (ExprValue) isValue() {}
 func (v ExprValue) String() string {
 	return v.Value.String()
 }
-func (v ExprValue) stringWithIndent(indent int) string {
-	return v.Value.stringWithIndent(indent)
-}
 
 func (StringValue) isValue() {}
 func (v StringValue) String() string {
-	return fmt.Sprintf("%q", v.Value)
+	return quote(v.Value)
 }
 
 func (NumberValue) isValue() {}
 func (v NumberValue) String() string {
-	// Format integers without decimals
-	if v.Value == float64(int(v.Value)) {
-		return fmt.Sprintf("%d", int(v.Value))
-	}
-	return fmt.Sprintf("%f", v.Value)
+	return FormatNumber(v.Value)
 }
 
 func (IdentifierValue) isValue() {}
@@ -63,103 +55,305 @@ type Expr struct {
 	Type       ExprType
 	Identifier string
 	Values     []Value
+	Layout     *Layout  // Source formatting, nil for expressions built in code
+	Pos        Position // Position of '(' in the source, zero for expressions built in code
 }
 
+// String writes the expression back with its source formatting when it was
+// parsed, and in pcbnew's style otherwise.
 func (e Expr) String() string {
-	return e.stringWithIndent(0)
+	if e.Layout != nil {
+		var sb strings.Builder
+		e.writeLayout(&sb, "\t")
+		return sb.String()
+	}
+	return Format(e)
+}
+
+func parseExprError(err error) (Expr, int, error) {
+	return Expr{}, 0, err
+}
+
+// tokenAt returns tokens[pos], or an EOF token positioned after the last
+// token when tokens ends early.
+func tokenAt(tokens []Token, pos int) Token {
+	if pos < len(tokens) {
+		return tokens[pos]
+	}
+	end := Token{Type: EOF, Pos: Position{Line: 1, Column: 1}}
+	if len(tokens) > 0 {
+		last := tokens[len(tokens)-1]
+		end.Pos = last.Pos
+		end.Pos.Offset += len(last.Raw)
+		end.Pos.Column += len(last.Raw)
+	}
+	return end
+}
+
+// rawText returns the source text of token, rebuilt from its value for
+// tokens built by hand.
+func rawText(token Token) string {
+	switch {
+	case token.Raw != "":
+		return token.Raw
+	case token.Type == STRING:
+		return quote(token.Value)
+	default:
+		return token.Value
+	}
+}
+
+func unexpected(token Token, expected string, path []string) *ParseError {
+	return &ParseError{Pos: token.Pos, Expected: expected, Got: describeToken(token), Path: path}
 }
 
-func (e Expr) stringWithIndent(indent int) string {
-	// Check if this is kicad_pcb root or has many nested children
-	isRootOrComplex := e.Identifier == "kicad_pcb" || e.Identifier == "module" || e.Identifier == "footprint"
+// parser holds the state of a single Parse or ParseRecover call.
+type parser struct {
+	tokens      []Token
+	recover     bool // Replace unreadable expressions by ExprError nodes instead of failing
+	diagnostics []*ParseError
+	reachedEnd  bool     // An unexpected end of input has been reported
+	lines       []string // Source lines, rebuilt from the tokens for excerpts
+}
 
-	if !isRootOrComplex {
-		// Simple expression - keep on one line
-		result := fmt.Sprintf("(%s", e.Identifier)
-		for _, val := range e.Values {
-			result += " " + val.String()
+// report records err in recovery mode. The input ending early is reported
+// once, not by every expression left open.
+func (p *parser) report(err *ParseError) {
+	if err.Got == describeToken(Token{Type: EOF}) {
+		if p.reachedEnd {
+			return
 		}
-		result += ")"
-		return result
+		p.reachedEnd = true
+	}
+	p.diagnostics = append(p.diagnostics, err)
+}
+
+// fail returns err, or in recovery mode reports it and replaces the
+// expression starting at tokens[open] by an error node.
+func (p *parser) fail(err *ParseError, open int) (Expr, int, error) {
+	if !p.recover {
+		return parseExprError(err)
 	}
+	p.report(err)
+	return p.errorNode(open)
+}
 
-	// Complex expression - format with newlines
-	result := fmt.Sprintf("(%s", e.Identifier)
-	for _, val := range e.Values {
-		if exprVal, ok := val.(ExprValue); ok {
-			result += "\n" + strings.Repeat("  ", indent+1) + exprVal.stringWithIndent(indent+1)
-		} else {
-			result += " " + val.String()
+// errorNode returns an ExprError node holding the source text from
+// tokens[open] to the matching ')', and the position after it.
+func (p *parser) errorNode(open int) (Expr, int, error) {
+	tokens := p.tokens
+	var raw strings.Builder
+	depth := 0
+	pos := open
+	for pos < len(tokens) && tokens[pos].Type != EOF {
+		if pos > open {
+			raw.WriteString(tokens[pos].Leading)
+		}
+		raw.WriteString(rawText(tokens[pos]))
+		switch tokens[pos].Type {
+		case OPEN_PAREN:
+			depth++
+		case CLOSE_PAREN:
+			depth--
 		}
+		pos++
+		if depth <= 0 {
+			break
+		}
+	}
+	// Close what is left open at the end of the input, like parseExpr does
+	raw.WriteString(strings.Repeat(")", max(depth, 0)))
+
+	identifier := ""
+	if head := tokenAt(tokens, open+1); tokenAt(tokens, open).Type == OPEN_PAREN && isHead(head.Type) {
+		identifier = head.Value
 	}
-	result += ")"
-	return result
+	return Expr{
+		Type:       ExprError,
+		Identifier: identifier,
+		Values:     []Value{},
+		Layout:     &Layout{Raw: raw.String()},
+		Pos:        tokenAt(tokens, open).Pos,
+	}, pos, nil
 }
 
-func parseExprError(err error) (Expr, int, error) {
-	return Expr{}, 0, err
+// isHead reports whether a token of type t can name an expression. Besides
+// identifiers, layers can look like this (34 "B.Paste" user).
+func isHead(t TokenType) bool {
+	switch t {
+	case IDENTIFIER, STRING, NUMBER, HEX:
+		return true
+	default:
+		return false
+	}
 }
 
-func parseExpr(tokens []Token, pos int) (Expr, int, error) {
+func (p *parser) parseExpr(pos int, path []string) (Expr, int, error) {
+	tokens := p.tokens
+	open := pos
 	identifier := ""
 	values := []Value{}
+	// Tokens built by hand carry no source text to preserve
+	var layout *Layout
+	if tokenAt(tokens, pos).Raw != "" {
+		layout = &Layout{}
+	}
 
-	if tokens[pos].Type != OPEN_PAREN {
-		return parseExprError(fmt.Errorf("Expr expected '(', got %s", tokens[pos].Type.String()))
+	if tokenAt(tokens, pos).Type != OPEN_PAREN {
+		return p.fail(unexpected(tokenAt(tokens, pos), "'('", path), open)
 	}
+	start := tokens[pos].Pos
 	pos++
 
-	if tokens[pos].Type != IDENTIFIER {
-		// return parseExprError(fmt.Errorf("Expr expected IDENTIFIER, got %s. Context: %v", tokens[pos].Type.String(), tokens[pos]))
-		// Layers can look like this (34 "B.Paste" user)
-		tokens[pos].Type = IDENTIFIER
+	if !isHead(tokenAt(tokens, pos).Type) {
+		return p.fail(unexpected(tokenAt(tokens, pos), "identifier", path), open)
 	}
 	identifier = tokens[pos].Value
+	path = append(path[:len(path):len(path)], identifier)
+	if layout != nil {
+		layout.Open = tokens[pos].Leading
+		layout.Head = tokens[pos].Raw
+	}
 	pos++
 
-	for pos < len(tokens) && tokens[pos].Type != CLOSE_PAREN {
+	for tokenAt(tokens, pos).Type != CLOSE_PAREN {
+		if tokenAt(tokens, pos).Type == EOF {
+			err := unexpected(tokenAt(tokens, pos), "')'", path)
+			if !p.recover {
+				return parseExprError(err)
+			}
+			// Close the expression where the input ends
+			p.report(err)
+			break
+		}
+		if layout != nil {
+			layout.Gaps = append(layout.Gaps, tokens[pos].Leading)
+			layout.Lexemes = append(layout.Lexemes, tokens[pos].Raw)
+		}
 		switch tokens[pos].Type {
 		case OPEN_PAREN:
-			expr, newPos, err := parseExpr(tokens, pos)
+			expr, newPos, err := p.parseExpr(pos, path)
 			if err != nil {
-				return parseExprError(fmt.Errorf("failed to parse nested expression: %w", err))
+				return parseExprError(err)
 			}
 			pos = newPos
 			values = append(values, ExprValue{Value: expr})
+			if layout != nil {
+				layout.Lexemes[len(layout.Lexemes)-1] = ""
+			}
 		case STRING:
 			values = append(values, StringValue{Value: tokens[pos].Value})
 			pos++
 		case NUMBER:
 			value, err := strconv.ParseFloat(tokens[pos].Value, 64)
 			if err != nil {
-				return parseExprError(fmt.Errorf("failed to parse number: %w", err))
+				parseErr := unexpected(tokens[pos], "number", path)
+				parseErr.Err = err
+				return p.fail(parseErr, open)
 			}
 			values = append(values, NumberValue{Value: value})
 			pos++
-		case IDENTIFIER:
+		case IDENTIFIER, HEX:
+			// Hexadecimal literals are masks rather than quantities and are
+			// kept as written
 			values = append(values, IdentifierValue{Value: tokens[pos].Value})
 			pos++
 		default:
-			return parseExprError(fmt.Errorf("unexpected token %s", tokens[pos].Type.String()))
+			return p.fail(unexpected(tokens[pos], "')'", path), open)
 		}
 	}
 
-	if pos >= len(tokens) {
-		return parseExprError(errors.New("unexpected end of tokens"))
+	if layout != nil {
+		gap := ""
+		if tokenAt(tokens, pos).Type == CLOSE_PAREN {
+			gap = tokens[pos].Leading
+		}
+		layout.Gaps = append(layout.Gaps, gap)
+	}
+	if tokenAt(tokens, pos).Type == CLOSE_PAREN {
+		pos++
 	}
 
 	return Expr{
 		Type:       IdentifierToExprType(identifier),
 		Identifier: identifier,
 		Values:     values,
-	}, pos + 1, nil
+		Layout:     layout,
+		Pos:        start,
+	}, pos, nil
 }
 
-func Parse(tokens []Token) (Expr, error) {
-	expr, _, err := parseExpr(tokens, 0)
+func (p *parser) parse() (Expr, error) {
+	tokens := p.tokens
+	expr, pos, err := p.parseExpr(0, nil)
 	if err != nil {
 		return Expr{}, err
 	}
 
+	var after strings.Builder
+	if tokenAt(tokens, pos).Type != EOF {
+		err := unexpected(tokens[pos], "end of input", nil)
+		if !p.recover {
+			return Expr{}, err
+		}
+		p.report(err)
+		// Keep the trailing text as it was
+		for ; pos < len(tokens) && tokens[pos].Type != EOF; pos++ {
+			after.WriteString(tokens[pos].Leading)
+			after.WriteString(rawText(tokens[pos]))
+		}
+	}
+
+	if expr.Layout != nil {
+		// There are no tokens when tokenizing failed on the first one
+		if len(tokens) > 0 {
+			expr.Layout.Before = tokens[0].Leading
+		}
+		if pos < len(tokens) {
+			after.WriteString(tokens[pos].Leading)
+		}
+		expr.Layout.After = after.String()
+	}
+	return expr, nil
+}
+
+// addExcerpt sets the source excerpt of err when the tokens carry their
+// source text.
+func (p *parser) addExcerpt(err error) {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || len(p.tokens) == 0 || p.tokens[0].Raw == "" {
+		return
+	}
+	if p.lines == nil {
+		p.lines = sourceLines(p.tokens)
+	}
+	if n := parseErr.Pos.Line; n >= 1 && n <= len(p.lines) {
+		parseErr.Excerpt = excerpt(p.lines[n-1], parseErr.Pos)
+	}
+}
+
+// Parse builds the expression tree from tokens. Malformed input is reported
+// as a *ParseError.
+func Parse(tokens []Token) (Expr, error) {
+	p := &parser{tokens: tokens}
+	expr, err := p.parse()
+	if err != nil {
+		p.addExcerpt(err)
+		return Expr{}, err
+	}
 	return expr, nil
 }
+
+// ParseRecover is like Parse but reads past malformed input. Each expression
+// that cannot be read is replaced by an ExprError node holding its source text
+// up to the matching ')', expressions left open at the end of the input are
+// closed, and every problem is returned as a diagnostic. Writing the tree back
+// reproduces the input apart from the added closing parentheses.
+func ParseRecover(tokens []Token) (Expr, []*ParseError) {
+	p := &parser{tokens: tokens, recover: true}
+	expr, _ := p.parse()
+	for _, diagnostic := range p.diagnostics {
+		p.addExcerpt(diagnostic)
+	}
+	return expr, p.diagnostics
+}
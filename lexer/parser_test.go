@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -168,7 +170,7 @@ func TestValueString(t *testing.T) {
 		{
 			"NumberValue",
 			NumberValue{Value: 3.14},
-			"3.140000",
+			"3.14",
 		},
 		{
 			"ExprValue",
@@ -195,7 +197,7 @@ func TestValueString(t *testing.T) {
 					NumberValue{Value: 123},
 				},
 			}},
-			"(outer (inner \"nested\") 123)",
+			"(outer\n\t(inner \"nested\") 123\n)",
 		},
 		{
 			"Nested ExprValue segment",
@@ -211,7 +213,7 @@ func TestValueString(t *testing.T) {
 					}},
 				},
 			}},
-			"(segment (start 0.100000 1.100000))",
+			"(segment\n\t(start 0.1 1.1)\n)",
 		},
 		{
 			"IdentifierValue",
@@ -242,6 +244,37 @@ func TestTokenizeCount(t *testing.T) {
 	}
 }
 
+func TestParseExprPositions(t *testing.T) {
+	tokens, err := Tokenize("(footprint \"R1\"\n\t(at 1 2)\n\t(pad \"1\" (net 1 \"GND\"))\n)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := expr.Values[1].(ExprValue).Value
+	pad := expr.Values[2].(ExprValue).Value
+	net := pad.Values[1].(ExprValue).Value
+	tests := []struct {
+		name string
+		got  Position
+		want Position
+	}{
+		{"footprint", expr.Pos, Position{Offset: 0, Line: 1, Column: 1}},
+		{"at", at.Pos, Position{Offset: 17, Line: 2, Column: 2}},
+		{"pad", pad.Pos, Position{Offset: 27, Line: 3, Column: 2}},
+		{"net", net.Pos, Position{Offset: 36, Line: 3, Column: 11}},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
 func TestRoundtrip(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -312,3 +345,161 @@ func TestRoundtrip(t *testing.T) {
 		})
 	}
 }
+
+func TestRoundtripTestDataByteIdentical(t *testing.T) {
+	paths, err := filepath.Glob("../test_data/*.kicad_pcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("Expected test_data boards")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := Tokenize(string(data))
+			if err != nil {
+				t.Fatalf("Tokenize failed: %v", err)
+			}
+			expr, err := Parse(tokens)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			result := expr.String()
+
+			if result != string(data) {
+				t.Errorf("Output differs from input at byte %d", firstDifference(result, string(data)))
+			}
+		})
+	}
+}
+
+func TestRoundtripAppendedValuesGetFreshFormatting(t *testing.T) {
+	input := "(kicad_pcb\r\n\t(version 20240108)\r\n\t(net 1 \"GND\")\r\n)\n"
+	tokens, err := Tokenize(input)
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v", err)
+	}
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expr.Values = append(expr.Values, ExprValue{Value: Expr{
+		Identifier: "segment",
+		Values: []Value{
+			ExprValue{Value: Expr{Identifier: "width", Values: []Value{NumberValue{Value: 0.25}}}},
+		},
+	}})
+	result := expr.String()
+
+	expected := "(kicad_pcb\r\n\t(version 20240108)\r\n\t(net 1 \"GND\")\r\n\t(segment\r\n\t\t(width 0.25)\r\n\t)\r\n)\n"
+	if result != expected {
+		t.Errorf("Expected: %q, Actual: %q", expected, result)
+	}
+}
+
+func TestRoundtripAppendedValuesKeepIndentUnit(t *testing.T) {
+	segment := Expr{
+		Identifier: "segment",
+		Values: []Value{
+			ExprValue{Value: Expr{Identifier: "width", Values: []Value{NumberValue{Value: 0.25}}}},
+		},
+	}
+	tests := []struct {
+		name     string
+		input    string
+		nested   bool // Append the segment to the first nested expression instead of the root
+		expected string
+	}{
+		{
+			"two spaces",
+			"(kicad_pcb\n  (version 20240108)\n  (net 1 \"GND\")\n)\n",
+			false,
+			"(kicad_pcb\n  (version 20240108)\n  (net 1 \"GND\")\n  (segment\n    (width 0.25)\n  )\n)\n",
+		},
+		{
+			"four spaces in a nested list",
+			"(kicad_pcb\n    (footprint \"R\"\n        (at 1 2)\n    )\n)\n",
+			true,
+			"(kicad_pcb\n    (footprint \"R\"\n        (at 1 2)\n        (segment\n            (width 0.25)\n        )\n    )\n)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.input)
+			if err != nil {
+				t.Fatalf("Tokenize failed: %v", err)
+			}
+			expr, err := Parse(tokens)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if tt.nested {
+				footprint := expr.Values[0].(ExprValue).Value
+				footprint.Values = append(footprint.Values, ExprValue{Value: segment})
+				expr.Values[0] = ExprValue{Value: footprint}
+			} else {
+				expr.Values = append(expr.Values, ExprValue{Value: segment})
+			}
+			result := expr.String()
+
+			if result != tt.expected {
+				t.Errorf("Expected: %q, Actual: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRoundtripModifiedNumberIsReformatted(t *testing.T) {
+	tokens, err := Tokenize("(at 1.50 -2.000 90)")
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v", err)
+	}
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expr.Values[1] = NumberValue{Value: 3}
+	result := expr.String()
+
+	if result != "(at 1.50 3 90)" {
+		t.Errorf("Expected: %q, Actual: %q", "(at 1.50 3 90)", result)
+	}
+}
+
+func TestNumberSpellingsRoundtrip(t *testing.T) {
+	input := "(setup (layerselection 0x00010fc_ffffffff) (at +0.5 .5 1e-3 2.5E+2) (tedit 5E412345))"
+	tokens, err := Tokenize(input)
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v", err)
+	}
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if result := expr.String(); result != input {
+		t.Errorf("Expected: %q, Actual: %q", input, result)
+	}
+	formatted := "(setup\n\t(layerselection 0x00010fc_ffffffff)\n\t(at 0.5 0.5 0.001 250)\n\t(tedit 5E412345)\n)"
+	if result := Format(expr); result != formatted {
+		t.Errorf("Expected: %q, Actual: %q", formatted, result)
+	}
+}
+
+func firstDifference(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
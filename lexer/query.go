@@ -0,0 +1,405 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned when a child, value or query match does not exist.
+var ErrNotFound = errors.New("not found")
+
+// matchesName reports whether e is named name. Names that IdentifierToExprType
+// knows also match their aliases, so "footprint" finds (module ...) too.
+func matchesName(e Expr, name string) bool {
+	if e.Identifier == name {
+		return true
+	}
+	exprType := IdentifierToExprType(name)
+	return exprType != ExprUnknown && e.Type == exprType
+}
+
+// Children returns the nested expressions of e named identifier, in order.
+func (e Expr) Children(identifier string) []Expr {
+	children := []Expr{}
+	for _, val := range e.Values {
+		if exprVal, ok := val.(ExprValue); ok && matchesName(exprVal.Value, identifier) {
+			children = append(children, exprVal.Value)
+		}
+	}
+	return children
+}
+
+// Child returns the first nested expression of e named identifier.
+func (e Expr) Child(identifier string) (Expr, error) {
+	for _, val := range e.Values {
+		if exprVal, ok := val.(ExprValue); ok && matchesName(exprVal.Value, identifier) {
+			return exprVal.Value, nil
+		}
+	}
+	return Expr{}, fmt.Errorf("%s has no %s: %w", e.Identifier, identifier, ErrNotFound)
+}
+
+func valueKind(val Value) string {
+	switch val.(type) {
+	case NumberValue:
+		return "a number"
+	case StringValue:
+		return "a string"
+	case IdentifierValue:
+		return "an identifier"
+	default:
+		return "an expression"
+	}
+}
+
+func (e Expr) valueAt(i int) (Value, error) {
+	if i < 0 || i >= len(e.Values) {
+		return nil, fmt.Errorf("%s has no value %d: %w", e.Identifier, i, ErrNotFound)
+	}
+	return e.Values[i], nil
+}
+
+// NumberAt returns value i of e, which must be a number.
+func (e Expr) NumberAt(i int) (float64, error) {
+	val, err := e.valueAt(i)
+	if err != nil {
+		return 0, err
+	}
+	num, ok := val.(NumberValue)
+	if !ok {
+		return 0, fmt.Errorf("%s value %d is %s, want a number", e.Identifier, i, valueKind(val))
+	}
+	return num.Value, nil
+}
+
+// StringAt returns value i of e, which must be a quoted string.
+func (e Expr) StringAt(i int) (string, error) {
+	val, err := e.valueAt(i)
+	if err != nil {
+		return "", err
+	}
+	str, ok := val.(StringValue)
+	if !ok {
+		return "", fmt.Errorf("%s value %d is %s, want a string", e.Identifier, i, valueKind(val))
+	}
+	return str.Value, nil
+}
+
+// IdentifierAt returns value i of e, which must be an unquoted identifier.
+func (e Expr) IdentifierAt(i int) (string, error) {
+	val, err := e.valueAt(i)
+	if err != nil {
+		return "", err
+	}
+	id, ok := val.(IdentifierValue)
+	if !ok {
+		return "", fmt.Errorf("%s value %d is %s, want an identifier", e.Identifier, i, valueKind(val))
+	}
+	return id.Value, nil
+}
+
+// TextAt returns value i of e, which may be a string or an identifier. KiCad
+// quotes some values, such as layer names, in some versions only.
+func (e Expr) TextAt(i int) (string, error) {
+	val, err := e.valueAt(i)
+	if err != nil {
+		return "", err
+	}
+	switch v := val.(type) {
+	case StringValue:
+		return v.Value, nil
+	case IdentifierValue:
+		return v.Value, nil
+	default:
+		return "", fmt.Errorf("%s value %d is %s, want text", e.Identifier, i, valueKind(val))
+	}
+}
+
+// FindAll returns the expressions below e matching path, in document order.
+// See ParseQuery for the path syntax.
+func (e Expr) FindAll(path string) ([]Expr, error) {
+	query, err := ParseQuery(path)
+	if err != nil {
+		return nil, err
+	}
+	return query.FindAll(e), nil
+}
+
+// Find returns the first expression below e matching path.
+func (e Expr) Find(path string) (Expr, error) {
+	matches, err := e.FindAll(path)
+	if err != nil {
+		return Expr{}, err
+	}
+	if len(matches) == 0 {
+		return Expr{}, fmt.Errorf("%s has no %s: %w", e.Identifier, path, ErrNotFound)
+	}
+	return matches[0], nil
+}
+
+// First is like Find but only reports whether there was a match, which suits
+// optional expressions.
+func (e Expr) First(path string) (Expr, bool) {
+	match, err := e.Find(path)
+	return match, err == nil
+}
+
+// Query is a compiled path, see ParseQuery.
+type Query struct {
+	steps []queryStep
+}
+
+type queryStep struct {
+	name       string // Identifier, "*" for any child or "**" for any depth
+	predicates []predicate
+}
+
+// predicate filters a step's matches, e.g. [net.1='GND'].
+type predicate struct {
+	path    *Query // Expressions to test relative to the match, nil for the match itself
+	index   int    // Value to test, -1 to only test that the expressions exist
+	op      string // "=", "!=" or "" to test existence
+	literal string
+}
+
+// ParseQuery compiles a path such as
+//
+//	footprint/pad[net.1='GND']/at
+//
+// Steps separated by '/' select nested expressions by identifier, starting
+// with the children of the expression the query runs on. A step of "*"
+// matches any child and "**" the expression itself and all its descendants.
+// Each step may be followed by predicates in brackets:
+//
+//	[net]          has a child named net
+//	[0='1']        value 0 equals 1
+//	[net.1='GND']  value 1 of a net child equals GND
+//	[layer='F.Cu'] value 0 of a layer child equals F.Cu
+//	[net.1!='GND'] no net child has value 1 equal to GND
+//
+// Literals are compared to strings and identifiers as text and to numbers
+// numerically. They may be quoted with ' or ".
+func ParseQuery(path string) (*Query, error) {
+	p := queryParser{path: path}
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(path) {
+		return nil, p.errorf("unexpected %q", path[p.pos])
+	}
+	return query, nil
+}
+
+type queryParser struct {
+	path string
+	pos  int
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid query %q at %d: %s", p.path, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) peek() byte {
+	if p.pos < len(p.path) {
+		return p.path[p.pos]
+	}
+	return 0
+}
+
+// parseQuery reads steps separated by '/'.
+func (p *queryParser) parseQuery() (*Query, error) {
+	query := &Query{}
+	p.pos += len(p.path[p.pos:]) - len(strings.TrimPrefix(p.path[p.pos:], "/"))
+	for {
+		name := p.readWhile(func(ch byte) bool { return isIdentifierChar(ch) && ch != '.' })
+		if name == "" {
+			return nil, p.errorf("expected an identifier")
+		}
+		step := queryStep{name: name}
+		for p.peek() == '[' {
+			p.pos++
+			pred, err := p.parsePredicate()
+			if err != nil {
+				return nil, err
+			}
+			step.predicates = append(step.predicates, pred)
+		}
+		query.steps = append(query.steps, step)
+
+		if p.peek() != '/' {
+			return query, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *queryParser) readWhile(accept func(byte) bool) string {
+	start := p.pos
+	for p.pos < len(p.path) && accept(p.path[p.pos]) {
+		p.pos++
+	}
+	return p.path[start:p.pos]
+}
+
+func (p *queryParser) parsePredicate() (predicate, error) {
+	pred := predicate{index: -1}
+	if !isDigit(p.peek()) {
+		path, err := p.parseQuery()
+		if err != nil {
+			return pred, err
+		}
+		pred.path = path
+		if p.peek() == '.' {
+			p.pos++
+			if !isDigit(p.peek()) {
+				return pred, p.errorf("expected a value index")
+			}
+		}
+	}
+	if isDigit(p.peek()) {
+		pred.index, _ = strconv.Atoi(p.readWhile(isDigit))
+	}
+
+	switch {
+	case strings.HasPrefix(p.path[p.pos:], "!="):
+		pred.op = "!="
+	case p.peek() == '=':
+		pred.op = "="
+	}
+	if pred.op != "" {
+		p.pos += len(pred.op)
+		literal, err := p.parseLiteral()
+		if err != nil {
+			return pred, err
+		}
+		pred.literal = literal
+		if pred.index < 0 {
+			pred.index = 0
+		}
+	} else if pred.path == nil {
+		return pred, p.errorf("expected '=' or '!='")
+	}
+
+	if p.peek() != ']' {
+		return pred, p.errorf("expected ']'")
+	}
+	p.pos++
+	return pred, nil
+}
+
+func (p *queryParser) parseLiteral() (string, error) {
+	if quote := p.peek(); quote == '\'' || quote == '"' {
+		end := strings.IndexByte(p.path[p.pos+1:], quote)
+		if end < 0 {
+			return "", p.errorf("unterminated literal")
+		}
+		literal := p.path[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return literal, nil
+	}
+	literal := p.readWhile(func(ch byte) bool { return ch != ']' })
+	if literal == "" {
+		return "", p.errorf("expected a literal")
+	}
+	return literal, nil
+}
+
+// FindAll returns the expressions below e matching q, in document order.
+func (q *Query) FindAll(e Expr) []Expr {
+	current := []Expr{e}
+	for _, step := range q.steps {
+		next := []Expr{}
+		for _, expr := range current {
+			for _, candidate := range step.candidates(expr) {
+				if step.accepts(candidate) {
+					next = append(next, candidate)
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+func (s queryStep) candidates(e Expr) []Expr {
+	switch s.name {
+	case "**":
+		all := []Expr{}
+		var walk func(Expr)
+		walk = func(e Expr) {
+			all = append(all, e)
+			for _, val := range e.Values {
+				if exprVal, ok := val.(ExprValue); ok {
+					walk(exprVal.Value)
+				}
+			}
+		}
+		walk(e)
+		return all
+	case "*":
+		children := []Expr{}
+		for _, val := range e.Values {
+			if exprVal, ok := val.(ExprValue); ok {
+				children = append(children, exprVal.Value)
+			}
+		}
+		return children
+	default:
+		return e.Children(s.name)
+	}
+}
+
+func (s queryStep) accepts(e Expr) bool {
+	for _, pred := range s.predicates {
+		if !pred.accepts(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p predicate) accepts(e Expr) bool {
+	targets := []Expr{e}
+	if p.path != nil {
+		targets = p.path.FindAll(e)
+	}
+
+	equal := false
+	for _, target := range targets {
+		if p.op == "" {
+			return true
+		}
+		if p.index >= len(target.Values) {
+			continue
+		}
+		if literalMatches(p.literal, target.Values[p.index]) {
+			equal = true
+			break
+		}
+	}
+	switch p.op {
+	case "=":
+		return equal
+	case "!=":
+		return !equal
+	default:
+		return false
+	}
+}
+
+func literalMatches(literal string, val Value) bool {
+	switch v := val.(type) {
+	case NumberValue:
+		n, err := strconv.ParseFloat(literal, 64)
+		return err == nil && n == v.Value
+	case StringValue:
+		return v.Value == literal
+	case IdentifierValue:
+		return v.Value == literal
+	default:
+		return false
+	}
+}
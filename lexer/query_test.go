@@ -0,0 +1,136 @@
+package lexer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const queryBoard = `(kicad_pcb
+	(net 0 "")
+	(net 1 "GND")
+	(footprint "R1" (at 10 20 90)
+		(pad "1" smd (at -1 0) (net 1 "GND") (layers "F.Cu"))
+		(pad "2" smd (at 1 0) (net 2 "VCC") (layers "F.Cu"))
+	)
+	(module "R2" (at 30 40)
+		(pad 1 thru_hole (at 0 0) (net 1 "GND") (layers *.Cu))
+	)
+	(segment (start 0 0) (end 1 1) (layer "F.Cu") (net 1))
+)`
+
+func parseQueryBoard(t *testing.T) Expr {
+	t.Helper()
+	tokens, err := Tokenize(queryBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expr
+}
+
+func TestFindAll(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{"children", "net", []string{`(net 0 "")`, `(net 1 "GND")`}},
+		{"nested", "footprint/at", []string{"(at 10 20 90)", "(at 30 40)"}},
+		{"leading slash", "/footprint/at", []string{"(at 10 20 90)", "(at 30 40)"}},
+		{"string value", "footprint/pad[net.1='GND']/at", []string{"(at -1 0)", "(at 0 0)"}},
+		{"double quotes", `footprint/pad[net.1="VCC"]/at`, []string{"(at 1 0)"}},
+		{"not equal", "footprint/pad[net.1!='GND']/at", []string{"(at 1 0)"}},
+		{"own value as number", "footprint/pad[0=1]/at", []string{"(at -1 0)", "(at 0 0)"}},
+		{"own identifier value", "footprint/pad[1=thru_hole]/at", []string{"(at 0 0)"}},
+		{"child value 0", "segment[layer='F.Cu']/net", []string{"(net 1)"}},
+		{"child exists", "footprint[pad/net]/at", []string{"(at 10 20 90)", "(at 30 40)"}},
+		{"several predicates", "footprint/pad[net.1='GND'][layers='F.Cu']/at", []string{"(at -1 0)"}},
+		{"any child", "footprint/*/net", []string{`(net 1 "GND")`, `(net 2 "VCC")`, `(net 1 "GND")`}},
+		{"any depth", "**/net[1='GND']", []string{`(net 1 "GND")`, `(net 1 "GND")`, `(net 1 "GND")`}},
+		{"number compares numerically", "footprint[at.1=20.0]/pad[0='1']/at", []string{"(at -1 0)"}},
+		{"no match", "footprint/pad[net.1='+5V']", []string{}},
+	}
+
+	expr := parseQueryBoard(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			matches, err := expr.FindAll(tt.path)
+
+			// Assert
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, match := range matches {
+				got = append(got, match.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	paths := []string{"", "footprint/", "pad[", "pad[net.1='GND'", "pad[net.x='GND']", "pad[0]", "pad[net='GND]", "pad)"}
+
+	for _, path := range paths {
+		if _, err := ParseQuery(path); err == nil {
+			t.Errorf("ParseQuery(%q): expected an error", path)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	expr := parseQueryBoard(t)
+
+	at, err := expr.Find("footprint/pad[net.1='VCC']/at")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, err := at.NumberAt(0); err != nil || x != 1 {
+		t.Errorf("got x %v, %v; want 1", x, err)
+	}
+
+	_, err = expr.Find("footprint/pad[net.1='+5V']")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want %v", err, ErrNotFound)
+	}
+	if _, ok := expr.First("via"); ok {
+		t.Error("First(via): expected no match")
+	}
+}
+
+func TestValueAccessors(t *testing.T) {
+	pad, err := parseQueryBoard(t).Find("footprint/pad")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := pad.StringAt(0); err != nil || got != "1" {
+		t.Errorf("StringAt(0) = %q, %v; want \"1\"", got, err)
+	}
+	if got, err := pad.IdentifierAt(1); err != nil || got != "smd" {
+		t.Errorf("IdentifierAt(1) = %q, %v; want smd", got, err)
+	}
+	if got, err := pad.TextAt(1); err != nil || got != "smd" {
+		t.Errorf("TextAt(1) = %q, %v; want smd", got, err)
+	}
+	if _, err := pad.NumberAt(0); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("NumberAt(0) on a string: got %v, want a type error", err)
+	}
+	if _, err := pad.NumberAt(42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("NumberAt(42): got %v, want %v", err, ErrNotFound)
+	}
+	if _, err := pad.Child("drill"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Child(drill): got %v, want %v", err, ErrNotFound)
+	}
+	if net, err := pad.Child("net"); err != nil || net.String() != `(net 1 "GND")` {
+		t.Errorf("Child(net) = %s, %v", net, err)
+	}
+}
@@ -8,6 +8,8 @@ const (
 	IDENTIFIER
 	NUMBER
 	STRING
+	HEX // Hexadecimal literal, e.g. the layer mask 0x00010fc_ffffffff
+	EOF
 )
 
 func (t TokenType) String() string {
@@ -17,10 +19,15 @@ func (t TokenType) String() string {
 		"IDENTIFIER",
 		"NUMBER",
 		"STRING",
+		"HEX",
+		"EOF",
 	}[t]
 }
 
 type Token struct {
-	Type  TokenType
-	Value string
+	Type    TokenType
+	Value   string
+	Raw     string // Source text of the token, e.g. quotes included for strings
+	Leading string // Whitespace and skipped characters before the token
+	Pos     Position
 }
@@ -0,0 +1,255 @@
+package lexer
+
+import (
+	"errors"
+)
+
+// ErrRoot is returned when a Cursor is asked to change the siblings of the
+// root expression, which has none.
+var ErrRoot = errors.New("the root expression has no parent")
+
+// Visitor is called for every expression during Walk.
+type Visitor interface {
+	// Pre is called before the children of c are walked. Returning false
+	// skips the children and the call to Post.
+	Pre(c *Cursor) bool
+	// Post is called after the children of c have been walked.
+	Post(c *Cursor)
+}
+
+// Cursor is the position of an expression during Walk. Changes made through
+// it are written back to the tree.
+type Cursor struct {
+	parent   *Cursor
+	expr     *Expr
+	index    int // Index in the parent's Values
+	deleted  bool
+	inserted int // Siblings inserted after the expression, which are not walked
+}
+
+// Expr returns the current expression. It may be modified in place.
+func (c *Cursor) Expr() *Expr {
+	return c.expr
+}
+
+// Parent returns the cursor of the enclosing expression, nil for the root.
+func (c *Cursor) Parent() *Cursor {
+	return c.parent
+}
+
+// Index returns the index of the expression in its parent's Values, -1 for
+// the root.
+func (c *Cursor) Index() int {
+	return c.index
+}
+
+// Path returns the identifiers from the root down to the current
+// expression, e.g. [kicad_pcb footprint pad].
+func (c *Cursor) Path() []string {
+	if c.parent == nil {
+		return []string{c.expr.Identifier}
+	}
+	return append(c.parent.Path(), c.expr.Identifier)
+}
+
+// Replace replaces the current expression. When called from Pre, the
+// children of the replacement are walked.
+func (c *Cursor) Replace(e Expr) {
+	*c.expr = e
+}
+
+// Delete removes the current expression from its parent.
+func (c *Cursor) Delete() error {
+	if c.parent == nil {
+		return ErrRoot
+	}
+	c.deleted = true
+	return nil
+}
+
+// InsertAfter inserts e as the next sibling of the current expression. It is
+// not walked.
+func (c *Cursor) InsertAfter(e Expr) error {
+	if c.parent == nil {
+		return ErrRoot
+	}
+	c.parent.expr.InsertValue(c.index+1+c.inserted, ExprValue{Value: e})
+	c.inserted++
+	return nil
+}
+
+// InsertBefore inserts e as the previous sibling of the current expression.
+// It is not walked.
+func (c *Cursor) InsertBefore(e Expr) error {
+	if c.parent == nil {
+		return ErrRoot
+	}
+	c.parent.expr.InsertValue(c.index, ExprValue{Value: e})
+	c.index++
+	return nil
+}
+
+// Walk calls v for root and every nested expression in document order.
+func Walk(root *Expr, v Visitor) {
+	walk(&Cursor{expr: root, index: -1}, v)
+}
+
+func walk(c *Cursor, v Visitor) {
+	if !v.Pre(c) || c.deleted {
+		return
+	}
+	e := c.expr
+	for i := 0; i < len(e.Values); i++ {
+		exprVal, ok := e.Values[i].(ExprValue)
+		if !ok {
+			continue
+		}
+		child := exprVal.Value
+		childCursor := &Cursor{parent: c, expr: &child, index: i}
+		walk(childCursor, v)
+
+		// Values live in interfaces, so changes are made on a copy
+		i = childCursor.index
+		if childCursor.deleted {
+			e.RemoveValue(i)
+			i--
+		} else {
+			e.Values[i] = ExprValue{Value: child}
+		}
+		i += childCursor.inserted
+	}
+	v.Post(c)
+}
+
+type funcVisitor struct {
+	pre  func(c *Cursor) bool
+	post func(c *Cursor)
+}
+
+func (v funcVisitor) Pre(c *Cursor) bool {
+	return v.pre == nil || v.pre(c)
+}
+
+func (v funcVisitor) Post(c *Cursor) {
+	if v.post != nil {
+		v.post(c)
+	}
+}
+
+// WalkFunc is Walk with the hooks given as functions, either of which may be
+// nil.
+func WalkFunc(root *Expr, pre func(c *Cursor) bool, post func(c *Cursor)) {
+	Walk(root, funcVisitor{pre: pre, post: post})
+}
+
+// HasUUID returns a predicate matching expressions with a (uuid ...) or,
+// as in older files, (tstamp ...) child equal to uuid.
+func HasUUID(uuid string) func(Expr) bool {
+	return func(e Expr) bool {
+		for _, identifier := range []string{"uuid", "tstamp"} {
+			if child, err := e.Child(identifier); err == nil {
+				if id, err := child.TextAt(0); err == nil && id == uuid {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// RemoveAll removes every expression below e for which match returns true,
+// and returns how many were removed. The children of removed expressions are
+// not visited.
+func (e *Expr) RemoveAll(match func(Expr) bool) int {
+	count := 0
+	WalkFunc(e, func(c *Cursor) bool {
+		if c.Parent() != nil && match(*c.Expr()) {
+			c.Delete()
+			count++
+			return false
+		}
+		return true
+	}, nil)
+	return count
+}
+
+// ReplaceAll replaces every expression in e for which match returns true with
+// the result of replace, and returns how many were replaced. The replacements
+// are not searched.
+func (e *Expr) ReplaceAll(match func(Expr) bool, replace func(Expr) Expr) int {
+	count := 0
+	WalkFunc(e, func(c *Cursor) bool {
+		if match(*c.Expr()) {
+			c.Replace(replace(*c.Expr()))
+			count++
+			return false
+		}
+		return true
+	}, nil)
+	return count
+}
+
+// InsertAfter inserts a copy of sibling after every expression below e for
+// which match returns true, and returns how many were inserted.
+func (e *Expr) InsertAfter(match func(Expr) bool, sibling Expr) int {
+	count := 0
+	WalkFunc(e, func(c *Cursor) bool {
+		if c.Parent() != nil && match(*c.Expr()) {
+			c.InsertAfter(sibling)
+			count++
+		}
+		return true
+	}, nil)
+	return count
+}
+
+// alignLayout gives values appended or removed since parsing their own gaps
+// and lexemes, so that values can be inserted and removed by index.
+func (e *Expr) alignLayout() {
+	l := e.Layout
+	if l == nil || l.aligned(*e) || e.Type == ExprError {
+		return
+	}
+	if len(l.Gaps) == 0 {
+		l.Gaps = []string{""}
+	}
+	closing := l.Gaps[len(l.Gaps)-1]
+	fresh := l.freshGap()
+	gaps := l.Gaps[:len(l.Gaps)-1]
+	for len(gaps) < len(e.Values) {
+		gaps = append(gaps, fresh)
+	}
+	l.Gaps = append(gaps[:len(e.Values):len(e.Values)], closing)
+	for len(l.Lexemes) < len(e.Values) {
+		l.Lexemes = append(l.Lexemes, "")
+	}
+	l.Lexemes = l.Lexemes[:len(e.Values)]
+}
+
+// InsertValue inserts v so that it becomes e.Values[i]. In a parsed tree it
+// is separated from the previous value like the value it follows.
+func (e *Expr) InsertValue(i int, v Value) {
+	e.alignLayout()
+	e.Values = append(e.Values[:i], append([]Value{v}, e.Values[i:]...)...)
+	if l := e.Layout; l != nil && e.Type != ExprError {
+		gap := " "
+		switch {
+		case i > 0:
+			gap = l.Gaps[i-1]
+		case len(l.Gaps) > 1:
+			gap = l.Gaps[0]
+		}
+		l.Gaps = append(l.Gaps[:i], append([]string{gap}, l.Gaps[i:]...)...)
+		l.Lexemes = append(l.Lexemes[:i], append([]string{""}, l.Lexemes[i:]...)...)
+	}
+}
+
+// RemoveValue removes e.Values[i] together with the whitespace before it.
+func (e *Expr) RemoveValue(i int) {
+	e.alignLayout()
+	e.Values = append(e.Values[:i], e.Values[i+1:]...)
+	if l := e.Layout; l != nil && e.Type != ExprError {
+		l.Gaps = append(l.Gaps[:i], l.Gaps[i+1:]...)
+		l.Lexemes = append(l.Lexemes[:i], l.Lexemes[i+1:]...)
+	}
+}
@@ -0,0 +1,229 @@
+package lexer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const walkBoard = "(kicad_pcb\n" +
+	"\t(net 1 \"GND\")\n" +
+	"\t(segment\n\t\t(start 0 0)\n\t\t(end 1 0)\n\t\t(uuid \"s1\")\n\t)\n" +
+	"\t(via\n\t\t(at 1 0)\n\t\t(size 0.6)\n\t\t(uuid \"v1\")\n\t)\n" +
+	"\t(segment\n\t\t(start 1 0)\n\t\t(end 2 0)\n\t\t(uuid \"s2\")\n\t)\n" +
+	")\n"
+
+func parseWalkBoard(t *testing.T) Expr {
+	t.Helper()
+	tokens, err := Tokenize(walkBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expr
+}
+
+func TestWalkOrderAndParents(t *testing.T) {
+	// Arrange
+	expr := parseWalkBoard(t)
+	events := []string{}
+
+	// Act
+	WalkFunc(&expr, func(c *Cursor) bool {
+		events = append(events, "pre "+strings.Join(c.Path(), ">"))
+		// Skip the children of vias
+		return c.Expr().Type != ExprVia
+	}, func(c *Cursor) {
+		parent := "<root>"
+		if c.Parent() != nil {
+			parent = c.Parent().Expr().Identifier
+		}
+		events = append(events, "post "+c.Expr().Identifier+" in "+parent)
+	})
+
+	// Assert
+	want := []string{
+		"pre kicad_pcb",
+		"pre kicad_pcb>net", "post net in kicad_pcb",
+		"pre kicad_pcb>segment",
+		"pre kicad_pcb>segment>start", "post start in segment",
+		"pre kicad_pcb>segment>end", "post end in segment",
+		"pre kicad_pcb>segment>uuid", "post uuid in segment",
+		"post segment in kicad_pcb",
+		"pre kicad_pcb>via",
+		"pre kicad_pcb>segment",
+		"pre kicad_pcb>segment>start", "post start in segment",
+		"pre kicad_pcb>segment>end", "post end in segment",
+		"pre kicad_pcb>segment>uuid", "post uuid in segment",
+		"post segment in kicad_pcb",
+		"post kicad_pcb in <root>",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(events, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRemoveAllKeepsFormatting(t *testing.T) {
+	tests := []struct {
+		name  string
+		match func(Expr) bool
+		count int
+		want  string
+	}{
+		{
+			"by uuid",
+			HasUUID("v1"),
+			1,
+			"(kicad_pcb\n\t(net 1 \"GND\")\n" +
+				"\t(segment\n\t\t(start 0 0)\n\t\t(end 1 0)\n\t\t(uuid \"s1\")\n\t)\n" +
+				"\t(segment\n\t\t(start 1 0)\n\t\t(end 2 0)\n\t\t(uuid \"s2\")\n\t)\n)\n",
+		},
+		{
+			"by type",
+			func(e Expr) bool { return e.Type == ExprSegment },
+			2,
+			"(kicad_pcb\n\t(net 1 \"GND\")\n" +
+				"\t(via\n\t\t(at 1 0)\n\t\t(size 0.6)\n\t\t(uuid \"v1\")\n\t)\n)\n",
+		},
+		{
+			"nested",
+			func(e Expr) bool { return e.Type == ExprUUID },
+			3,
+			"(kicad_pcb\n\t(net 1 \"GND\")\n" +
+				"\t(segment\n\t\t(start 0 0)\n\t\t(end 1 0)\n\t)\n" +
+				"\t(via\n\t\t(at 1 0)\n\t\t(size 0.6)\n\t)\n" +
+				"\t(segment\n\t\t(start 1 0)\n\t\t(end 2 0)\n\t)\n)\n",
+		},
+		{"no match", HasUUID("missing"), 0, walkBoard},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			expr := parseWalkBoard(t)
+
+			// Act
+			count := expr.RemoveAll(tt.match)
+
+			// Assert
+			if count != tt.count {
+				t.Errorf("got %d removed, want %d", count, tt.count)
+			}
+			if got := expr.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceAllUpdatesViaSize(t *testing.T) {
+	// Arrange
+	expr := parseWalkBoard(t)
+
+	// Act
+	count := expr.ReplaceAll(func(e Expr) bool { return e.Type == ExprSize }, func(e Expr) Expr {
+		e.Values[0] = NumberValue{Value: 0.8}
+		return e
+	})
+
+	// Assert
+	if count != 1 {
+		t.Errorf("got %d replaced, want 1", count)
+	}
+	want := strings.Replace(walkBoard, "(size 0.6)", "(size 0.8)", 1)
+	if got := expr.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInsertAfterUsesSiblingIndentation(t *testing.T) {
+	// Arrange
+	expr := parseWalkBoard(t)
+	locked := Expr{Type: IdentifierToExprType("locked"), Identifier: "locked", Values: []Value{IdentifierValue{Value: "yes"}}}
+
+	// Act
+	count := expr.InsertAfter(func(e Expr) bool { return e.Type == ExprEnd }, locked)
+
+	// Assert
+	if count != 2 {
+		t.Errorf("got %d inserted, want 2", count)
+	}
+	want := strings.ReplaceAll(walkBoard, "0)\n\t\t(uuid \"s", "0)\n\t\t(locked yes)\n\t\t(uuid \"s")
+	if got := expr.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCursorEdits(t *testing.T) {
+	// Arrange
+	expr := parseWalkBoard(t)
+	marker := Expr{Identifier: "marker", Values: []Value{}}
+
+	// Act
+	WalkFunc(&expr, func(c *Cursor) bool {
+		switch {
+		case c.Expr().Type == ExprNet:
+			c.InsertBefore(marker)
+			c.InsertAfter(marker)
+		case c.Expr().Type == ExprVia:
+			c.Delete()
+		case c.Expr().Identifier == "marker":
+			t.Error("inserted expressions must not be walked")
+		}
+		return true
+	}, nil)
+
+	// Assert
+	ids := []string{}
+	for _, val := range expr.Values {
+		ids = append(ids, val.(ExprValue).Value.Identifier)
+	}
+	if want := []string{"marker", "net", "marker", "segment", "segment"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+	if !strings.HasPrefix(expr.String(), "(kicad_pcb\n\t(marker)\n\t(net 1 \"GND\")\n\t(marker)\n\t(segment") {
+		t.Errorf("unexpected formatting:\n%s", expr.String())
+	}
+}
+
+func TestCursorRootErrors(t *testing.T) {
+	expr := parseWalkBoard(t)
+	WalkFunc(&expr, func(c *Cursor) bool {
+		if c.Parent() == nil {
+			if err := c.Delete(); !errors.Is(err, ErrRoot) {
+				t.Errorf("Delete: got %v, want %v", err, ErrRoot)
+			}
+			if err := c.InsertAfter(Expr{}); !errors.Is(err, ErrRoot) {
+				t.Errorf("InsertAfter: got %v, want %v", err, ErrRoot)
+			}
+		}
+		return false
+	}, nil)
+}
+
+func TestMutationsAfterAppend(t *testing.T) {
+	// Arrange: values appended without layout, as AddSegmentsToExpr does
+	expr := parseWalkBoard(t)
+	expr.Values = append(expr.Values, ExprValue{Value: Expr{Type: ExprVia, Identifier: "via", Values: []Value{
+		ExprValue{Value: Expr{Type: ExprUUID, Identifier: "uuid", Values: []Value{StringValue{Value: "v2"}}}},
+	}}})
+
+	// Act
+	removed := expr.RemoveAll(HasUUID("s1"))
+
+	// Assert
+	if removed != 1 {
+		t.Fatalf("got %d removed, want 1", removed)
+	}
+	want := "(kicad_pcb\n\t(net 1 \"GND\")\n" +
+		"\t(via\n\t\t(at 1 0)\n\t\t(size 0.6)\n\t\t(uuid \"v1\")\n\t)\n" +
+		"\t(segment\n\t\t(start 1 0)\n\t\t(end 2 0)\n\t\t(uuid \"s2\")\n\t)\n" +
+		"\t(via\n\t\t(uuid \"v2\")\n\t)\n)\n"
+	if got := expr.String(); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
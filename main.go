@@ -1,58 +1,223 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/mackeper/lin_router/lexer"
 	"github.com/mackeper/lin_router/pcb"
 )
 
-func main() {
-	inputPath := flag.String("i", "", "Path to the KiCad PCB file to process (required)")
-	verbose := flag.Bool("v", false, "Enable verbose output")
-	maxDistance := flag.Float64("max-distance", 3.0, "Maximum routing distance in mm")
-	flag.Parse()
+// Exit codes shared by every command.
+const (
+	exitOK       = 0 // Done, and nothing to report
+	exitFindings = 1 // Done, and found something: DRC violations, differences, conflicts, unrouted nets
+	exitError    = 2 // Bad usage, unreadable input or unwritable output
+)
+
+// command is a subcommand of lin_router, e.g. `lin_router route`.
+type command struct {
+	name    string
+	summary string
+	run     func(g *globalFlags, args []string) int
+}
+
+// commands is filled in by init, as usage refers to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"route", "Add tracks and vias to a board", runRoute},
+		{"drc", "List copper of different nets closer than the clearance", runDRC},
+		{"stats", "Count the nets, footprints, pads, tracks and vias of a board", runStats},
+		{"ratsnest", "List the connections still to be routed", runRatsnest},
+		{"fmt", "Reformat boards in place in pcbnew's style", runFmt},
+		{"check", "List all syntax errors in boards", runCheck},
+		{"query", "Print the expressions matching a path", runQuery},
+		{"export", "Write a board as JSON or as a Specctra design", runExport},
+		{"import", "Write a board from JSON, or add the routes of a Specctra session", runImport},
+		{"diff", "List the tracks, vias, footprints and zones that differ between two boards", runDiff},
+		{"merge", "Merge two boards changed from a common base", runMerge},
+	}
+}
+
+// globalFlags are accepted before the command and by every command.
+type globalFlags struct {
+	verbose bool
+	output  string
+}
+
+// register adds the global flags to a flag set, keeping values already
+// parsed before the command.
+func (g *globalFlags) register(flags *flag.FlagSet) {
+	flags.BoolVar(&g.verbose, "v", g.verbose, "Log debug messages to stderr")
+	flags.StringVar(&g.output, "o", g.output, "Write the output to this file instead of stdout")
+}
 
-	// Setup logging
+// writeOutput writes data to the -o file, atomically, or to stdout.
+func (g *globalFlags) writeOutput(data []byte) error {
+	if g.output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return WritePcbFile(g.output, data, WriteOptions{})
+}
+
+// newFlagSet returns the flag set of a command, with the global flags and a
+// usage message of the form
+//
+//	Usage: lin_router NAME [flags] ARGS
+//
+//	DESCRIPTION
+//
+//	Flags: ...
+func (g *globalFlags) newFlagSet(name, args, description string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	g.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags] %s\n\n%s\n\nFlags:\n", os.Args[0], name, args, description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse reads the command line of a command and sets up logging.
+func (g *globalFlags) parse(flags *flag.FlagSet, args []string) {
+	flags.Parse(args)
 	level := slog.LevelError
-	if *verbose {
+	if g.verbose {
 		level = slog.LevelDebug
 	}
-	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run runs the command named by args and returns its exit code.
+func run(args []string) int {
+	g := &globalFlags{}
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	g.register(flags)
+	flags.Usage = func() { usage(flags) }
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if flags.NArg() == 0 {
+		usage(flags)
+		return exitError
+	}
 
-	if *inputPath == "" {
-		slog.Error("-i flag is required")
-		flag.Usage()
-		os.Exit(1)
+	name, rest := flags.Arg(0), flags.Args()[1:]
+	if name == "help" {
+		if len(rest) == 0 {
+			flags.SetOutput(os.Stdout)
+			usage(flags)
+			return exitOK
+		}
+		name, rest = rest[0], []string{"-h"}
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(g, rest)
+		}
 	}
+	fmt.Fprintf(os.Stderr, "%s: unknown command %q\n", os.Args[0], name)
+	usage(flags)
+	return exitError
+}
 
-	slog.Debug("Parsing PCB file", "path", *inputPath)
-	expr, err := ParsePcbFile(*inputPath)
-	if err != nil {
-		slog.Error("Error parsing PCB file", "error", err)
-		os.Exit(1)
+func usage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprintf(out, "Usage: %s [global flags] COMMAND [flags] ARGS\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(out, "\nRun '%s help COMMAND' for the flags of a command.\n\nGlobal flags, also accepted after the command:\n", os.Args[0])
+	flags.PrintDefaults()
+	fmt.Fprintf(out, "\nExit codes: %d done, %d done with findings such as DRC violations, %d error.\n", exitOK, exitFindings, exitError)
+}
 
-	slog.Debug("Converting expression to PCB structure")
+// readBoard parses the board at path and reads its model, reporting errors
+// on stderr.
+func readBoard(path string) (lexer.Expr, *pcb.Board, bool) {
+	expr, err := ParsePcbFile(path)
+	if err != nil {
+		printFileError(path, err)
+		return lexer.Expr{}, nil, false
+	}
 	board, err := ExprToPCB(expr)
 	if err != nil {
-		slog.Error("Error converting expression to PCB", "error", err)
-		os.Exit(1)
+		printFileError(path, err)
+		return lexer.Expr{}, nil, false
 	}
+	return expr, board, true
+}
 
-	slog.Debug("Adding trivial segments to PCB", "max_distance", *maxDistance)
-	pcb.AddTrivialSegments(board, *maxDistance)
+// sameFile reports whether two paths name the same existing file.
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
 
-	slog.Debug("Converting PCB structure back to expression")
-	expr, err = AddSegmentsToExpr(board, &expr)
-	if err != nil {
-		slog.Error("Error converting PCB back to expression", "error", err)
-		os.Exit(1)
+func printRouteReport(report pcb.RouteReport) {
+	fmt.Fprintf(os.Stderr, "Routed %d nets in %d iterations, %d failed\n",
+		len(report.RoutedNets), report.Iterations, len(report.FailedNets))
+	for _, net := range report.FailedNets {
+		fmt.Fprintf(os.Stderr, "  could not legalise net %d %q\n", net.Number, net.Name)
 	}
+}
 
-	fmt.Println(expr.String())
+// printError reports an error that is not about a particular file.
+func printError(err error) {
+	fmt.Fprintf(os.Stderr, "%s: error: %v\n", os.Args[0], err)
+}
+
+func printWarning(err error) {
+	fmt.Fprintf(os.Stderr, "%s: warning: %v\n", os.Args[0], err)
+}
+
+// printFileError reports an error reading path to stderr. Syntax errors are
+// printed like compiler diagnostics, see printDiagnostic.
+func printFileError(path string, err error) {
+	var parseErr *lexer.ParseError
+	if !errors.As(err, &parseErr) {
+		fmt.Fprintf(os.Stderr, "%s: error: %v\n", path, err)
+		return
+	}
+	printDiagnostic(path, "error", parseErr)
+}
+
+// printDiagnostic prints a syntax error in path to stderr like a compiler
+// diagnostic with the offending source line:
+//
+//	board.kicad_pcb:12:5: error: expected ')', got end of input in kicad_pcb > footprint
+//	12 | 	(footprint "R1"
+//	   | 	^
+func printDiagnostic(path, severity string, parseErr *lexer.ParseError) {
+	fmt.Fprintf(os.Stderr, "%s:%s: %s: %s\n", path, parseErr.Pos, severity, parseErr.Message())
+	if parseErr.Excerpt != "" {
+		fmt.Fprintln(os.Stderr, parseErr.Excerpt)
+	}
+}
+
+// scalarValues returns the values of e that are not nested expressions,
+// separated by spaces.
+func scalarValues(e lexer.Expr) string {
+	values := []string{}
+	for _, val := range e.Values {
+		if _, ok := val.(lexer.ExprValue); !ok {
+			values = append(values, val.String())
+		}
+	}
+	return strings.Join(values, " ")
 }
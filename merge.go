@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+// MergeConflict is a change MergeBoards could not combine. The merged board
+// keeps our side of it.
+type MergeConflict struct {
+	Kind   string // Identifier of the item, e.g. segment
+	Key    string // UUID, net name, or identifier and ordinal for other items
+	Reason string
+}
+
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("%s %s: %s", c.Kind, c.Key, c.Reason)
+}
+
+// mergeItem is a top-level expression of a board, with its text on one line
+// to compare versions of it.
+type mergeItem struct {
+	expr lexer.Expr
+	text string
+}
+
+// mergeKeys returns a key per value of a board, naming the same item in
+// every version of it: the UUID when it has one, the name for nets and the
+// identifier and ordinal for anything else, such as (setup ...). Values that
+// are not expressions get an empty key.
+func mergeKeys(board lexer.Expr) []string {
+	keys := make([]string, len(board.Values))
+	ordinals := make(map[string]int) // Items without a UUID by identifier
+	seen := make(map[string]int)
+	for i, val := range board.Values {
+		v, ok := val.(lexer.ExprValue)
+		if !ok {
+			continue
+		}
+		key := v.Value.Identifier
+		if uuid := itemUUID(v.Value); uuid != "" {
+			key += "|" + uuid
+		} else if name, err := v.Value.TextAt(1); err == nil && v.Value.Type == lexer.ExprNet {
+			key += "|" + name
+		} else {
+			key += fmt.Sprintf("#%d", ordinals[key])
+			ordinals[v.Value.Identifier]++
+		}
+		if n := seen[key]; n > 0 {
+			// A UUID used twice
+			key += fmt.Sprintf("#%d", n)
+		}
+		seen[key]++
+		keys[i] = key
+	}
+	return keys
+}
+
+// mergeItems returns the expressions of a board by their mergeKeys.
+func mergeItems(board lexer.Expr, keys []string) map[string]mergeItem {
+	items := make(map[string]mergeItem)
+	for i, key := range keys {
+		if key != "" {
+			e := board.Values[i].(lexer.ExprValue).Value
+			items[key] = mergeItem{expr: e, text: lexer.FormatInline(e)}
+		}
+	}
+	return items
+}
+
+// newConflict describes a conflict on the item with the given key.
+func newConflict(key, reason string) MergeConflict {
+	kind, rest, ok := strings.Cut(key, "|")
+	if !ok {
+		kind, _, _ = strings.Cut(key, "#")
+		rest = key
+	}
+	return MergeConflict{Kind: kind, Key: rest, Reason: reason}
+}
+
+// MergeBoards merges the changes from base to theirs into ours, comparing
+// the top-level items of the board. An item changed on one side only takes
+// that change and an item changed the same way on both sides is kept; an
+// item changed differently on both sides is a conflict and keeps our
+// version. Tracks and vias added or moved by one side that overlap copper of
+// another net added or moved by the other side are conflicts too, although
+// both are kept. Everything not taken from theirs keeps our formatting.
+func MergeBoards(base, ours, theirs lexer.Expr) (lexer.Expr, []MergeConflict) {
+	baseKeys, ourKeys, theirKeys := mergeKeys(base), mergeKeys(ours), mergeKeys(theirs)
+	baseItems, ourItems, theirItems := mergeItems(base, baseKeys), mergeItems(ours, ourKeys), mergeItems(theirs, theirKeys)
+	conflicts := []MergeConflict{}
+
+	merged := ours
+	merged.Values = append([]lexer.Value{}, ours.Values...)
+	if ours.Layout != nil {
+		layout := *ours.Layout
+		layout.Gaps = append([]string{}, layout.Gaps...)
+		layout.Lexemes = append([]string{}, layout.Lexemes...)
+		merged.Layout = &layout
+	}
+	mergedKeys := append([]string{}, ourKeys...)
+
+	// resolve applies their change to an item when we left it alone, and
+	// records a conflict when we changed it differently.
+	resolve := func(key string) {
+		b, inBase := baseItems[key]
+		o, inOurs := ourItems[key]
+		t, inTheirs := theirItems[key]
+		switch {
+		case inTheirs == inBase && t.text == b.text, inTheirs == inOurs && t.text == o.text:
+			// Unchanged by them, or changed the same way as by us
+		case inOurs == inBase && o.text == b.text:
+			switch {
+			case !inTheirs:
+				i := keyIndex(mergedKeys, key)
+				merged.RemoveValue(i)
+				mergedKeys = append(mergedKeys[:i], mergedKeys[i+1:]...)
+			case inOurs:
+				merged.Values[keyIndex(mergedKeys, key)] = lexer.ExprValue{Value: t.expr}
+			default:
+				i := insertIndex(key, theirKeys, mergedKeys, func(k string) bool {
+					_, inBase := baseItems[k]
+					_, inTheirs := theirItems[k]
+					return !inBase && !inTheirs
+				})
+				merged.InsertValue(i, lexer.ExprValue{Value: t.expr})
+				mergedKeys = append(mergedKeys[:i], append([]string{key}, mergedKeys[i:]...)...)
+			}
+		case !inBase:
+			conflicts = append(conflicts, newConflict(key, "added differently on both sides"))
+		case !inOurs:
+			conflicts = append(conflicts, newConflict(key, "removed by us, modified by them"))
+		case !inTheirs:
+			conflicts = append(conflicts, newConflict(key, "modified by us, removed by them"))
+		default:
+			conflicts = append(conflicts, newConflict(key, "modified differently on both sides"))
+		}
+	}
+	for _, key := range baseKeys {
+		if _, ok := theirItems[key]; key != "" && !ok {
+			resolve(key)
+		}
+	}
+	for _, key := range theirKeys {
+		if key != "" {
+			resolve(key)
+		}
+	}
+
+	conflicts = append(conflicts, netNumberConflicts(merged)...)
+	ourDec, theirDec := newDecoder(ours), newDecoder(theirs)
+	conflicts = append(conflicts, copperConflicts(
+		copperChanges(baseItems, ourItems, ourKeys, ourDec),
+		copperChanges(baseItems, theirItems, theirKeys, theirDec),
+	)...)
+	return merged, conflicts
+}
+
+// insertIndex places an item added by them after the nearest item before it
+// in their file that is also in the merged board, and after anything we
+// added there.
+func insertIndex(key string, theirKeys, mergedKeys []string, ourAddition func(string) bool) int {
+	for i := keyIndex(theirKeys, key) - 1; i >= 0; i-- {
+		if theirKeys[i] == "" {
+			continue
+		}
+		if j := keyIndex(mergedKeys, theirKeys[i]); j >= 0 {
+			for j+1 < len(mergedKeys) && mergedKeys[j+1] != "" && ourAddition(mergedKeys[j+1]) {
+				j++
+			}
+			return j + 1
+		}
+	}
+	return 0
+}
+
+func keyIndex(keys []string, key string) int {
+	for i, k := range keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// netNumberConflicts finds net numbers given to different nets by the two
+// sides, e.g. when both added a net.
+func netNumberConflicts(merged lexer.Expr) []MergeConflict {
+	conflicts := []MergeConflict{}
+	names := make(map[int]string)
+	for _, net := range merged.Children("net") {
+		number, numErr := net.NumberAt(0)
+		name, nameErr := net.TextAt(1)
+		if numErr != nil || nameErr != nil {
+			continue
+		}
+		if other, ok := names[int(number)]; ok && other != name {
+			conflicts = append(conflicts, MergeConflict{
+				Kind:   "net",
+				Key:    name,
+				Reason: fmt.Sprintf("net number %d is also used by %s", int(number), other),
+			})
+			continue
+		}
+		names[int(number)] = name
+	}
+	return conflicts
+}
+
+// copperChange is a track or via added or moved by one side of a merge.
+type copperChange struct {
+	key     string
+	net     string
+	segment *pcb.Segment
+	via     *pcb.Via
+}
+
+// copperChanges returns the segments and vias of a side that differ from
+// base, in file order.
+func copperChanges(baseItems, sideItems map[string]mergeItem, sideKeys []string, d *decoder) []copperChange {
+	changes := []copperChange{}
+	for _, key := range sideKeys {
+		item, ok := sideItems[key]
+		if !ok {
+			continue
+		}
+		if b, ok := baseItems[key]; ok && b.text == item.text {
+			continue
+		}
+		change := copperChange{key: key, net: itemNet(item.expr, d)}
+		switch item.expr.Type {
+		case lexer.ExprSegment:
+			segment := pcb.Segment{}
+			if err := lexer.Unmarshal(item.expr, &segment); err != nil {
+				continue
+			}
+			change.segment = &segment
+		case lexer.ExprVia:
+			via, err := d.via(item.expr)
+			if err != nil {
+				continue
+			}
+			change.via = &via
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// copperConflicts finds copper of one side touching copper of another net
+// on the other side.
+func copperConflicts(ours, theirs []copperChange) []MergeConflict {
+	conflicts := []MergeConflict{}
+	for _, o := range ours {
+		for _, t := range theirs {
+			if o.net == t.net || !o.overlaps(t) {
+				continue
+			}
+			kind, uuid, _ := strings.Cut(t.key, "|")
+			conflicts = append(conflicts, newConflict(o.key,
+				fmt.Sprintf("our %s copper overlaps their %s %s of net %s", o.net, kind, uuid, t.net)))
+		}
+	}
+	return conflicts
+}
+
+// overlaps reports whether the copper of two changes touches. Any clearance
+// violation is left to DRC; only shorts make a merge conflict.
+func (c copperChange) overlaps(other copperChange) bool {
+	switch {
+	case c.segment != nil && other.segment != nil:
+		return c.segment.Overlaps(*other.segment, 0)
+	case c.segment != nil:
+		return other.via.OverlapsSegment(*c.segment, 0)
+	case other.segment != nil:
+		return c.via.OverlapsSegment(*other.segment, 0)
+	default:
+		return c.via.Overlaps(*other.via, 0)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// applyReplacements applies pairs of old and new text to source.
+func applyReplacements(source string, replace []string) string {
+	for i := 0; i+1 < len(replace); i += 2 {
+		source = strings.Replace(source, replace[i], replace[i+1], 1)
+	}
+	return source
+}
+
+func TestMergeBoards(t *testing.T) {
+	tests := []struct {
+		name      string
+		ours      []string // Pairs of old and new text applied to diffBase
+		theirs    []string
+		expected  []string // Pairs applied to diffBase to get the merged board
+		conflicts []string // MergeConflict.String of each conflict
+	}{
+		{
+			"no changes",
+			nil,
+			nil,
+			nil,
+			[]string{},
+		},
+		{
+			"their segment added",
+			nil,
+			[]string{"(uuid \"v1\"))", "(uuid \"v1\"))\n\t(segment (start 5 0) (end 5 5) (width 0.2) (layer \"B.Cu\") (net 1) (uuid \"s2\"))"},
+			[]string{"(uuid \"v1\"))", "(uuid \"v1\"))\n\t(segment (start 5 0) (end 5 5) (width 0.2) (layer \"B.Cu\") (net 1) (uuid \"s2\"))"},
+			[]string{},
+		},
+		{
+			"their via removed",
+			nil,
+			[]string{"\n\t(via (at 5 0) (size 0.6) (drill 0.3) (layers \"F.Cu\" \"B.Cu\") (net 1) (uuid \"v1\"))", ""},
+			[]string{"\n\t(via (at 5 0) (size 0.6) (drill 0.3) (layers \"F.Cu\" \"B.Cu\") (net 1) (uuid \"v1\"))", ""},
+			[]string{},
+		},
+		{
+			"changes on both sides to different items",
+			[]string{"(at 10 10)", "(at 12 10)"},
+			[]string{"(width 0.2)", "(width 0.25)"},
+			[]string{"(at 10 10)", "(at 12 10)", "(width 0.2)", "(width 0.25)"},
+			[]string{},
+		},
+		{
+			"same change on both sides",
+			[]string{"(width 0.2)", "(width 0.25)"},
+			[]string{"(width 0.2)", "(width 0.25)"},
+			[]string{"(width 0.2)", "(width 0.25)"},
+			[]string{},
+		},
+		{
+			"different changes to the same item",
+			[]string{"(width 0.2)", "(width 0.25)"},
+			[]string{"(width 0.2)", "(width 0.3)"},
+			[]string{"(width 0.2)", "(width 0.25)"},
+			[]string{"segment s1: modified differently on both sides"},
+		},
+		{
+			"modified by us, removed by them",
+			[]string{"(size 0.6)", "(size 0.8)"},
+			[]string{"\n\t(via (at 5 0) (size 0.6) (drill 0.3) (layers \"F.Cu\" \"B.Cu\") (net 1) (uuid \"v1\"))", ""},
+			[]string{"(size 0.6)", "(size 0.8)"},
+			[]string{"via v1: modified by us, removed by them"},
+		},
+		{
+			"nets added with the same number",
+			[]string{`(net 2 "VCC")`, `(net 2 "VCC") (net 3 "SDA")`},
+			[]string{`(net 2 "VCC")`, `(net 2 "VCC") (net 3 "SCL")`},
+			[]string{`(net 2 "VCC")`, `(net 2 "VCC") (net 3 "SDA") (net 3 "SCL")`},
+			[]string{"net SCL: net number 3 is also used by SDA"},
+		},
+		{
+			"tracks of different nets crossing",
+			[]string{"(uuid \"v1\"))", "(uuid \"v1\"))\n\t(segment (start 2 -2) (end 2 2) (width 0.2) (layer \"F.Cu\") (net 2) (uuid \"s2\"))"},
+			[]string{"(uuid \"v1\"))", "(uuid \"v1\"))\n\t(segment (start 0 1) (end 4 -1) (width 0.2) (layer \"F.Cu\") (net 1) (uuid \"s3\"))"},
+			[]string{"(uuid \"v1\"))", "(uuid \"v1\"))\n\t(segment (start 2 -2) (end 2 2) (width 0.2) (layer \"F.Cu\") (net 2) (uuid \"s2\"))\n\t(segment (start 0 1) (end 4 -1) (width 0.2) (layer \"F.Cu\") (net 1) (uuid \"s3\"))"},
+			[]string{"segment s2: our VCC copper overlaps their segment s3 of net GND"},
+		},
+		{
+			"tracks of different nets on different layers",
+			[]string{"(uuid \"v1\"))", "(uuid \"v1\"))\n\t(segment (start 2 -2) (end 2 2) (width 0.2) (layer \"B.Cu\") (net 2) (uuid \"s2\"))"},
+			[]string{"(uuid \"v1\"))", "(uuid \"v1\"))\n\t(segment (start 0 1) (end 4 -1) (width 0.2) (layer \"F.Cu\") (net 1) (uuid \"s3\"))"},
+			[]string{"(uuid \"v1\"))", "(uuid \"v1\"))\n\t(segment (start 2 -2) (end 2 2) (width 0.2) (layer \"B.Cu\") (net 2) (uuid \"s2\"))\n\t(segment (start 0 1) (end 4 -1) (width 0.2) (layer \"F.Cu\") (net 1) (uuid \"s3\"))"},
+			[]string{},
+		},
+		{
+			"via moved onto a track of another net",
+			[]string{"(width 0.2) (layer \"F.Cu\") (net 1)", "(width 0.2) (layer \"F.Cu\") (net 2)"},
+			[]string{"(at 5 0) (size 0.6)", "(at 3 0) (size 0.6)"},
+			[]string{"(width 0.2) (layer \"F.Cu\") (net 1)", "(width 0.2) (layer \"F.Cu\") (net 2)", "(at 5 0) (size 0.6)", "(at 3 0) (size 0.6)"},
+			[]string{"segment s1: our VCC copper overlaps their via v1 of net GND"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			base := parseBoardString(t, diffBase)
+			ours := parseBoardString(t, applyReplacements(diffBase, tt.ours))
+			theirs := parseBoardString(t, applyReplacements(diffBase, tt.theirs))
+
+			// Act
+			merged, conflicts := MergeBoards(base, ours, theirs)
+
+			// Assert
+			if expected := applyReplacements(diffBase, tt.expected); merged.String() != expected {
+				t.Errorf("Expected merged board\n%s\ngot\n%s", expected, merged.String())
+			}
+			got := []string{}
+			for _, conflict := range conflicts {
+				got = append(got, conflict.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.conflicts, "\n") {
+				t.Errorf("Expected conflicts\n%s\ngot\n%s", strings.Join(tt.conflicts, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestMergeBoards_KeepsOurFormatting(t *testing.T) {
+	// Arrange
+	data, err := os.ReadFile("test_data/main.kicad_pcb")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	source := string(data)
+	base := parseBoardString(t, source)
+	ours := parseBoardString(t, source)
+	theirs := parseBoardString(t, strings.Replace(source, "(generator_version \"8.0\")", "(generator_version \"8.1\")", 1))
+
+	// Act
+	merged, conflicts := MergeBoards(base, ours, theirs)
+
+	// Assert
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+	expected := strings.Replace(source, "(generator_version \"8.0\")", "(generator_version \"8.1\")", 1)
+	if merged.String() != expected {
+		t.Error("Expected the merged board to be our file with their change")
+	}
+	if ours.String() != source {
+		t.Error("Expected MergeBoards to leave ours unchanged")
+	}
+}
@@ -0,0 +1,12 @@
+package pcb
+
+// Footprint is a placed part. Its pads are in Board.Pads, with Reference set
+// to the footprint's.
+type Footprint struct {
+	Reference string   // Reference designator, e.g. R1
+	Name      string   // Library name, e.g. Resistor_SMD:R_0805
+	Layer     string   // F.Cu or B.Cu
+	Position  Position // Absolute position of the footprint's origin
+	Rotation  float64  // Degrees counterclockwise
+	UUID      string
+}
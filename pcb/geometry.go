@@ -0,0 +1,59 @@
+package pcb
+
+import "math"
+
+// DistanceToPoint returns the distance from p to the nearest point of the
+// segment's centre line.
+func (s Segment) DistanceToPoint(p Position) float64 {
+	dx, dy := s.End.X-s.Start.X, s.End.Y-s.Start.Y
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return s.Start.Distance(p)
+	}
+	// Project p onto the line and clamp to the segment
+	t := ((p.X-s.Start.X)*dx + (p.Y-s.Start.Y)*dy) / lengthSquared
+	t = math.Max(0, math.Min(1, t))
+	return p.Distance(Position{X: s.Start.X + t*dx, Y: s.Start.Y + t*dy})
+}
+
+// DistanceTo returns the distance between the centre lines of two segments,
+// 0 when they cross.
+func (s Segment) DistanceTo(other Segment) float64 {
+	if segmentsCross(s, other) {
+		return 0
+	}
+	return math.Min(
+		math.Min(s.DistanceToPoint(other.Start), s.DistanceToPoint(other.End)),
+		math.Min(other.DistanceToPoint(s.Start), other.DistanceToPoint(s.End)),
+	)
+}
+
+func segmentsCross(a, b Segment) bool {
+	orientation := func(p, q, r Position) float64 {
+		return (q.X-p.X)*(r.Y-p.Y) - (q.Y-p.Y)*(r.X-p.X)
+	}
+	d1 := orientation(b.Start, b.End, a.Start)
+	d2 := orientation(b.Start, b.End, a.End)
+	d3 := orientation(a.Start, a.End, b.Start)
+	d4 := orientation(a.Start, a.End, b.End)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// Overlaps reports whether the copper of two segments on the same layer
+// comes closer than clearance.
+func (s Segment) Overlaps(other Segment, clearance float64) bool {
+	return s.Layer == other.Layer && s.DistanceTo(other) < (s.Width+other.Width)/2+clearance
+}
+
+// OverlapsSegment reports whether the copper of a via and a segment comes
+// closer than clearance. Vias are taken to span every copper layer.
+func (v Via) OverlapsSegment(s Segment, clearance float64) bool {
+	return s.DistanceToPoint(v.Position) < (v.Size+s.Width)/2+clearance
+}
+
+// Overlaps reports whether the copper of two vias comes closer than
+// clearance.
+func (v Via) Overlaps(other Via, clearance float64) bool {
+	return v.Distance(other) < (v.Size+other.Size)/2+clearance
+}
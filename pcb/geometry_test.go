@@ -0,0 +1,83 @@
+package pcb
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSegmentDistanceToPoint(t *testing.T) {
+	seg := Segment{Start: Position{0, 0}, End: Position{10, 0}}
+	tests := []struct {
+		name     string
+		point    Position
+		expected float64
+	}{
+		{"on the segment", Position{5, 0}, 0},
+		{"beside the middle", Position{5, 3}, 3},
+		{"past the start", Position{-3, 4}, 5},
+		{"past the end", Position{13, 0}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seg.DistanceToPoint(tt.point); math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("Expected %f, got %f", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSegmentOverlaps(t *testing.T) {
+	base := Segment{Start: Position{0, 0}, End: Position{10, 0}, Width: 0.2, Layer: "F.Cu"}
+	tests := []struct {
+		name     string
+		other    Segment
+		expected bool
+	}{
+		{"crossing", Segment{Start: Position{5, -5}, End: Position{5, 5}, Width: 0.2, Layer: "F.Cu"}, true},
+		{"crossing on another layer", Segment{Start: Position{5, -5}, End: Position{5, 5}, Width: 0.2, Layer: "B.Cu"}, false},
+		{"parallel within clearance", Segment{Start: Position{0, 0.3}, End: Position{10, 0.3}, Width: 0.2, Layer: "F.Cu"}, true},
+		{"parallel with room", Segment{Start: Position{0, 1}, End: Position{10, 1}, Width: 0.2, Layer: "F.Cu"}, false},
+		{"collinear beyond the end", Segment{Start: Position{11, 0}, End: Position{20, 0}, Width: 0.2, Layer: "F.Cu"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.Overlaps(tt.other, DefaultClearance); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if got := tt.other.Overlaps(base, DefaultClearance); got != tt.expected {
+				t.Errorf("Expected %v the other way round, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestViaOverlaps(t *testing.T) {
+	via := Via{Position: Position{0, 0}, Size: 0.6}
+	tests := []struct {
+		name     string
+		segment  *Segment
+		via      *Via
+		expected bool
+	}{
+		{"segment through the via", &Segment{Start: Position{-5, 0}, End: Position{5, 0}, Width: 0.2, Layer: "B.Cu"}, nil, true},
+		{"segment passing by", &Segment{Start: Position{-5, 1}, End: Position{5, 1}, Width: 0.2, Layer: "F.Cu"}, nil, false},
+		{"touching via", nil, &Via{Position: Position{0.7, 0}, Size: 0.6}, true},
+		{"distant via", nil, &Via{Position: Position{2, 0}, Size: 0.6}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bool
+			if tt.segment != nil {
+				got = via.OverlapsSegment(*tt.segment, DefaultClearance)
+			} else {
+				got = via.Overlaps(*tt.via, DefaultClearance)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package pcb
+
+import "encoding/json"
+
+// BoardSchemaVersion is the "schema_version" written by Board.MarshalJSON.
+// It is raised when a field is removed or changes meaning; fields may be
+// added without raising it. The schema is board.schema.json next to this
+// file.
+const BoardSchemaVersion = 1
+
+// boardJSON is the JSON encoding of a Board. Lengths are in mm, angles in
+// degrees counterclockwise, and nets are referred to by number.
+type boardJSON struct {
+	SchemaVersion int             `json:"schema_version"`
+	Units         string          `json:"units"`
+	Nets          []netJSON       `json:"nets"`
+	Footprints    []footprintJSON `json:"footprints"`
+	Pads          []padJSON       `json:"pads"`
+	Tracks        []trackJSON     `json:"tracks"`
+	Vias          []viaJSON       `json:"vias"`
+	Outline       []outlineJSON   `json:"outline"`
+}
+
+type pointJSON struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type netJSON struct {
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+}
+
+type footprintJSON struct {
+	Reference string    `json:"reference"`
+	Name      string    `json:"name"`
+	Layer     string    `json:"layer"`
+	Position  pointJSON `json:"position"`
+	Rotation  float64   `json:"rotation"`
+	UUID      string    `json:"uuid"`
+}
+
+type padJSON struct {
+	Footprint     string    `json:"footprint"` // Reference of the footprint
+	FootprintUUID string    `json:"footprint_uuid"`
+	Number        string    `json:"number"`
+	Net           int       `json:"net"`
+	Type          string    `json:"type"`
+	Shape         string    `json:"shape"`
+	Position      pointJSON `json:"position"` // Absolute
+	Width         float64   `json:"width"`
+	Height        float64   `json:"height"`
+	Rotation      float64   `json:"rotation"`
+	Drill         float64   `json:"drill"`
+	Layers        []string  `json:"layers"`
+}
+
+type trackJSON struct {
+	Start pointJSON `json:"start"`
+	End   pointJSON `json:"end"`
+	Width float64   `json:"width"`
+	Layer string    `json:"layer"`
+	Net   int       `json:"net"`
+	UUID  string    `json:"uuid"`
+}
+
+type viaJSON struct {
+	Position pointJSON `json:"position"`
+	Size     float64   `json:"size"`
+	Drill    float64   `json:"drill"`
+	Layers   []string  `json:"layers"`
+	Net      int       `json:"net"`
+	UUID     string    `json:"uuid"`
+}
+
+type outlineJSON struct {
+	Kind   string      `json:"kind"`
+	Points []pointJSON `json:"points"`
+	Width  float64     `json:"width"`
+}
+
+func toPointJSON(p Position) pointJSON {
+	return pointJSON{X: p.X, Y: p.Y}
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// MarshalJSON writes the board in the versioned schema of
+// board.schema.json, for tools that do not read KiCad files themselves.
+func (b Board) MarshalJSON() ([]byte, error) {
+	out := boardJSON{
+		SchemaVersion: BoardSchemaVersion,
+		Units:         "mm",
+		Nets:          make([]netJSON, 0, len(b.Nets)),
+		Footprints:    make([]footprintJSON, 0, len(b.Footprints)),
+		Pads:          make([]padJSON, 0, len(b.Pads)),
+		Tracks:        make([]trackJSON, 0, len(b.Segments)),
+		Vias:          make([]viaJSON, 0, len(b.Vias)),
+		Outline:       make([]outlineJSON, 0, len(b.Outline)),
+	}
+	for _, net := range b.Nets {
+		out.Nets = append(out.Nets, netJSON{Number: net.Number, Name: net.Name})
+	}
+	for _, fp := range b.Footprints {
+		out.Footprints = append(out.Footprints, footprintJSON{
+			Reference: fp.Reference,
+			Name:      fp.Name,
+			Layer:     fp.Layer,
+			Position:  toPointJSON(fp.Position),
+			Rotation:  fp.Rotation,
+			UUID:      fp.UUID,
+		})
+	}
+	for _, pad := range b.Pads {
+		out.Pads = append(out.Pads, padJSON{
+			Footprint:     pad.Reference,
+			FootprintUUID: pad.FootprintUUID,
+			Number:        pad.Number,
+			Net:           pad.Net.Number,
+			Type:          pad.Type,
+			Shape:         pad.Shape,
+			Position:      toPointJSON(pad.Position),
+			Width:         pad.Size.Width,
+			Height:        pad.Size.Height,
+			Rotation:      pad.Rotation,
+			Drill:         pad.Drill,
+			Layers:        nonNil(pad.Layers),
+		})
+	}
+	for _, seg := range b.Segments {
+		out.Tracks = append(out.Tracks, trackJSON{
+			Start: toPointJSON(seg.Start),
+			End:   toPointJSON(seg.End),
+			Width: seg.Width,
+			Layer: seg.Layer,
+			Net:   seg.Net,
+			UUID:  seg.UUID,
+		})
+	}
+	for _, via := range b.Vias {
+		out.Vias = append(out.Vias, viaJSON{
+			Position: toPointJSON(via.Position),
+			Size:     via.Size,
+			Drill:    via.Drill,
+			Layers:   nonNil(via.Layers),
+			Net:      via.Net,
+			UUID:     via.UUID,
+		})
+	}
+	for _, shape := range b.Outline {
+		points := make([]pointJSON, 0, len(shape.Points))
+		for _, p := range shape.Points {
+			points = append(points, toPointJSON(p))
+		}
+		out.Outline = append(out.Outline, outlineJSON{Kind: shape.Kind, Points: points, Width: shape.Width})
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,151 @@
+package pcb
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sampleBoard() *Board {
+	board := NewBoard()
+	board.Nets = append(board.Nets, Net{Number: 0}, Net{Number: 1, Name: "GND"})
+	board.Footprints = append(board.Footprints, Footprint{Reference: "R1", Name: "R_0805", Layer: "F.Cu", Position: Position{10, 10}, Rotation: 90, UUID: "f1"})
+	board.AddPad(Pad{
+		Position:      Position{10, 9},
+		Net:           Net{Number: 1, Name: "GND"},
+		Number:        "1",
+		Layers:        []string{"F.Cu"},
+		Reference:     "R1",
+		FootprintUUID: "f1",
+		Type:          "smd",
+		Shape:         "rect",
+		Size:          Size{Width: 1, Height: 1.2},
+		Rotation:      90,
+	})
+	board.AddSegment(Segment{Start: Position{10, 9}, End: Position{12, 9}, Width: 0.2, Layer: "F.Cu", Net: 1, UUID: "s1"})
+	board.AddVia(Via{Position: Position{12, 9}, Size: 0.6, Drill: 0.3, Layers: []string{"F.Cu", "B.Cu"}, Net: 1, UUID: "v1"})
+	board.Outline = append(board.Outline, OutlineShape{Kind: "rect", Points: []Position{{0, 0}, {20, 20}}, Width: 0.1})
+	return board
+}
+
+func TestBoardMarshalJSON(t *testing.T) {
+	// Act
+	data, err := json.Marshal(sampleBoard())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := `{"schema_version":1,"units":"mm",` +
+		`"nets":[{"number":0,"name":""},{"number":1,"name":"GND"}],` +
+		`"footprints":[{"reference":"R1","name":"R_0805","layer":"F.Cu","position":{"x":10,"y":10},"rotation":90,"uuid":"f1"}],` +
+		`"pads":[{"footprint":"R1","footprint_uuid":"f1","number":"1","net":1,"type":"smd","shape":"rect","position":{"x":10,"y":9},"width":1,"height":1.2,"rotation":90,"drill":0,"layers":["F.Cu"]}],` +
+		`"tracks":[{"start":{"x":10,"y":9},"end":{"x":12,"y":9},"width":0.2,"layer":"F.Cu","net":1,"uuid":"s1"}],` +
+		`"vias":[{"position":{"x":12,"y":9},"size":0.6,"drill":0.3,"layers":["F.Cu","B.Cu"],"net":1,"uuid":"v1"}],` +
+		`"outline":[{"kind":"rect","points":[{"x":0,"y":0},{"x":20,"y":20}],"width":0.1}]}`
+	if string(data) != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, data)
+	}
+}
+
+func TestBoardMarshalJSON_EmptyBoard(t *testing.T) {
+	// Act
+	data, err := json.Marshal(&Board{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := `{"schema_version":1,"units":"mm","nets":[],"footprints":[],"pads":[],"tracks":[],"vias":[],"outline":[]}`
+	if string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+}
+
+// schemaFields returns the property paths a JSON schema describes and the
+// ones it requires, e.g. "pads[].position.x".
+func schemaFields(schema, defs map[string]any, prefix string, fields map[string]bool) {
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		schemaFields(items, defs, prefix+"[]", fields)
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	for name, property := range properties {
+		fields[prefix+"."+name] = true
+		schemaFields(property.(map[string]any), defs, prefix+"."+name, fields)
+	}
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		if properties[name.(string)] == nil {
+			fields[prefix+"."+name.(string)+" (required, undescribed)"] = true
+		}
+	}
+}
+
+// jsonFields returns the object keys of a decoded JSON document by path.
+func jsonFields(value any, prefix string, fields map[string]bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		for name, child := range v {
+			fields[prefix+"."+name] = true
+			jsonFields(child, prefix+"."+name, fields)
+		}
+	case []any:
+		for _, child := range v {
+			jsonFields(child, prefix+"[]", fields)
+		}
+	}
+}
+
+func TestBoardMarshalJSON_MatchesSchema(t *testing.T) {
+	// Arrange
+	data, err := os.ReadFile("board.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("board.schema.json is not valid JSON: %v", err)
+	}
+	described := make(map[string]bool)
+	schemaFields(schema, schema["$defs"].(map[string]any), "", described)
+
+	// Act
+	data, err = json.Marshal(sampleBoard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded any
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		t.Fatal(err)
+	}
+	written := make(map[string]bool)
+	jsonFields(encoded, "", written)
+
+	// Assert
+	for _, fields := range []struct {
+		from, missing map[string]bool
+		message       string
+	}{
+		{written, described, "written but not in board.schema.json"},
+		{described, written, "in board.schema.json but not written"},
+	} {
+		names := []string{}
+		for name := range fields.from {
+			if !fields.missing[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			t.Errorf("Fields %s: %s", fields.message, strings.Join(names, ", "))
+		}
+	}
+	if version := schema["properties"].(map[string]any)["schema_version"].(map[string]any)["const"]; version != float64(BoardSchemaVersion) {
+		t.Errorf("Expected board.schema.json to describe version %d, got %v", BoardSchemaVersion, version)
+	}
+}
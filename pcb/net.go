@@ -1,6 +1,6 @@
 package pcb
 
 type Net struct {
-	Number int
-	Name   string
+	Number int    `sexpr:",arg"`
+	Name   string `sexpr:",arg,optional"`
 }
@@ -0,0 +1,200 @@
+package pcb
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// NetOrder selects the order in which sequential routers process nets.
+type NetOrder string
+
+const (
+	OrderNetNumber     NetOrder = "net"      // Ascending net number
+	OrderShortestFirst NetOrder = "shortest" // Smallest half-perimeter wire length first
+	OrderLongestFirst  NetOrder = "longest"  // Largest half-perimeter wire length first
+	OrderPinCount      NetOrder = "pins"     // Most pins first
+	OrderArea          NetOrder = "area"     // Smallest bounding box area first
+	OrderPowerFirst    NetOrder = "power"    // Power and ground nets first, then by net number
+)
+
+var netOrders = []NetOrder{OrderNetNumber, OrderShortestFirst, OrderLongestFirst, OrderPinCount, OrderArea, OrderPowerFirst}
+
+// NetOrdering combines an ordering strategy with an optional list of net
+// names that are always routed first, in the given order.
+type NetOrdering struct {
+	Strategy NetOrder
+	Priority []string
+}
+
+func ParseNetOrder(s string) (NetOrder, error) {
+	names := []string{}
+	for _, order := range netOrders {
+		if string(order) == s {
+			return order, nil
+		}
+		names = append(names, string(order))
+	}
+	return "", fmt.Errorf("unknown net order %q, expected one of %s", s, strings.Join(names, ", "))
+}
+
+type netStats struct {
+	net                    Net
+	pins                   int
+	minX, minY, maxX, maxY float64
+}
+
+func (s netStats) halfPerimeter() float64 {
+	return (s.maxX - s.minX) + (s.maxY - s.minY)
+}
+
+func (s netStats) area() float64 {
+	return (s.maxX - s.minX) * (s.maxY - s.minY)
+}
+
+// OrderNets returns every net with pads or vias except net 0, sorted by the
+// ordering. Ties are broken by net number so the result is deterministic.
+func OrderNets(board *Board, ordering NetOrdering) []Net {
+	statsByNet := make(map[int]*netStats)
+	add := func(net Net, p Position) {
+		s, ok := statsByNet[net.Number]
+		if !ok {
+			s = &netStats{net: net, minX: math.Inf(1), minY: math.Inf(1), maxX: math.Inf(-1), maxY: math.Inf(-1)}
+			statsByNet[net.Number] = s
+		}
+		if s.net.Name == "" {
+			s.net.Name = net.Name
+		}
+		s.pins++
+		s.minX, s.minY = math.Min(s.minX, p.X), math.Min(s.minY, p.Y)
+		s.maxX, s.maxY = math.Max(s.maxX, p.X), math.Max(s.maxY, p.Y)
+	}
+	for _, pad := range board.Pads {
+		add(pad.Net, pad.Position)
+	}
+	for _, via := range board.Vias {
+		add(Net{Number: via.Net}, via.Position)
+	}
+
+	stats := []netStats{}
+	for number, s := range statsByNet {
+		if number != 0 {
+			stats = append(stats, *s)
+		}
+	}
+
+	var less func(a, b netStats) bool
+	switch ordering.Strategy {
+	case OrderNetNumber, "":
+		less = func(a, b netStats) bool { return false }
+	case OrderShortestFirst:
+		less = func(a, b netStats) bool { return a.halfPerimeter() < b.halfPerimeter() }
+	case OrderLongestFirst:
+		less = func(a, b netStats) bool { return a.halfPerimeter() > b.halfPerimeter() }
+	case OrderPinCount:
+		less = func(a, b netStats) bool { return a.pins > b.pins }
+	case OrderArea:
+		less = func(a, b netStats) bool { return a.area() < b.area() }
+	case OrderPowerFirst:
+		less = func(a, b netStats) bool { return IsPowerNet(a.net.Name) && !IsPowerNet(b.net.Name) }
+	default:
+		slog.Warn("Unknown net order, using net number", "order", ordering.Strategy)
+		less = func(a, b netStats) bool { return false }
+	}
+
+	priority := make(map[string]int)
+	for i, name := range ordering.Priority {
+		if _, ok := priority[name]; !ok {
+			priority[name] = i
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		pa, aListed := priority[a.net.Name]
+		pb, bListed := priority[b.net.Name]
+		switch {
+		case aListed && bListed:
+			if pa != pb {
+				return pa < pb
+			}
+		case aListed != bListed:
+			return aListed
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.net.Number < b.net.Number
+	})
+
+	nets := make([]Net, len(stats))
+	for i, s := range stats {
+		nets[i] = s.net
+	}
+	return nets
+}
+
+var powerNetNames = []string{"GND", "VCC", "VDD", "VSS", "VEE", "VBUS", "VBAT", "VIN", "PWR"}
+
+// powerNetPrefixes are put in front of power net names for variants, e.g.
+// AGND for analog ground or IOVDD for the I/O supply.
+var powerNetPrefixes = []string{"", "A", "D", "P", "S", "IO"}
+
+// IsPowerNet reports whether a net name looks like a supply or ground net,
+// e.g. GND, AGND, VCC, +3V3, -12V, VDD_1V8 or /USB/VBUS. Power names only
+// count as whole parts of the name between delimiters, at its end or at its
+// start followed by voltages, so DRIVIN and /PWR_LED_EN are not power nets.
+func IsPowerNet(name string) bool {
+	upper := strings.ToUpper(name[strings.LastIndex(name, "/")+1:])
+	if len(upper) > 1 && (upper[0] == '+' || upper[0] == '-') && isDigit(upper[1]) {
+		return true
+	}
+	parts := strings.FieldsFunc(upper, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.' || r == ' '
+	})
+	if len(parts) == 0 {
+		return false
+	}
+	if isPowerPart(parts[len(parts)-1]) {
+		return true
+	}
+	for _, part := range parts[1:] {
+		if !isVoltage(part) {
+			return false
+		}
+	}
+	return isPowerPart(parts[0]) || isVoltage(parts[0])
+}
+
+func isPowerPart(part string) bool {
+	for _, prefix := range powerNetPrefixes {
+		if rest, ok := strings.CutPrefix(part, prefix); ok && slices.Contains(powerNetNames, rest) {
+			return true
+		}
+	}
+	return false
+}
+
+// isVoltage reports whether part is a voltage such as 5V, 12V or 3V3.
+func isVoltage(part string) bool {
+	digits, decimals, ok := strings.Cut(part, "V")
+	if !ok || digits == "" {
+		return false
+	}
+	for _, ch := range []byte(digits + decimals) {
+		if !isDigit(ch) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
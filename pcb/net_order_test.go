@@ -0,0 +1,107 @@
+package pcb
+
+import (
+	"testing"
+)
+
+func netOrderTestBoard() *Board {
+	board := NewBoard()
+	// Net 1: 2 pins, 10 mm apart
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "SIG_LONG"}})
+	board.AddPad(Pad{Position: Position{10, 0}, Net: Net{Number: 1, Name: "SIG_LONG"}})
+	// Net 2: 3 pins in a 2x2 box
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 2, Name: "GND"}})
+	board.AddPad(Pad{Position: Position{2, 0}, Net: Net{Number: 2, Name: "GND"}})
+	board.AddPad(Pad{Position: Position{2, 2}, Net: Net{Number: 2, Name: "GND"}})
+	// Net 3: 2 pins, 1 mm apart
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 3, Name: "SIG_SHORT"}})
+	board.AddPad(Pad{Position: Position{1, 0}, Net: Net{Number: 3, Name: "SIG_SHORT"}})
+	// Net 0 is never routed
+	board.AddPad(Pad{Position: Position{5, 5}, Net: Net{Number: 0, Name: ""}})
+	return board
+}
+
+func netNumbers(nets []Net) []int {
+	numbers := []int{}
+	for _, net := range nets {
+		numbers = append(numbers, net.Number)
+	}
+	return numbers
+}
+
+func TestOrderNets(t *testing.T) {
+	tests := []struct {
+		name     string
+		ordering NetOrdering
+		expected []int
+	}{
+		{"default", NetOrdering{}, []int{1, 2, 3}},
+		{"net number", NetOrdering{Strategy: OrderNetNumber}, []int{1, 2, 3}},
+		{"shortest first", NetOrdering{Strategy: OrderShortestFirst}, []int{3, 2, 1}},
+		{"longest first", NetOrdering{Strategy: OrderLongestFirst}, []int{1, 2, 3}},
+		{"pin count", NetOrdering{Strategy: OrderPinCount}, []int{2, 1, 3}},
+		{"area", NetOrdering{Strategy: OrderArea}, []int{1, 3, 2}},
+		{"power first", NetOrdering{Strategy: OrderPowerFirst}, []int{2, 1, 3}},
+		{"priority list", NetOrdering{Strategy: OrderShortestFirst, Priority: []string{"SIG_LONG"}}, []int{1, 3, 2}},
+		{"priority list order", NetOrdering{Priority: []string{"SIG_SHORT", "GND"}}, []int{3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := netNumbers(OrderNets(netOrderTestBoard(), tt.ordering))
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, result)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Fatalf("Expected %v, got %v", tt.expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestParseNetOrder(t *testing.T) {
+	order, err := ParseNetOrder("shortest")
+	if err != nil || order != OrderShortestFirst {
+		t.Errorf("Expected %v, got %v (err %v)", OrderShortestFirst, order, err)
+	}
+
+	if _, err := ParseNetOrder("random"); err == nil {
+		t.Errorf("Expected error for unknown net order")
+	}
+}
+
+func TestIsPowerNet(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"GND", true},
+		{"AGND", true},
+		{"VCC", true},
+		{"+3V3", true},
+		{"-12V", true},
+		{"/VBUS", true},
+		{"/USB/VBUS", true},
+		{"IOVDD", true},
+		{"VDD_1V8", true},
+		{"USB_VBUS", true},
+		{"3V3", true},
+		{"DRIVIN", false},
+		{"/PWR_LED_EN", false},
+		{"VBAT_SENSE", false},
+		{"GNDA_SENSE", false},
+		{"SDA", false},
+		{"P0", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if IsPowerNet(tt.name) != tt.expected {
+				t.Errorf("Expected IsPowerNet(%q) = %v", tt.name, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package pcb
+
+// OutlineShape is a line, arc, rectangle, circle or polygon on the board's
+// Edge.Cuts layer. Points depend on Kind:
+//
+//	line    start, end
+//	arc     start, mid, end
+//	rect    two opposite corners
+//	circle  centre, a point on the circle
+//	poly    every corner, in order
+type OutlineShape struct {
+	Kind   string
+	Points []Position
+	Width  float64 // Stroke width
+}
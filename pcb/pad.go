@@ -1,12 +1,51 @@
 package pcb
 
+import "math"
+
 type Pad struct {
 	Position Position
 	Net      Net
 	Number   string
 	Layers   []string
+
+	// Reference is the reference designator of the pad's footprint, e.g. R1,
+	// and FootprintUUID its UUID, which tells footprints apart that share a
+	// reference such as REF**
+	Reference     string
+	FootprintUUID string
+
+	Type     string  // smd, thru_hole, np_thru_hole or connect
+	Shape    string  // rect, roundrect, circle, oval, trapezoid or custom
+	Size     Size    // Before rotation
+	Rotation float64 // Degrees counterclockwise, including the footprint's
+	Drill    float64 // Hole diameter, 0 for pads without a hole
+}
+
+// Size is the width and height of a pad.
+type Size struct {
+	Width  float64 `sexpr:",arg"`
+	Height float64 `sexpr:",arg"`
 }
 
 func (p Pad) Distance(other Pad) float64 {
 	return p.Position.Distance(other.Position)
 }
+
+// Copper approximates the pad by the stadium along its longer side, which is
+// exact for oval and round pads and leaves out the corners of rectangles.
+func (p Pad) Copper() Segment {
+	length, width := p.Size.Width, p.Size.Height
+	angle := p.Rotation
+	if width > length {
+		length, width = width, length
+		angle += 90
+	}
+	// Counterclockwise on screen, where y points down
+	radians := -angle * math.Pi / 180
+	dx, dy := (length-width)/2*math.Cos(radians), (length-width)/2*math.Sin(radians)
+	return Segment{
+		Start: Position{X: p.Position.X - dx, Y: p.Position.Y - dy},
+		End:   Position{X: p.Position.X + dx, Y: p.Position.Y + dy},
+		Width: width,
+	}
+}
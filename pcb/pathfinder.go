@@ -0,0 +1,674 @@
+package pcb
+
+import (
+	"container/heap"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+)
+
+const (
+	DefaultViaSize  = 0.6
+	DefaultViaDrill = 0.3
+)
+
+// PathFinderOptions configures RoutePathFinder.
+type PathFinderOptions struct {
+	GridPitch        float64  // Distance between grid cell centers in mm
+	Margin           float64  // Extra routing area around the terminals in mm
+	PadKeepout       float64  // Radius around a pad that other nets may not enter in mm
+	Clearance        float64  // Gap kept to the existing copper of other nets in mm
+	Layers           []string // Copper layers available for routing
+	MaxIterations    int      // Rip-up and reroute iterations before giving up
+	ViaCost          float64  // Cost of a layer change, in units of one grid step
+	HistoryIncrement float64  // History cost added per extra net on a shared cell
+	PresentFactor    float64  // Initial penalty per extra net on a cell
+	PresentGrowth    float64  // Multiplier applied to PresentFactor each iteration
+	TraceWidth       float64
+	ViaSize          float64
+	Workers          int          // Nets routed concurrently; the result does not depend on it
+	Ordering         NetOrdering  // Order in which nets are routed and legalised
+	Connected        map[int]bool // Nets whose copper is connected already, left as they are
+}
+
+func DefaultPathFinderOptions() PathFinderOptions {
+	return PathFinderOptions{
+		GridPitch:        0.5,
+		Margin:           5.0,
+		PadKeepout:       0.5,
+		Clearance:        DefaultClearance,
+		Layers:           []string{"F.Cu", "B.Cu"},
+		MaxIterations:    30,
+		ViaCost:          10,
+		HistoryIncrement: 1,
+		PresentFactor:    0.5,
+		PresentGrowth:    1.5,
+		TraceWidth:       DefaultTraceWidth,
+		ViaSize:          DefaultViaSize,
+	}
+}
+
+// RouteReport summarises a routing run.
+type RouteReport struct {
+	Iterations int
+	RoutedNets []Net
+	FailedNets []Net // Nets that could not be legalised and were left unrouted
+}
+
+type terminal struct {
+	position Position
+	layers   []string // Of the pad or via
+	cells    []int
+}
+
+type netRoute struct {
+	net       Net
+	terminals []terminal
+	paths     [][]int
+	cells     map[int]bool
+	blocked   bool // No path exists even when sharing is allowed
+}
+
+type routingGrid struct {
+	origin    Position
+	pitch     float64
+	nx, ny    int
+	layers    []string
+	owner     []int // Net whose copper or keepout covers the cell, 0 when free and -1 when no net may use it
+	viaOwner  []int // Like owner, for the vias placed where a path changes layer
+	occupancy []int // Number of nets currently using the cell
+	history   []float64
+	viaReach  float64 // Distance from a via within which cells are too close for other nets
+}
+
+// RoutePathFinder connects the pads and vias of every net on a grid using
+// negotiated congestion: nets may temporarily share cells, shared cells get
+// more expensive each iteration, and congested nets are ripped up and rerouted
+// until no sharing remains or MaxIterations is reached. Nets that still overlap
+// at the end are reported as failed and not added to the board. The pads,
+// tracks and vias already on the board are kept clear of, and nets in
+// opts.Connected are not routed again.
+func RoutePathFinder(board *Board, opts PathFinderOptions) RouteReport {
+	report := RouteReport{}
+	routes := collectNetRoutes(board, opts.Ordering, opts.Connected)
+	if len(routes) == 0 {
+		return report
+	}
+
+	g := newRoutingGrid(board, opts)
+	g.markCopper(board, opts)
+	for _, r := range routes {
+		for i := range r.terminals {
+			r.terminals[i].cells = g.terminalCells(r.terminals[i].position, r.terminals[i].layers)
+		}
+	}
+
+	slog.Debug("PathFinder starting", "nets", len(routes), "grid_x", g.nx, "grid_y", g.ny, "layers", len(g.layers))
+
+	// Nets whose terminals are more than twice the margin apart are routed
+	// concurrently, against the grid as it was before any of them, so that
+	// the result is the same for any number of workers. Their paths can still
+	// leave the margin, so a net that ran into an earlier net of its batch is
+	// routed again after it, as if the nets had been routed one at a time.
+	byNumber := make(map[int]*netRoute, len(routes))
+	nets := make([]Net, len(routes))
+	for i, r := range routes {
+		byNumber[r.net.Number] = r
+		nets[i] = r.net
+	}
+	batches := ScheduleNets(board, nets, opts.Margin)
+
+	presentFactor := opts.PresentFactor
+	for iteration := 1; iteration <= opts.MaxIterations; iteration++ {
+		report.Iterations = iteration
+		for _, batch := range batches {
+			var pending []*netRoute
+			for _, net := range batch {
+				r := byNumber[net.Number]
+				if r.blocked || (iteration > 1 && !g.isCongested(r)) {
+					continue
+				}
+				g.ripUp(r)
+				pending = append(pending, r)
+			}
+			found := make([]bool, len(pending))
+			runConcurrently(len(pending), opts.Workers, func(i int) {
+				found[i] = g.routeNet(pending[i], presentFactor, opts.ViaCost)
+			})
+			batchCells := make(map[int]bool)
+			for i, r := range pending {
+				if found[i] && sharesCell(r.cells, batchCells) {
+					slog.Debug("Net crossed its batch, routing it again", "net", r.net.Number)
+					r.cells = make(map[int]bool)
+					r.paths = nil
+					found[i] = g.routeNet(r, presentFactor, opts.ViaCost)
+				}
+				if !found[i] {
+					slog.Debug("Net has no path", "net", r.net.Number)
+					r.blocked = true
+					continue
+				}
+				g.commit(r)
+				maps.Copy(batchCells, r.cells)
+			}
+		}
+
+		shared := g.updateHistory(opts.HistoryIncrement)
+		slog.Debug("PathFinder iteration", "iteration", iteration, "shared_cells", shared)
+		if shared == 0 {
+			break
+		}
+		presentFactor *= opts.PresentGrowth
+	}
+
+	// Keep nets greedily in routing order; a net that still overlaps an
+	// already kept net could not be legalised.
+	used := make(map[int]bool)
+	for _, r := range routes {
+		legal := !r.blocked
+		for cell := range r.cells {
+			if used[cell] {
+				legal = false
+				break
+			}
+		}
+		if !legal {
+			report.FailedNets = append(report.FailedNets, r.net)
+			continue
+		}
+		for cell := range r.cells {
+			used[cell] = true
+		}
+		g.emit(board, r, opts.TraceWidth)
+		report.RoutedNets = append(report.RoutedNets, r.net)
+	}
+	return report
+}
+
+// sharesCell reports whether any of cells is in other.
+func sharesCell(cells, other map[int]bool) bool {
+	for cell := range cells {
+		if other[cell] {
+			return true
+		}
+	}
+	return false
+}
+
+func collectNetRoutes(board *Board, ordering NetOrdering, connected map[int]bool) []*netRoute {
+	byNet := make(map[int]*netRoute)
+	for _, pad := range board.Pads {
+		if pad.Net.Number == 0 || connected[pad.Net.Number] {
+			continue
+		}
+		r, ok := byNet[pad.Net.Number]
+		if !ok {
+			r = &netRoute{net: pad.Net, cells: make(map[int]bool)}
+			byNet[pad.Net.Number] = r
+		}
+		r.terminals = append(r.terminals, terminal{position: pad.Position, layers: pad.Layers})
+	}
+	for _, via := range board.Vias {
+		if r, ok := byNet[via.Net]; ok {
+			r.terminals = append(r.terminals, terminal{position: via.Position, layers: via.Layers})
+		}
+	}
+
+	routes := []*netRoute{}
+	for _, net := range OrderNets(board, ordering) {
+		if r, ok := byNet[net.Number]; ok && len(r.terminals) > 1 {
+			routes = append(routes, r)
+		}
+	}
+	return routes
+}
+
+func newRoutingGrid(board *Board, opts PathFinderOptions) *routingGrid {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	extend := func(p Position) {
+		minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+		maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+	}
+	for _, pad := range board.Pads {
+		extend(pad.Position)
+	}
+	for _, via := range board.Vias {
+		extend(via.Position)
+	}
+	for _, seg := range board.Segments {
+		extend(seg.Start)
+		extend(seg.End)
+	}
+
+	g := &routingGrid{
+		origin: Position{X: minX - opts.Margin, Y: minY - opts.Margin},
+		pitch:  opts.GridPitch,
+		layers: opts.Layers,
+	}
+	g.viaReach = math.Hypot(opts.ViaSize/2+opts.Clearance+math.Max(opts.TraceWidth, opts.ViaSize)/2, opts.GridPitch/2)
+	g.nx = int(math.Ceil((maxX-minX+2*opts.Margin)/opts.GridPitch)) + 1
+	g.ny = int(math.Ceil((maxY-minY+2*opts.Margin)/opts.GridPitch)) + 1
+	size := g.nx * g.ny * len(g.layers)
+	g.owner = make([]int, size)
+	g.viaOwner = make([]int, size)
+	g.occupancy = make([]int, size)
+	g.history = make([]float64, size)
+	return g
+}
+
+func (g *routingGrid) index(x, y, layer int) int {
+	return (layer*g.ny+y)*g.nx + x
+}
+
+func (g *routingGrid) coords(cell int) (int, int, int) {
+	layerSize := g.nx * g.ny
+	layer := cell / layerSize
+	rest := cell % layerSize
+	return rest % g.nx, rest / g.nx, layer
+}
+
+func (g *routingGrid) cellOf(p Position) (int, int) {
+	x := int(math.Round((p.X - g.origin.X) / g.pitch))
+	y := int(math.Round((p.Y - g.origin.Y) / g.pitch))
+	return min(max(x, 0), g.nx-1), min(max(y, 0), g.ny-1)
+}
+
+func (g *routingGrid) center(x, y int) Position {
+	return Position{X: g.origin.X + float64(x)*g.pitch, Y: g.origin.Y + float64(y)*g.pitch}
+}
+
+func (g *routingGrid) layerIndex(layer string) int {
+	for i, l := range g.layers {
+		if l == layer {
+			return i
+		}
+	}
+	return -1
+}
+
+func (g *routingGrid) terminalCells(p Position, layers []string) []int {
+	x, y := g.cellOf(p)
+	var cells []int
+	for _, l := range g.layerIndices(layers) {
+		cells = append(cells, g.index(x, y, l))
+	}
+	return cells
+}
+
+// layerIndices returns the grid layers among layers, all of them for *.Cu.
+func (g *routingGrid) layerIndices(layers []string) []int {
+	var indices []int
+	for _, layer := range layers {
+		if layer == "*.Cu" {
+			for l := range g.layers {
+				indices = append(indices, l)
+			}
+			continue
+		}
+		if l := g.layerIndex(layer); l >= 0 {
+			indices = append(indices, l)
+		}
+	}
+	return indices
+}
+
+// markCopper reserves the cells around the pads, tracks and vias on the
+// board for their nets, far enough out that a track, or a via where a path
+// changes layer, kept to the other cells stays opts.Clearance away. Cells
+// near copper of two nets, or of pads without a net, are left to none.
+func (g *routingGrid) markCopper(board *Board, opts PathFinderOptions) {
+	// A track between the centres of two free cells passes closer to the
+	// copper than the centres do, by up to half a pitch to the side
+	reach := func(halfWidth, routeWidth float64) float64 {
+		return math.Hypot(halfWidth+opts.Clearance+routeWidth/2, g.pitch/2)
+	}
+	mark := func(shape Segment, layers []int, netNum int) {
+		if netNum == 0 {
+			netNum = -1
+		}
+		g.reserve(g.owner, shape, layers, netNum, reach(shape.Width/2, opts.TraceWidth))
+		g.reserve(g.viaOwner, shape, layers, netNum, reach(shape.Width/2, opts.ViaSize))
+	}
+
+	for _, pad := range board.Pads {
+		layers := g.layerIndices(pad.Layers)
+		mark(pad.Copper(), layers, pad.Net.Number)
+		keepout := Segment{Start: pad.Position, End: pad.Position}
+		g.reserve(g.owner, keepout, layers, pad.Net.Number, opts.PadKeepout)
+	}
+	for _, seg := range board.Segments {
+		mark(seg, g.layerIndices([]string{seg.Layer}), seg.Net)
+	}
+	for _, via := range board.Vias {
+		mark(Segment{Start: via.Position, End: via.Position, Width: via.Size}, g.viaLayers(via), via.Net)
+	}
+
+	// A pad must stay reachable by its own net even inside another keepout
+	for _, pad := range board.Pads {
+		if pad.Net.Number == 0 {
+			continue
+		}
+		for _, cell := range g.terminalCells(pad.Position, pad.Layers) {
+			g.owner[cell] = pad.Net.Number
+		}
+	}
+}
+
+// reserve gives the cells on layers whose centres are within reach of the
+// centre line of shape to netNum, or to no net when another has them.
+func (g *routingGrid) reserve(owner []int, shape Segment, layers []int, netNum int, reach float64) {
+	minX, minY := g.cellOf(Position{X: math.Min(shape.Start.X, shape.End.X) - reach, Y: math.Min(shape.Start.Y, shape.End.Y) - reach})
+	maxX, maxY := g.cellOf(Position{X: math.Max(shape.Start.X, shape.End.X) + reach, Y: math.Max(shape.Start.Y, shape.End.Y) + reach})
+	cx, cy := g.cellOf(shape.Start)
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			if shape.DistanceToPoint(g.center(x, y)) > reach && (x != cx || y != cy) {
+				continue
+			}
+			for _, layer := range layers {
+				idx := g.index(x, y, layer)
+				if owner[idx] == 0 {
+					owner[idx] = netNum
+				} else if owner[idx] != netNum {
+					owner[idx] = -1
+				}
+			}
+		}
+	}
+}
+
+// viaLayers returns the grid layers a via spans.
+func (g *routingGrid) viaLayers(via Via) []int {
+	indices := g.layerIndices(via.Layers)
+	if len(indices) == 0 {
+		return nil
+	}
+	var layers []int
+	for l := slices.Min(indices); l <= slices.Max(indices); l++ {
+		layers = append(layers, l)
+	}
+	return layers
+}
+
+func (g *routingGrid) isCongested(r *netRoute) bool {
+	for cell := range r.cells {
+		if g.occupancy[cell] > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *routingGrid) ripUp(r *netRoute) {
+	for cell := range r.cells {
+		g.occupancy[cell]--
+	}
+	r.cells = make(map[int]bool)
+	r.paths = nil
+}
+
+func (g *routingGrid) commit(r *netRoute) {
+	for cell := range r.cells {
+		g.occupancy[cell]++
+	}
+}
+
+// updateHistory raises the history cost of every shared cell and returns how
+// many cells are shared.
+func (g *routingGrid) updateHistory(increment float64) int {
+	shared := 0
+	for cell, occ := range g.occupancy {
+		if occ > 1 {
+			g.history[cell] += increment * float64(occ-1)
+			shared++
+		}
+	}
+	return shared
+}
+
+func (g *routingGrid) cellCost(cell, netNum int, presentFactor float64) float64 {
+	if owner := g.owner[cell]; owner != 0 && owner != netNum {
+		return math.Inf(1)
+	}
+	return (1 + g.history[cell]) * (1 + presentFactor*float64(g.occupancy[cell]))
+}
+
+// viaAllowed reports whether a net may place a via on the cell.
+func (g *routingGrid) viaAllowed(cell, netNum int) bool {
+	owner := g.viaOwner[cell]
+	return owner == 0 || owner == netNum
+}
+
+// routeNet connects the terminals of a net one at a time, each time searching
+// from everything already connected to the nearest remaining terminal.
+func (g *routingGrid) routeNet(r *netRoute, presentFactor, viaCost float64) bool {
+	tree := make(map[int]bool)
+	for _, cell := range r.terminals[0].cells {
+		tree[cell] = true
+	}
+	remaining := append([]terminal{}, r.terminals[1:]...)
+	sort.SliceStable(remaining, func(i, j int) bool {
+		return remaining[i].position.Distance(r.terminals[0].position) < remaining[j].position.Distance(r.terminals[0].position)
+	})
+
+	for _, t := range remaining {
+		if len(t.cells) == 0 || len(tree) == 0 {
+			return false
+		}
+		path := g.search(tree, t.cells, r.net.Number, presentFactor, viaCost)
+		if path == nil {
+			return false
+		}
+		for i, cell := range path {
+			tree[cell] = true
+			r.cells[cell] = true
+			// The via where the path changes layer also takes the cells
+			// around it
+			if _, _, layer := g.coords(cell); i > 0 && layer != g.layerOf(path[i-1]) {
+				g.addViaCells(r, cell)
+			}
+		}
+		r.paths = append(r.paths, path)
+	}
+	return true
+}
+
+func (g *routingGrid) layerOf(cell int) int {
+	_, _, layer := g.coords(cell)
+	return layer
+}
+
+// addViaCells adds the cells within viaReach of a via at cell to r.
+func (g *routingGrid) addViaCells(r *netRoute, cell int) {
+	cx, cy, _ := g.coords(cell)
+	reach := int(g.viaReach / g.pitch)
+	for y := max(cy-reach, 0); y <= min(cy+reach, g.ny-1); y++ {
+		for x := max(cx-reach, 0); x <= min(cx+reach, g.nx-1); x++ {
+			if g.center(x, y).Distance(g.center(cx, cy)) > g.viaReach {
+				continue
+			}
+			for l := range g.layers {
+				r.cells[g.index(x, y, l)] = true
+			}
+		}
+	}
+}
+
+type searchItem struct {
+	cell     int
+	priority float64
+}
+
+type searchQueue []searchItem
+
+func (q searchQueue) Len() int { return len(q) }
+func (q searchQueue) Less(i, j int) bool {
+	// Ties are broken by cell so that the same path is found every run
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return q[i].cell < q[j].cell
+}
+func (q searchQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *searchQueue) Push(x interface{}) { *q = append(*q, x.(searchItem)) }
+func (q *searchQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// search runs A* from all source cells to the nearest target cell and returns
+// the path from a source to a target, or nil if none exists.
+func (g *routingGrid) search(sources map[int]bool, targets []int, netNum int, presentFactor, viaCost float64) []int {
+	targetSet := make(map[int]bool, len(targets))
+	tx, ty, _ := g.coords(targets[0])
+	for _, cell := range targets {
+		targetSet[cell] = true
+	}
+	heuristic := func(cell int) float64 {
+		x, y, _ := g.coords(cell)
+		return math.Abs(float64(x-tx)) + math.Abs(float64(y-ty))
+	}
+
+	cost := make(map[int]float64)
+	prev := make(map[int]int)
+	queue := &searchQueue{}
+	for _, cell := range slices.Sorted(maps.Keys(sources)) {
+		cost[cell] = 0
+		prev[cell] = -1
+		heap.Push(queue, searchItem{cell: cell, priority: heuristic(cell)})
+	}
+
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(searchItem)
+		current := item.cell
+		if targetSet[current] {
+			path := []int{}
+			for cell := current; cell != -1; cell = prev[cell] {
+				path = append([]int{cell}, path...)
+			}
+			return path
+		}
+		if item.priority > cost[current]+heuristic(current) {
+			continue // Stale queue entry
+		}
+
+		x, y, layer := g.coords(current)
+		for _, next := range g.neighbours(x, y, layer) {
+			stepCost := 1.0
+			if _, _, nextLayer := g.coords(next); nextLayer != layer {
+				if !g.viaAllowed(current, netNum) || !g.viaAllowed(next, netNum) {
+					continue
+				}
+				stepCost = viaCost
+			}
+			c := g.cellCost(next, netNum, presentFactor)
+			if math.IsInf(c, 1) {
+				continue
+			}
+			newCost := cost[current] + stepCost*c
+			if old, seen := cost[next]; seen && old <= newCost {
+				continue
+			}
+			cost[next] = newCost
+			prev[next] = current
+			heap.Push(queue, searchItem{cell: next, priority: newCost + heuristic(next)})
+		}
+	}
+	return nil
+}
+
+// neighbours returns the orthogonally adjacent cells on the same layer and the
+// cells above and below on the other layers.
+func (g *routingGrid) neighbours(x, y, layer int) []int {
+	var result []int
+	if x > 0 {
+		result = append(result, g.index(x-1, y, layer))
+	}
+	if x < g.nx-1 {
+		result = append(result, g.index(x+1, y, layer))
+	}
+	if y > 0 {
+		result = append(result, g.index(x, y-1, layer))
+	}
+	if y < g.ny-1 {
+		result = append(result, g.index(x, y+1, layer))
+	}
+	for l := range g.layers {
+		if l != layer {
+			result = append(result, g.index(x, y, l))
+		}
+	}
+	return result
+}
+
+// emit converts the grid paths of a net into segments and vias on the board.
+func (g *routingGrid) emit(board *Board, r *netRoute, width float64) {
+	// Pads and vias of the net at the same place, e.g. on both sides of the
+	// board, are different terminals that a via still has to join
+	terminalAt := make(map[int]int)
+	for i, t := range r.terminals {
+		for _, cell := range t.cells {
+			terminalAt[cell] = i
+		}
+	}
+
+	addSegment := func(start, end Position, layer int) {
+		if start.Distance(end) < 1e-9 {
+			return
+		}
+		board.AddSegment(Segment{
+			Start: start,
+			End:   end,
+			Width: width,
+			Layer: g.layers[layer],
+			Net:   r.net.Number,
+		})
+	}
+
+	stubbed := make(map[int]bool)
+	for _, path := range r.paths {
+		for _, end := range []int{path[0], path[len(path)-1]} {
+			if t, ok := terminalAt[end]; ok && !stubbed[end] {
+				x, y, layer := g.coords(end)
+				addSegment(r.terminals[t].position, g.center(x, y), layer)
+				stubbed[end] = true
+			}
+		}
+
+		runStart := path[0]
+		for i := 1; i < len(path); i++ {
+			sx, sy, sl := g.coords(runStart)
+			px, py, pl := g.coords(path[i-1])
+			cx, cy, cl := g.coords(path[i])
+			if cl != pl {
+				addSegment(g.center(sx, sy), g.center(px, py), sl)
+				from, fromTerminal := terminalAt[path[i-1]]
+				to, toTerminal := terminalAt[path[i]]
+				if !fromTerminal || !toTerminal || from != to {
+					board.AddVia(Via{
+						Position: g.center(cx, cy),
+						Layers:   []string{g.layers[min(pl, cl)], g.layers[max(pl, cl)]},
+						Net:      r.net.Number,
+						Size:     DefaultViaSize,
+						Drill:    DefaultViaDrill,
+					})
+				}
+				runStart = path[i]
+				continue
+			}
+			// Close the run when the direction changes
+			if (sx == px && px != cx) || (sy == py && py != cy) {
+				addSegment(g.center(sx, sy), g.center(px, py), sl)
+				runStart = path[i-1]
+			}
+		}
+		ex, ey, el := g.coords(path[len(path)-1])
+		sx, sy, _ := g.coords(runStart)
+		addSegment(g.center(sx, sy), g.center(ex, ey), el)
+	}
+}
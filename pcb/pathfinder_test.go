@@ -0,0 +1,287 @@
+package pcb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func pathFinderTestOptions() PathFinderOptions {
+	opts := DefaultPathFinderOptions()
+	opts.GridPitch = 1.0
+	opts.PadKeepout = 0
+	return opts
+}
+
+func TestRoutePathFinder_TwoPadsConnected(t *testing.T) {
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "VCC"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{5, 3}, Net: Net{Number: 1, Name: "VCC"}, Layers: []string{"F.Cu"}})
+
+	report := RoutePathFinder(board, pathFinderTestOptions())
+
+	if len(report.FailedNets) != 0 {
+		t.Fatalf("Expected no failed nets, got %v", report.FailedNets)
+	}
+	if len(report.RoutedNets) != 1 {
+		t.Fatalf("Expected 1 routed net, got %d", len(report.RoutedNets))
+	}
+	length := 0.0
+	for _, seg := range board.Segments {
+		if seg.Net != 1 || seg.Layer != "F.Cu" {
+			t.Errorf("Unexpected segment %+v", seg)
+		}
+		length += seg.Length()
+	}
+	if length != 8 {
+		t.Errorf("Expected Manhattan wire length 8, got %f", length)
+	}
+	if len(board.Vias) != 0 {
+		t.Errorf("Expected no vias, got %d", len(board.Vias))
+	}
+}
+
+func TestRoutePathFinder_CrossingNetsUseBothLayers(t *testing.T) {
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 5}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"*.Cu"}})
+	board.AddPad(Pad{Position: Position{10, 5}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"*.Cu"}})
+	board.AddPad(Pad{Position: Position{5, 0}, Net: Net{Number: 2, Name: "B"}, Layers: []string{"*.Cu"}})
+	board.AddPad(Pad{Position: Position{5, 10}, Net: Net{Number: 2, Name: "B"}, Layers: []string{"*.Cu"}})
+	opts := pathFinderTestOptions()
+	opts.Margin = 0
+
+	report := RoutePathFinder(board, opts)
+
+	if len(report.FailedNets) != 0 {
+		t.Fatalf("Expected no failed nets, got %v", report.FailedNets)
+	}
+	layers := map[int]string{}
+	for _, seg := range board.Segments {
+		if layer, ok := layers[seg.Net]; ok && layer != seg.Layer {
+			t.Errorf("Expected net %d on a single layer", seg.Net)
+		}
+		layers[seg.Net] = seg.Layer
+	}
+	if layers[1] == layers[2] {
+		t.Errorf("Expected crossing nets on different layers, both on %s", layers[1])
+	}
+}
+
+func TestRoutePathFinder_NegotiatesAroundCongestion(t *testing.T) {
+	// Both nets want the cell at (2, 1) on the only layer. Net 2 can detour
+	// around the end of net 1, but only once that cell has become expensive.
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 1}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{4, 1}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{2, 0}, Net: Net{Number: 2, Name: "B"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{2, 2}, Net: Net{Number: 2, Name: "B"}, Layers: []string{"F.Cu"}})
+	opts := pathFinderTestOptions()
+	opts.Layers = []string{"F.Cu"}
+	opts.Margin = 1
+
+	report := RoutePathFinder(board, opts)
+
+	if len(report.FailedNets) != 0 {
+		t.Fatalf("Expected no failed nets, got %v", report.FailedNets)
+	}
+	if len(report.RoutedNets) != 2 {
+		t.Fatalf("Expected 2 routed nets, got %d", len(report.RoutedNets))
+	}
+	if report.Iterations < 2 {
+		t.Errorf("Expected rip-up and reroute iterations, got %d", report.Iterations)
+	}
+}
+
+func TestRoutePathFinder_ReportsNetsThatCannotBeLegalised(t *testing.T) {
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 5}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{10, 5}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{5, 0}, Net: Net{Number: 2, Name: "B"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{5, 10}, Net: Net{Number: 2, Name: "B"}, Layers: []string{"F.Cu"}})
+	opts := pathFinderTestOptions()
+	opts.Layers = []string{"F.Cu"}
+	opts.Margin = 0
+	opts.MaxIterations = 5
+
+	report := RoutePathFinder(board, opts)
+
+	if report.Iterations != 5 {
+		t.Errorf("Expected all 5 iterations to be used, got %d", report.Iterations)
+	}
+	if len(report.RoutedNets) != 1 || len(report.FailedNets) != 1 {
+		t.Fatalf("Expected 1 routed and 1 failed net, got %v and %v", report.RoutedNets, report.FailedNets)
+	}
+	for _, seg := range board.Segments {
+		if seg.Net == report.FailedNets[0].Number {
+			t.Errorf("Expected no segments for failed net %d", seg.Net)
+		}
+	}
+}
+
+func TestRoutePathFinder_LayerChangeAddsVia(t *testing.T) {
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "VCC"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{4, 0}, Net: Net{Number: 1, Name: "VCC"}, Layers: []string{"B.Cu"}})
+
+	report := RoutePathFinder(board, pathFinderTestOptions())
+
+	if len(report.FailedNets) != 0 {
+		t.Fatalf("Expected no failed nets, got %v", report.FailedNets)
+	}
+	if len(board.Vias) != 1 {
+		t.Fatalf("Expected 1 via, got %d", len(board.Vias))
+	}
+	if board.Vias[0].Net != 1 || len(board.Vias[0].Layers) != 2 {
+		t.Errorf("Unexpected via %+v", board.Vias[0])
+	}
+}
+
+func TestRoutePathFinder_EmptyBoard(t *testing.T) {
+	board := NewBoard()
+
+	report := RoutePathFinder(board, DefaultPathFinderOptions())
+
+	if len(board.Segments) != 0 || len(report.RoutedNets) != 0 || len(report.FailedNets) != 0 {
+		t.Errorf("Expected nothing routed on an empty board, got %+v", report)
+	}
+}
+
+func TestRoutePathFinder_KeepsClearOfExistingCopper(t *testing.T) {
+	// A track of net 2 and a pad without a net stand between the pads of net 1
+	wall := Segment{Start: Position{3, -3}, End: Position{3, 3}, Width: 0.2, Layer: "F.Cu", Net: 2}
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{6, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{3, 4}, Size: Size{1, 1}, Layers: []string{"F.Cu"}})
+	board.AddSegment(wall)
+	opts := pathFinderTestOptions()
+	opts.Layers = []string{"F.Cu"}
+
+	report := RoutePathFinder(board, opts)
+
+	if len(report.RoutedNets) != 1 {
+		t.Fatalf("Expected 1 routed net, got %v failed", report.FailedNets)
+	}
+	hole := Segment{Start: Position{3, 4}, End: Position{3, 4}, Width: 1}
+	for _, seg := range board.Segments[1:] {
+		for _, other := range []Segment{wall, hole} {
+			if gap := seg.DistanceTo(other) - (seg.Width+other.Width)/2; gap < opts.Clearance-1e-9 {
+				t.Errorf("Expected %+v at least %g from %+v, got %g", seg, opts.Clearance, other, gap)
+			}
+		}
+	}
+}
+
+func TestRoutePathFinder_GoesAroundPadsWithoutNet(t *testing.T) {
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{8, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	hole := Pad{Position: Position{4, 0}, Size: Size{1, 3}, Layers: []string{"F.Cu"}}
+	board.AddPad(hole)
+	opts := pathFinderTestOptions()
+	opts.Layers = []string{"F.Cu"}
+
+	report := RoutePathFinder(board, opts)
+
+	if len(report.RoutedNets) != 1 {
+		t.Fatalf("Expected 1 routed net, got %v failed", report.FailedNets)
+	}
+	copper := hole.Copper()
+	for _, seg := range board.Segments {
+		if gap := seg.DistanceTo(copper) - (seg.Width+copper.Width)/2; gap < opts.Clearance-1e-9 {
+			t.Errorf("Expected %+v at least %g from the pad, got %g", seg, opts.Clearance, gap)
+		}
+	}
+}
+
+func TestRoutePathFinder_SkipsConnectedNets(t *testing.T) {
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{4, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	opts := pathFinderTestOptions()
+	opts.Connected = map[int]bool{1: true}
+
+	report := RoutePathFinder(board, opts)
+
+	if len(board.Segments) != 0 || len(report.RoutedNets) != 0 {
+		t.Errorf("Expected the connected net to be left alone, got %d segments and %+v", len(board.Segments), report)
+	}
+}
+
+func TestRoutePathFinder_JoinsPadsOnBothSidesWithVia(t *testing.T) {
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"B.Cu"}})
+
+	report := RoutePathFinder(board, pathFinderTestOptions())
+
+	if len(report.RoutedNets) != 1 {
+		t.Fatalf("Expected 1 routed net, got %v failed", report.FailedNets)
+	}
+	if len(board.Vias) != 1 {
+		t.Errorf("Expected 1 via joining the sides, got %d", len(board.Vias))
+	}
+}
+
+func TestRoutePathFinder_SameForAnyNumberOfWorkers(t *testing.T) {
+	newBoard := func() *Board {
+		board := NewBoard()
+		board.UUIDSeed = "seed"
+		for net := 1; net <= 24; net++ {
+			x := float64(net%6) * 8
+			y := float64(net/6) * 8
+			board.AddPad(Pad{Position: Position{x, y}, Net: Net{Number: net}, Layers: []string{"F.Cu"}})
+			board.AddPad(Pad{Position: Position{x + 9, y + 3}, Net: Net{Number: net}, Layers: []string{"B.Cu"}})
+		}
+		return board
+	}
+	opts := pathFinderTestOptions()
+	opts.Margin = 2
+
+	sequential := newBoard()
+	opts.Workers = 1
+	want := RoutePathFinder(sequential, opts)
+	parallel := newBoard()
+	opts.Workers = 8
+	got := RoutePathFinder(parallel, opts)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected report %+v, got %+v", want, got)
+	}
+	if !reflect.DeepEqual(parallel.Segments, sequential.Segments) || !reflect.DeepEqual(parallel.Vias, sequential.Vias) {
+		t.Errorf("Expected the same tracks and vias with 8 workers as with 1")
+	}
+}
+
+func TestRoutePathFinder_BatchedNetsSeeEachOther(t *testing.T) {
+	// The pads of net 1 and net 2 are far enough apart to share a batch, but
+	// a pad without a net makes net 1 detour across the straight path of
+	// net 2. As when the nets are routed one at a time, net 2 has to see
+	// net 1 and step around it in the first iteration.
+	newBoard := func() *Board {
+		board := NewBoard()
+		board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+		board.AddPad(Pad{Position: Position{8, 0}, Net: Net{Number: 1, Name: "A"}, Layers: []string{"F.Cu"}})
+		board.AddPad(Pad{Position: Position{1, 4}, Net: Net{Number: 2, Name: "B"}, Layers: []string{"F.Cu"}})
+		board.AddPad(Pad{Position: Position{7, 4}, Net: Net{Number: 2, Name: "B"}, Layers: []string{"F.Cu"}})
+		board.AddPad(Pad{Position: Position{4, 0}, Size: Size{1, 6}, Layers: []string{"F.Cu"}})
+		return board
+	}
+	opts := pathFinderTestOptions()
+	opts.Layers = []string{"F.Cu"}
+	opts.Margin = 1
+	opts.PresentFactor = 10
+
+	for _, workers := range []int{1, 8} {
+		board := newBoard()
+		opts.Workers = workers
+		if batches := ScheduleNets(board, []Net{{Number: 1}, {Number: 2}}, opts.Margin); len(batches) != 1 {
+			t.Fatalf("Expected the nets in one batch, got %d", len(batches))
+		}
+
+		report := RoutePathFinder(board, opts)
+
+		if len(report.RoutedNets) != 2 || report.Iterations != 1 {
+			t.Errorf("Expected both nets routed in 1 iteration with %d workers, got %+v", workers, report)
+		}
+	}
+}
@@ -1,12 +1,17 @@
 package pcb
 
 import (
+	"fmt"
 	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mackeper/lin_router/utils"
 )
 
 type Position struct {
-	X float64
-	Y float64
+	X float64 `sexpr:",arg"`
+	Y float64 `sexpr:",arg"`
 }
 
 func (p Position) Distance(other Position) float64 {
@@ -16,16 +21,27 @@ func (p Position) Distance(other Position) float64 {
 }
 
 type Board struct {
-	Pads     []Pad
-	Segments []Segment
-	Vias     []Via
+	Nets       []Net
+	Footprints []Footprint
+	Pads       []Pad
+	Segments   []Segment
+	Vias       []Via
+	Outline    []OutlineShape
+
+	// UUIDSeed makes the UUIDs of added segments and vias a function of the
+	// seed, the item's net and its geometry. Random UUIDs are used when empty.
+	UUIDSeed string
+	uuidUses map[string]int
 }
 
 func NewBoard() *Board {
 	return &Board{
-		Pads:     []Pad{},
-		Segments: []Segment{},
-		Vias:     []Via{},
+		Nets:       []Net{},
+		Footprints: []Footprint{},
+		Pads:       []Pad{},
+		Segments:   []Segment{},
+		Vias:       []Via{},
+		Outline:    []OutlineShape{},
 	}
 }
 
@@ -33,14 +49,45 @@ func (b *Board) AddPad(pad Pad) {
 	b.Pads = append(b.Pads, pad)
 }
 
+// AddSegment adds a segment, assigning a UUID if it has none.
 func (b *Board) AddSegment(seg Segment) {
+	if seg.UUID == "" {
+		seg.UUID = b.newUUID(fmt.Sprintf("segment:%d:%s:%s:%s:%s:%s:%s",
+			seg.Net, seg.Layer, formatCoord(seg.Start.X), formatCoord(seg.Start.Y),
+			formatCoord(seg.End.X), formatCoord(seg.End.Y), formatCoord(seg.Width)))
+	}
 	b.Segments = append(b.Segments, seg)
 }
 
+// AddVia adds a via, assigning a UUID if it has none.
 func (b *Board) AddVia(via Via) {
+	if via.UUID == "" {
+		via.UUID = b.newUUID(fmt.Sprintf("via:%d:%s:%s:%s",
+			via.Net, strings.Join(via.Layers, ","), formatCoord(via.Position.X), formatCoord(via.Position.Y)))
+	}
 	b.Vias = append(b.Vias, via)
 }
 
+func (b *Board) newUUID(name string) string {
+	if b.UUIDSeed == "" {
+		return utils.GenerateUUID()
+	}
+	if b.uuidUses == nil {
+		b.uuidUses = make(map[string]int)
+	}
+	// Identical items would otherwise get identical UUIDs
+	uses := b.uuidUses[name]
+	b.uuidUses[name]++
+	if uses > 0 {
+		name = fmt.Sprintf("%s#%d", name, uses)
+	}
+	return utils.GenerateSeededUUID(b.UUIDSeed, name)
+}
+
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
 func (b *Board) GetPadsByNet(netNum int) []Pad {
 	var pads []Pad
 	for _, pad := range b.Pads {
@@ -134,3 +134,41 @@ func TestSegmentLength(t *testing.T) {
 		t.Errorf("Expected 5.0, got %f", length)
 	}
 }
+
+func TestBoardAddSegment_SeededUUIDs(t *testing.T) {
+	newBoard := func() *Board {
+		board := NewBoard()
+		board.UUIDSeed = "seed"
+		seg := Segment{Start: Position{0, 0}, End: Position{10, 10}, Width: 0.25, Layer: "F.Cu", Net: 1}
+		board.AddSegment(seg)
+		board.AddSegment(seg)
+		board.AddVia(Via{Position: Position{10, 10}, Layers: []string{"F.Cu", "B.Cu"}, Net: 1})
+		return board
+	}
+
+	board1 := newBoard()
+	board2 := newBoard()
+
+	if board1.Segments[0].UUID == "" || board1.Segments[0].UUID != board2.Segments[0].UUID {
+		t.Errorf("Expected equal seeded segment UUIDs, got %q and %q", board1.Segments[0].UUID, board2.Segments[0].UUID)
+	}
+	if board1.Segments[0].UUID == board1.Segments[1].UUID {
+		t.Errorf("Expected identical segments to get distinct UUIDs")
+	}
+	if board1.Vias[0].UUID != board2.Vias[0].UUID {
+		t.Errorf("Expected equal seeded via UUIDs, got %q and %q", board1.Vias[0].UUID, board2.Vias[0].UUID)
+	}
+}
+
+func TestBoardAddSegment_KeepsExistingUUID(t *testing.T) {
+	board := NewBoard()
+	board.AddSegment(Segment{UUID: "existing"})
+	board.AddSegment(Segment{})
+
+	if board.Segments[0].UUID != "existing" {
+		t.Errorf("Expected existing UUID to be kept, got %q", board.Segments[0].UUID)
+	}
+	if board.Segments[1].UUID == "" {
+		t.Errorf("Expected a generated UUID")
+	}
+}
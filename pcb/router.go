@@ -2,95 +2,108 @@ package pcb
 
 import (
 	"log/slog"
-
-	"github.com/mackeper/lin_router/utils"
 )
 
 const DefaultTraceWidth = 0.2
 
 func AddTrivialSegments(board *Board, maxRoutingDistance float64) {
-	netMap := make(map[int]bool)
-	for _, pad := range board.Pads {
-		netMap[pad.Net.Number] = true
-	}
-	for _, via := range board.Vias {
-		netMap[via.Net] = true
+	AddTrivialSegmentsOrdered(board, maxRoutingDistance, NetOrdering{Strategy: OrderNetNumber})
+}
+
+// AddTrivialSegmentsOrdered connects pads and vias of the same net that are
+// within maxRoutingDistance of each other, processing nets in the given order.
+func AddTrivialSegmentsOrdered(board *Board, maxRoutingDistance float64, ordering NetOrdering) {
+	for _, net := range OrderNets(board, ordering) {
+		result := trivialRouteNet(board, net.Number, maxRoutingDistance)
+		for _, seg := range result.Segments {
+			board.AddSegment(seg)
+		}
+		for _, via := range result.Vias {
+			board.AddVia(via)
+		}
 	}
+}
 
-	slog.Debug("Router starting", "total_pads", len(board.Pads), "total_vias", len(board.Vias), "nets", len(netMap))
+// AddTrivialSegmentsParallel is AddTrivialSegmentsOrdered with nets routed on
+// the given number of workers. Nets whose pads are within clearance of each
+// other are not routed at the same time. The result does not depend on
+// workers.
+func AddTrivialSegmentsParallel(board *Board, maxRoutingDistance, clearance float64, ordering NetOrdering, workers int) {
+	nets := OrderNets(board, ordering)
 
-	for netNum := range netMap {
-		if netNum == 0 {
-			slog.Debug("Skipping net 0 (no net)")
-			continue
-		}
-		pads := board.GetPadsByNet(netNum)
-		vias := board.GetViasByNet(netNum)
-		slog.Debug("Processing net", "net", netNum, "pads", len(pads), "vias", len(vias))
+	slog.Debug("Router starting", "total_pads", len(board.Pads), "total_vias", len(board.Vias), "nets", len(nets))
+
+	RouteNetsParallel(board, nets, clearance, workers, func(net Net) NetResult {
+		return trivialRouteNet(board, net.Number, maxRoutingDistance)
+	})
+}
 
-		// Pad to Pad
-		for i := range pads {
-			for j := i + 1; j < len(pads); j++ {
-				dist := pads[i].Distance(pads[j])
-				if dist <= maxRoutingDistance {
-					sharedLayers := getSharedLayers(pads[i].Layers, pads[j].Layers)
-					slog.Debug("Found pad pair within distance", "net", netNum, "dist", dist, "shared_layers", len(sharedLayers), "pad1_layers", pads[i].Layers, "pad2_layers", pads[j].Layers)
-					for _, layer := range sharedLayers {
-						seg := Segment{
-							Start: pads[i].Position,
-							End:   pads[j].Position,
-							Width: DefaultTraceWidth,
-							Layer: layer,
-							Net:   netNum,
-							UUID:  utils.GenerateUUID(),
-						}
-						board.AddSegment(seg)
-						slog.Debug("Added segment", "net", netNum, "layer", layer)
+func trivialRouteNet(board *Board, netNum int, maxRoutingDistance float64) NetResult {
+	result := NetResult{}
+	pads := board.GetPadsByNet(netNum)
+	vias := board.GetViasByNet(netNum)
+	slog.Debug("Processing net", "net", netNum, "pads", len(pads), "vias", len(vias))
+
+	// Pad to Pad
+	for i := range pads {
+		for j := i + 1; j < len(pads); j++ {
+			dist := pads[i].Distance(pads[j])
+			if dist <= maxRoutingDistance {
+				sharedLayers := getSharedLayers(pads[i].Layers, pads[j].Layers)
+				slog.Debug("Found pad pair within distance", "net", netNum, "dist", dist, "shared_layers", len(sharedLayers), "pad1_layers", pads[i].Layers, "pad2_layers", pads[j].Layers)
+				for _, layer := range sharedLayers {
+					seg := Segment{
+						Start: pads[i].Position,
+						End:   pads[j].Position,
+						Width: DefaultTraceWidth,
+						Layer: layer,
+						Net:   netNum,
 					}
+					result.Segments = append(result.Segments, seg)
+					slog.Debug("Added segment", "net", netNum, "layer", layer)
 				}
 			}
 		}
+	}
 
-		// Pad to Via
-		for _, pad := range pads {
-			for _, via := range vias {
-				if pad.Position.Distance(via.Position) <= maxRoutingDistance {
-					sharedLayers := getSharedLayers(pad.Layers, via.Layers)
-					for _, layer := range sharedLayers {
-						seg := Segment{
-							Start: pad.Position,
-							End:   via.Position,
-							Width: DefaultTraceWidth,
-							Layer: layer,
-							Net:   netNum,
-							UUID:  utils.GenerateUUID(),
-						}
-						board.AddSegment(seg)
+	// Pad to Via
+	for _, pad := range pads {
+		for _, via := range vias {
+			if pad.Position.Distance(via.Position) <= maxRoutingDistance {
+				sharedLayers := getSharedLayers(pad.Layers, via.Layers)
+				for _, layer := range sharedLayers {
+					seg := Segment{
+						Start: pad.Position,
+						End:   via.Position,
+						Width: DefaultTraceWidth,
+						Layer: layer,
+						Net:   netNum,
 					}
+					result.Segments = append(result.Segments, seg)
 				}
 			}
 		}
+	}
 
-		// Via to Via
-		for i := range vias {
-			for j := i + 1; j < len(vias); j++ {
-				if vias[i].Distance(vias[j]) <= maxRoutingDistance {
-					sharedLayers := getSharedLayers(vias[i].Layers, vias[j].Layers)
-					for _, layer := range sharedLayers {
-						seg := Segment{
-							Start: vias[i].Position,
-							End:   vias[j].Position,
-							Width: DefaultTraceWidth,
-							Layer: layer,
-							Net:   netNum,
-							UUID:  utils.GenerateUUID(),
-						}
-						board.AddSegment(seg)
+	// Via to Via
+	for i := range vias {
+		for j := i + 1; j < len(vias); j++ {
+			if vias[i].Distance(vias[j]) <= maxRoutingDistance {
+				sharedLayers := getSharedLayers(vias[i].Layers, vias[j].Layers)
+				for _, layer := range sharedLayers {
+					seg := Segment{
+						Start: vias[i].Position,
+						End:   vias[j].Position,
+						Width: DefaultTraceWidth,
+						Layer: layer,
+						Net:   netNum,
 					}
+					result.Segments = append(result.Segments, seg)
 				}
 			}
 		}
 	}
+	return result
 }
 
 func getSharedLayers(layers1, layers2 []string) []string {
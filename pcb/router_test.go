@@ -261,3 +261,20 @@ func TestRouteBoard_VeryLargeMaxDistance(t *testing.T) {
 		t.Errorf("Expected 3 segments with very large max distance, got %d", len(board.Segments))
 	}
 }
+
+func TestRouteBoard_SegmentsFollowNetOrder(t *testing.T) {
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1, Name: "SIG"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{2, 0}, Net: Net{Number: 1, Name: "SIG"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{10, 10}, Net: Net{Number: 2, Name: "GND"}, Layers: []string{"F.Cu"}})
+	board.AddPad(Pad{Position: Position{11, 10}, Net: Net{Number: 2, Name: "GND"}, Layers: []string{"F.Cu"}})
+
+	AddTrivialSegmentsOrdered(board, 3.0, NetOrdering{Strategy: OrderPowerFirst})
+
+	if len(board.Segments) != 2 {
+		t.Fatalf("Expected 2 segments, got %d", len(board.Segments))
+	}
+	if board.Segments[0].Net != 2 || board.Segments[1].Net != 1 {
+		t.Errorf("Expected GND routed before SIG, got nets %d then %d", board.Segments[0].Net, board.Segments[1].Net)
+	}
+}
@@ -0,0 +1,127 @@
+package pcb
+
+import (
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+)
+
+const DefaultClearance = 0.2
+
+// NetResult holds the copper a router produced for a single net.
+type NetResult struct {
+	Segments []Segment
+	Vias     []Via
+}
+
+// NetRouteFunc routes a single net. It is called concurrently for nets in the
+// same batch, so it must only read the board and return new items instead of
+// adding them.
+type NetRouteFunc func(net Net) NetResult
+
+type bounds struct {
+	minX, minY, maxX, maxY float64
+}
+
+func (b bounds) overlaps(other bounds) bool {
+	return b.minX <= other.maxX && other.minX <= b.maxX &&
+		b.minY <= other.maxY && other.minY <= b.maxY
+}
+
+func netBounds(board *Board, netNum int, inflate float64) bounds {
+	b := bounds{minX: math.Inf(1), minY: math.Inf(1), maxX: math.Inf(-1), maxY: math.Inf(-1)}
+	extend := func(p Position) {
+		b.minX, b.minY = math.Min(b.minX, p.X-inflate), math.Min(b.minY, p.Y-inflate)
+		b.maxX, b.maxY = math.Max(b.maxX, p.X+inflate), math.Max(b.maxY, p.Y+inflate)
+	}
+	for _, pad := range board.GetPadsByNet(netNum) {
+		extend(pad.Position)
+	}
+	for _, via := range board.GetViasByNet(netNum) {
+		extend(via.Position)
+	}
+	return b
+}
+
+// ScheduleNets partitions nets into batches that can be routed concurrently.
+// Nets in the same batch have bounding boxes, inflated by clearance, that do
+// not overlap. A net is always scheduled after every earlier net it overlaps,
+// so running the batches in order sees the same board as routing the nets
+// sequentially.
+func ScheduleNets(board *Board, nets []Net, clearance float64) [][]Net {
+	netBoxes := make([]bounds, len(nets))
+	batchOf := make([]int, len(nets))
+	batches := [][]Net{}
+	for i, net := range nets {
+		netBoxes[i] = netBounds(board, net.Number, clearance)
+		batch := 0
+		for j := 0; j < i; j++ {
+			if batchOf[j] >= batch && netBoxes[i].overlaps(netBoxes[j]) {
+				batch = batchOf[j] + 1
+			}
+		}
+		batchOf[i] = batch
+		if batch == len(batches) {
+			batches = append(batches, []Net{})
+		}
+		batches[batch] = append(batches[batch], net)
+	}
+	return batches
+}
+
+// RouteNetsParallel routes nets batch by batch on a pool of workers. Results
+// are added to the board after each batch and finally put in the order of
+// nets, so the output is identical to routing the nets one at a time.
+func RouteNetsParallel(board *Board, nets []Net, clearance float64, workers int, route NetRouteFunc) {
+	workers = max(workers, 1)
+	batches := ScheduleNets(board, nets, clearance)
+	slog.Debug("Scheduled nets", "nets", len(nets), "batches", len(batches), "workers", workers)
+
+	firstSegment, firstVia := len(board.Segments), len(board.Vias)
+	for _, batch := range batches {
+		results := make([]NetResult, len(batch))
+		runConcurrently(len(batch), workers, func(i int) {
+			results[i] = route(batch[i])
+		})
+
+		for _, result := range results {
+			for _, seg := range result.Segments {
+				board.AddSegment(seg)
+			}
+			for _, via := range result.Vias {
+				board.AddVia(via)
+			}
+		}
+	}
+
+	rank := make(map[int]int, len(nets))
+	for i, net := range nets {
+		rank[net.Number] = i
+	}
+	newSegments := board.Segments[firstSegment:]
+	sort.SliceStable(newSegments, func(i, j int) bool { return rank[newSegments[i].Net] < rank[newSegments[j].Net] })
+	newVias := board.Vias[firstVia:]
+	sort.SliceStable(newVias, func(i, j int) bool { return rank[newVias[i].Net] < rank[newVias[j].Net] })
+}
+
+// runConcurrently calls job for 0 to n-1 on up to workers goroutines and
+// returns when all calls have.
+func runConcurrently(n, workers int, job func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				job(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,86 @@
+package pcb
+
+import (
+	"testing"
+)
+
+func TestScheduleNets_SeparatesOverlappingNets(t *testing.T) {
+	board := NewBoard()
+	// Nets 1 and 2 overlap, net 3 is far away
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1}})
+	board.AddPad(Pad{Position: Position{10, 0}, Net: Net{Number: 1}})
+	board.AddPad(Pad{Position: Position{5, -1}, Net: Net{Number: 2}})
+	board.AddPad(Pad{Position: Position{5, 1}, Net: Net{Number: 2}})
+	board.AddPad(Pad{Position: Position{100, 100}, Net: Net{Number: 3}})
+	board.AddPad(Pad{Position: Position{101, 100}, Net: Net{Number: 3}})
+	nets := []Net{{Number: 1}, {Number: 2}, {Number: 3}}
+
+	batches := ScheduleNets(board, nets, DefaultClearance)
+
+	if len(batches) != 2 {
+		t.Fatalf("Expected 2 batches, got %d", len(batches))
+	}
+	if len(batches[0]) != 2 || batches[0][0].Number != 1 || batches[0][1].Number != 3 {
+		t.Errorf("Expected nets 1 and 3 in the first batch, got %v", batches[0])
+	}
+	if len(batches[1]) != 1 || batches[1][0].Number != 2 {
+		t.Errorf("Expected net 2 in the second batch, got %v", batches[1])
+	}
+}
+
+func TestScheduleNets_ClearanceInflatesBoundingBoxes(t *testing.T) {
+	board := NewBoard()
+	board.AddPad(Pad{Position: Position{0, 0}, Net: Net{Number: 1}})
+	board.AddPad(Pad{Position: Position{1, 0}, Net: Net{Number: 1}})
+	board.AddPad(Pad{Position: Position{1.3, 0}, Net: Net{Number: 2}})
+	board.AddPad(Pad{Position: Position{2, 0}, Net: Net{Number: 2}})
+	nets := []Net{{Number: 1}, {Number: 2}}
+
+	if batches := ScheduleNets(board, nets, 0.1); len(batches) != 1 {
+		t.Errorf("Expected 1 batch with small clearance, got %d", len(batches))
+	}
+	if batches := ScheduleNets(board, nets, 0.2); len(batches) != 2 {
+		t.Errorf("Expected 2 batches with large clearance, got %d", len(batches))
+	}
+}
+
+func TestAddTrivialSegmentsParallel_MatchesSequential(t *testing.T) {
+	newBoard := func() *Board {
+		board := NewBoard()
+		board.UUIDSeed = "seed"
+		for net := 1; net <= 40; net++ {
+			x := float64(net%7) * 2
+			y := float64(net/7) * 2
+			board.AddPad(Pad{Position: Position{x, y}, Net: Net{Number: net}, Layers: []string{"F.Cu", "B.Cu"}})
+			board.AddPad(Pad{Position: Position{x + 1, y + 1}, Net: Net{Number: net}, Layers: []string{"F.Cu"}})
+			board.AddPad(Pad{Position: Position{x + 2, y}, Net: Net{Number: net}, Layers: []string{"F.Cu"}})
+		}
+		return board
+	}
+	ordering := NetOrdering{Strategy: OrderShortestFirst}
+
+	// Route one net at a time in net order, without the scheduler
+	sequential := newBoard()
+	for _, net := range OrderNets(sequential, ordering) {
+		for _, seg := range trivialRouteNet(sequential, net.Number, 3.0).Segments {
+			sequential.AddSegment(seg)
+		}
+	}
+	if len(sequential.Segments) == 0 {
+		t.Fatalf("Expected segments to be added")
+	}
+
+	for _, workers := range []int{1, 8} {
+		parallel := newBoard()
+		AddTrivialSegmentsParallel(parallel, 3.0, DefaultClearance, ordering, workers)
+
+		if len(parallel.Segments) != len(sequential.Segments) {
+			t.Fatalf("Expected %d segments with %d workers, got %d", len(sequential.Segments), workers, len(parallel.Segments))
+		}
+		for i := range sequential.Segments {
+			if parallel.Segments[i] != sequential.Segments[i] {
+				t.Fatalf("Segment %d differs with %d workers: %+v vs %+v", i, workers, parallel.Segments[i], sequential.Segments[i])
+			}
+		}
+	}
+}
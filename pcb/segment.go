@@ -1,12 +1,14 @@
 package pcb
 
+// Segment is a straight track, (segment (start x y) (end x y) ...) in a
+// board file. The UUID may be missing when read.
 type Segment struct {
-	Start Position
-	End   Position
-	Width float64
-	Layer string
-	Net   int
-	UUID  string
+	Start Position `sexpr:"start"`
+	End   Position `sexpr:"end"`
+	Width float64  `sexpr:"width"`
+	Layer string   `sexpr:"layer"`
+	Net   int      `sexpr:"net"`
+	UUID  string   `sexpr:"uuid,optional"`
 }
 
 func (s Segment) Length() float64 {
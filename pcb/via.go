@@ -1,10 +1,14 @@
 package pcb
 
+// Via is a plated hole between layers, (via (at x y) (size d) ...) in a
+// board file. Every field but the position may be missing when read.
 type Via struct {
-	Position Position
-	Layers   []string
-	Net      int
-	UUID     string
+	Position Position `sexpr:"at"`
+	Size     float64  `sexpr:"size,optional"`
+	Drill    float64  `sexpr:"drill,optional"`
+	Layers   []string `sexpr:"layers,optional"`
+	Net      int      `sexpr:"net,optional"`
+	UUID     string   `sexpr:"uuid,optional"`
 }
 
 func (v Via) Distance(other Via) float64 {
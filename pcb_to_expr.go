@@ -7,62 +7,28 @@ import (
 	"github.com/mackeper/lin_router/pcb"
 )
 
+// AddSegmentsToExpr appends the new segments and vias of board to expr,
+// written like the rest of the file for its version, see BoardVersion. Items
+// already in expr, by UUID or as written when they have none, are skipped.
 func AddSegmentsToExpr(board *pcb.Board, expr *lexer.Expr) (lexer.Expr, error) {
+	version := BoardVersion(*expr)
+	existingSegments := existingUUIDs(expr, lexer.ExprSegment)
 	segmentExprs := []lexer.Expr{}
 	for _, seg := range board.Segments {
+		if seg.UUID != "" && existingSegments[seg.UUID] {
+			continue
+		}
 		slog.Debug("Add segment",
 			"start_x", seg.Start.X, "start_y", seg.Start.Y,
 			"end_x", seg.End.X, "end_y", seg.End.Y,
 			"width", seg.Width, "layer", seg.Layer)
-		segExpr := lexer.Expr{
-			Type:       lexer.ExprSegment,
-			Identifier: "segment",
-			Values: []lexer.Value{
-				lexer.ExprValue{Value: lexer.Expr{
-					Type:       lexer.ExprStart,
-					Identifier: "start",
-					Values: []lexer.Value{
-						lexer.NumberValue{Value: seg.Start.X},
-						lexer.NumberValue{Value: seg.Start.Y},
-					},
-				}},
-				lexer.ExprValue{Value: lexer.Expr{
-					Type:       lexer.ExprEnd,
-					Identifier: "end",
-					Values: []lexer.Value{
-						lexer.NumberValue{Value: seg.End.X},
-						lexer.NumberValue{Value: seg.End.Y},
-					},
-				}},
-				lexer.ExprValue{Value: lexer.Expr{
-					Type:       lexer.ExprWidth,
-					Identifier: "width",
-					Values: []lexer.Value{
-						lexer.NumberValue{Value: seg.Width},
-					},
-				}},
-				lexer.ExprValue{Value: lexer.Expr{
-					Type:       lexer.ExprLayer,
-					Identifier: "layer",
-					Values: []lexer.Value{
-						lexer.StringValue{Value: seg.Layer},
-					},
-				}},
-				lexer.ExprValue{Value: lexer.Expr{
-					Type:       lexer.ExprNet,
-					Identifier: "net",
-					Values: []lexer.Value{
-						lexer.NumberValue{Value: float64(seg.Net)},
-					},
-				}},
-				lexer.ExprValue{Value: lexer.Expr{
-					Type:       lexer.ExprUUID,
-					Identifier: "uuid",
-					Values: []lexer.Value{
-						lexer.StringValue{Value: seg.UUID},
-					},
-				}},
-			},
+		segExpr, err := lexer.Marshal("segment", seg)
+		if err != nil {
+			return lexer.Expr{}, err
+		}
+		encodeItem(&segExpr, version)
+		if seg.UUID == "" && existingSegments[lexer.FormatInline(segExpr)] {
+			continue
 		}
 		segmentExprs = append(segmentExprs, segExpr)
 		slog.Debug("Created segment expression", "expr", segExpr.String())
@@ -71,7 +37,54 @@ func AddSegmentsToExpr(board *pcb.Board, expr *lexer.Expr) (lexer.Expr, error) {
 	for _, segExpr := range segmentExprs {
 		expr.Values = append(expr.Values, lexer.ExprValue{Value: segExpr})
 	}
-
 	slog.Debug("Added segments to expression", "count", len(segmentExprs))
+
+	existingVias := existingUUIDs(expr, lexer.ExprVia)
+	viaCount := 0
+	for _, via := range board.Vias {
+		if via.UUID != "" && existingVias[via.UUID] {
+			continue
+		}
+		viaExpr, err := viaToExpr(via)
+		if err != nil {
+			return lexer.Expr{}, err
+		}
+		encodeItem(&viaExpr, version)
+		if via.UUID == "" && existingVias[lexer.FormatInline(viaExpr)] {
+			continue
+		}
+		expr.Values = append(expr.Values, lexer.ExprValue{Value: viaExpr})
+		viaCount++
+	}
+	slog.Debug("Added vias to expression", "count", viaCount)
+
 	return *expr, nil
 }
+
+// existingUUIDs returns the UUIDs of the top-level items of type t in expr,
+// and the items without one on a single line.
+func existingUUIDs(expr *lexer.Expr, t lexer.ExprType) map[string]bool {
+	uuids := make(map[string]bool)
+	for _, val := range expr.Values {
+		v, ok := val.(lexer.ExprValue)
+		if !ok || v.Value.Type != t {
+			continue
+		}
+		if id := itemUUID(v.Value); id != "" {
+			uuids[id] = true
+		} else {
+			uuids[lexer.FormatInline(v.Value)] = true
+		}
+	}
+	return uuids
+}
+
+func viaToExpr(via pcb.Via) (lexer.Expr, error) {
+	if via.Size == 0 {
+		via.Size = pcb.DefaultViaSize
+	}
+	if via.Drill == 0 {
+		via.Drill = pcb.DefaultViaDrill
+	}
+	return lexer.Marshal("via", via)
+}
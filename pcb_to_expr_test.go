@@ -1,6 +1,7 @@
 package main
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/mackeper/lin_router/lexer"
@@ -100,6 +101,53 @@ func TestAddSegmentsToExpr_MultipleSegments(t *testing.T) {
 	}
 }
 
+func TestAddSegmentsToExpr_NewViasOnly(t *testing.T) {
+	// Arrange
+	expr := lexer.Expr{
+		Type: lexer.ExprUnknown,
+		Values: []lexer.Value{
+			lexer.ExprValue{Value: lexer.Expr{
+				Type:       lexer.ExprVia,
+				Identifier: "via",
+				Values: []lexer.Value{
+					lexer.ExprValue{Value: lexer.Expr{
+						Type:       lexer.ExprUUID,
+						Identifier: "uuid",
+						Values:     []lexer.Value{lexer.StringValue{Value: "existing"}},
+					}},
+				},
+			}},
+		},
+	}
+
+	board := pcb.NewBoard()
+	board.Vias = append(board.Vias,
+		pcb.Via{Position: pcb.Position{X: 1, Y: 2}, Layers: []string{"F.Cu", "B.Cu"}, Net: 3, UUID: "existing"},
+		pcb.Via{Position: pcb.Position{X: 4, Y: 5}, Layers: []string{"F.Cu", "B.Cu"}, Net: 3, UUID: "new"},
+	)
+
+	// Act
+	resultExpr, err := AddSegmentsToExpr(board, &expr)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(resultExpr.Values) != 2 {
+		t.Fatalf("Expected 2 vias in expression, got %d", len(resultExpr.Values))
+	}
+	via, err := parseViaExpr(resultExpr.Values[1].(lexer.ExprValue).Value)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if via.UUID != "new" || via.Position.X != 4 || via.Position.Y != 5 || via.Net != 3 {
+		t.Errorf("Unexpected via %+v", via)
+	}
+	if via.Size != pcb.DefaultViaSize || via.Drill != pcb.DefaultViaDrill {
+		t.Errorf("Expected default via size and drill, got %v and %v", via.Size, via.Drill)
+	}
+}
+
 func findExprValueByType(values []lexer.Value, exprType lexer.ExprType) (lexer.ExprValue, bool) {
 	for _, v := range values {
 		ev, ok := v.(lexer.ExprValue)
@@ -150,3 +198,71 @@ func validateSegmentExpr(t *testing.T, expected pcb.Segment, actual lexer.Expr)
 			width)
 	}
 }
+
+func TestSegmentAndViaRoundtripThroughExpr(t *testing.T) {
+	// Arrange
+	segment := pcb.Segment{
+		Start: pcb.Position{X: 1.25, Y: -2}, End: pcb.Position{X: 3, Y: 4.5},
+		Width: 0.25, Layer: "B.Cu", Net: 2, UUID: "seg-uuid",
+	}
+	via := pcb.Via{
+		Position: pcb.Position{X: 3, Y: 4.5}, Size: 0.8, Drill: 0.4,
+		Layers: []string{"F.Cu", "B.Cu"}, Net: 2, UUID: "via-uuid",
+	}
+	board := pcb.NewBoard()
+	board.Segments = append(board.Segments, segment)
+	board.Vias = append(board.Vias, via)
+	expr := lexer.Expr{Identifier: "kicad_pcb"}
+
+	// Act
+	result, err := AddSegmentsToExpr(board, &expr)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var gotSegment pcb.Segment
+	err = lexer.Unmarshal(result.Values[0].(lexer.ExprValue).Value, &gotSegment)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	gotVia, err := parseViaExpr(result.Values[1].(lexer.ExprValue).Value)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Assert
+	if !reflect.DeepEqual(gotSegment, segment) {
+		t.Errorf("Expected segment %+v, got %+v", segment, gotSegment)
+	}
+	if !reflect.DeepEqual(gotVia, via) {
+		t.Errorf("Expected via %+v, got %+v", via, gotVia)
+	}
+}
+
+func TestAddSegmentsToExpr_SkipsTracksReadFromFile(t *testing.T) {
+	// Arrange
+	expr := parseBoardString(t, `(kicad_pcb (version 20240108)
+	(net 0 "") (net 1 "GND")
+	(segment (start 0 0) (end 5 0) (width 0.2) (layer "F.Cu") (net 1) (uuid "s1"))
+	(segment (start 5 0) (end 5 5) (width 0.2) (layer "F.Cu") (net 1))
+	(via (at 5 5) (size 0.6) (drill 0.3) (layers "F.Cu" "B.Cu") (net 1))
+)`)
+	board, err := ExprToPCB(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.AddSegment(pcb.Segment{Start: pcb.Position{X: 5, Y: 5}, End: pcb.Position{X: 9, Y: 5}, Width: 0.2, Layer: "F.Cu", Net: 1})
+
+	// Act
+	result, err := AddSegmentsToExpr(board, &expr)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if segments := result.Children("segment"); len(segments) != 3 {
+		t.Errorf("Expected the 2 segments of the file and 1 new one, got %d", len(segments))
+	}
+	if vias := result.Children("via"); len(vias) != 1 {
+		t.Errorf("Expected the via of the file only, got %d", len(vias))
+	}
+}
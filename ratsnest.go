@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+// Connection is a connection still to be routed between two islands of a
+// net's copper, from a pad or via of one to the nearest of the other.
+type Connection struct {
+	Net    string    `json:"net"`
+	From   diffPoint `json:"from"`
+	To     diffPoint `json:"to"`
+	Length float64   `json:"length"`
+}
+
+func (c Connection) String() string {
+	return c.Net + " " + c.From.String() + " -> " + c.To.String() + " " + lexer.FormatNumber(c.Length) + " mm"
+}
+
+// Ratsnest returns, net by net, the connections of a minimum spanning tree
+// over the islands of each net's copper. Copper touching on a shared layer
+// is one island; islands of tracks alone are left out, as they have no pad
+// to connect.
+func Ratsnest(board *pcb.Board) []Connection {
+	byNet := ratsnestByNet(board)
+	nets := []int{}
+	for net := range byNet {
+		nets = append(nets, net)
+	}
+	sort.Ints(nets)
+
+	connections := []Connection{}
+	for _, net := range nets {
+		connections = append(connections, byNet[net]...)
+	}
+	return connections
+}
+
+// ratsnestByNet returns the connections of the nets that have any, by net
+// number.
+func ratsnestByNet(board *pcb.Board) map[int][]Connection {
+	names := netNames(board)
+	byNet := make(map[int][]copperItem)
+	for _, item := range boardCopper(board) {
+		if item.net != 0 {
+			byNet[item.net] = append(byNet[item.net], item)
+		}
+	}
+
+	connections := make(map[int][]Connection)
+	for net, items := range byNet {
+		for _, link := range netRatsnest(items) {
+			link.Net = names[net]
+			connections[net] = append(connections[net], link)
+		}
+	}
+	return connections
+}
+
+// netRatsnest joins the islands of one net with Prim's algorithm.
+func netRatsnest(items []copperItem) []Connection {
+	// Union-find over touching items
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i, a := range items {
+		for j := i + 1; j < len(items); j++ {
+			b := items[j]
+			if _, ok := a.sharedLayer(b); ok && a.shape.DistanceTo(b.shape) <= (a.shape.Width+b.shape.Width)/2+1e-6 {
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+
+	// The pads and vias of each island, in the order first seen
+	islands := [][]pcb.Position{}
+	islandOf := make(map[int]int)
+	for i, item := range items {
+		if item.kind == "segment" {
+			continue
+		}
+		root := find(i)
+		index, ok := islandOf[root]
+		if !ok {
+			index = len(islands)
+			islandOf[root] = index
+			islands = append(islands, nil)
+		}
+		centre := pcb.Position{X: (item.shape.Start.X + item.shape.End.X) / 2, Y: (item.shape.Start.Y + item.shape.End.Y) / 2}
+		islands[index] = append(islands[index], centre)
+	}
+	if len(islands) < 2 {
+		return nil
+	}
+
+	connected := make([]bool, len(islands))
+	connected[0] = true
+	connections := []Connection{}
+	for range islands[1:] {
+		best := Connection{Length: math.Inf(1)}
+		bestIsland := -1
+		for i, island := range islands {
+			if !connected[i] {
+				continue
+			}
+			for j, other := range islands {
+				if connected[j] {
+					continue
+				}
+				for _, from := range island {
+					for _, to := range other {
+						if d := from.Distance(to); d < best.Length {
+							best = Connection{From: diffPoint{X: from.X, Y: from.Y}, To: diffPoint{X: to.X, Y: to.Y}, Length: d}
+							bestIsland = j
+						}
+					}
+				}
+			}
+		}
+		connected[bestIsland] = true
+		best.Length = math.Round(best.Length*1e6) / 1e6
+		connections = append(connections, best)
+	}
+	return connections
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const ratsnestBase = `(kicad_pcb (version 20240108)
+	(net 0 "") (net 1 "GND") (net 2 "VCC")
+	(footprint "R_0805" (layer "F.Cu") (at 10 10)
+		(property "Reference" "R1" (at 0 -1.5) (layer "F.SilkS"))
+		(pad "1" smd rect (at -1 0) (size 1 1) (layers "F.Cu") (net 1 "GND"))
+		(pad "2" smd rect (at 1 0) (size 1 1) (layers "F.Cu") (net 2 "VCC")))
+	(footprint "R_0805" (layer "F.Cu") (at 10 20)
+		(property "Reference" "R2" (at 0 -1.5) (layer "F.SilkS"))
+		(pad "1" smd rect (at -1 0) (size 1 1) (layers "F.Cu") (net 1 "GND"))
+		(pad "2" smd rect (at 1 0) (size 1 1) (layers "F.Cu") (net 2 "VCC")))
+`
+
+func TestRatsnest(t *testing.T) {
+	vcc := Connection{Net: "VCC", From: diffPoint{X: 11, Y: 20}, To: diffPoint{X: 11, Y: 10}, Length: 10}
+	tests := []struct {
+		name     string
+		items    string // Appended to ratsnestBase
+		expected []Connection
+	}{
+		{
+			"unrouted",
+			"",
+			[]Connection{{Net: "GND", From: diffPoint{X: 9, Y: 20}, To: diffPoint{X: 9, Y: 10}, Length: 10}, vcc},
+		},
+		{
+			"routed net",
+			`(segment (start 9 10) (end 9 20) (width 0.2) (layer "F.Cu") (net 1))`,
+			[]Connection{vcc},
+		},
+		{
+			"track on another layer than the pads",
+			`(segment (start 9 10) (end 9 20) (width 0.2) (layer "B.Cu") (net 1))`,
+			[]Connection{{Net: "GND", From: diffPoint{X: 9, Y: 20}, To: diffPoint{X: 9, Y: 10}, Length: 10}, vcc},
+		},
+		{
+			"routed up to a via",
+			`(segment (start 9 10) (end 9 15) (width 0.2) (layer "F.Cu") (net 1))
+			(via (at 9 15) (size 0.6) (drill 0.3) (layers "F.Cu" "B.Cu") (net 1))`,
+			[]Connection{{Net: "GND", From: diffPoint{X: 9, Y: 20}, To: diffPoint{X: 9, Y: 15}, Length: 5}, vcc},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			board, err := ExprToPCB(parseBoardString(t, ratsnestBase+tt.items+")"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			connections := Ratsnest(board)
+
+			// Assert
+			if !reflect.DeepEqual(connections, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, connections)
+			}
+		})
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	// Arrange
+	board, err := ExprToPCB(parseBoardString(t, ratsnestBase+
+		`(segment (start 9 10) (end 9 20) (width 0.2) (layer "F.Cu") (net 1))
+		(via (at 9 15) (size 0.6) (drill 0.3) (layers "F.Cu" "B.Cu") (net 1)))`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	stats := ComputeStats(board)
+
+	// Assert
+	expected := BoardStats{
+		Nets:        2,
+		Footprints:  2,
+		Pads:        4,
+		Tracks:      1,
+		Vias:        1,
+		TrackLength: map[string]float64{"F.Cu": 10},
+		Unrouted:    1,
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
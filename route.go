@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+type RouteOptions struct {
+	Router      string // trivial or pathfinder
+	MaxDistance float64
+	Clearance   float64 // To copper of other nets in mm, kept by pathfinder and between nets routed concurrently
+	PathFinder  pcb.PathFinderOptions
+	Ordering    pcb.NetOrdering
+	Seed        string // Derive UUIDs from this seed instead of randomly when set
+	Workers     int    // Nets routed concurrently; output does not depend on it
+	Recover     bool   // Leave out board items that cannot be read, see ExprToPCBRecover
+}
+
+// RouteExpr routes the board described by expr and returns the tree with the
+// new segments and vias added. The report is nil for the trivial router.
+func RouteExpr(expr lexer.Expr, opts RouteOptions) (lexer.Expr, *pcb.RouteReport, error) {
+	slog.Debug("Converting expression to PCB structure")
+	board, err := exprToBoard(expr, opts.Recover)
+	if err != nil {
+		return lexer.Expr{}, nil, fmt.Errorf("failed to convert expression to PCB: %w", err)
+	}
+	board.UUIDSeed = opts.Seed
+
+	var report *pcb.RouteReport
+	switch opts.Router {
+	case "trivial":
+		slog.Debug("Adding trivial segments to PCB", "max_distance", opts.MaxDistance, "net_order", opts.Ordering.Strategy, "workers", opts.Workers)
+		pcb.AddTrivialSegmentsParallel(board, opts.MaxDistance, opts.Clearance, opts.Ordering, opts.Workers)
+	case "pathfinder":
+		pathFinderOpts := opts.PathFinder
+		pathFinderOpts.Ordering = opts.Ordering
+		pathFinderOpts.Clearance = opts.Clearance
+		pathFinderOpts.Connected = connectedNets(board)
+		pathFinderOpts.Workers = opts.Workers
+		slog.Debug("Routing PCB with PathFinder", "grid", pathFinderOpts.GridPitch, "max_iterations", pathFinderOpts.MaxIterations, "workers", pathFinderOpts.Workers)
+		r := pcb.RoutePathFinder(board, pathFinderOpts)
+		report = &r
+	default:
+		return lexer.Expr{}, nil, fmt.Errorf("unknown router %q", opts.Router)
+	}
+
+	slog.Debug("Converting PCB structure back to expression")
+	expr, err = AddSegmentsToExpr(board, &expr)
+	if err != nil {
+		return lexer.Expr{}, nil, fmt.Errorf("failed to convert PCB back to expression: %w", err)
+	}
+	return expr, report, nil
+}
+
+// exprToBoard converts expr with ExprToPCB, or with ExprToPCBRecover when
+// recoverItems is set, whose diagnostics are left to the caller to report.
+func exprToBoard(expr lexer.Expr, recoverItems bool) (*pcb.Board, error) {
+	if recoverItems {
+		board, _ := ExprToPCBRecover(expr)
+		return board, nil
+	}
+	return ExprToPCB(expr)
+}
+
+// connectedNets returns the nets of the board's pads that the ratsnest has
+// no connections left for.
+func connectedNets(board *pcb.Board) map[int]bool {
+	unrouted := ratsnestByNet(board)
+	connected := make(map[int]bool)
+	for _, pad := range board.Pads {
+		if _, ok := unrouted[pad.Net.Number]; !ok {
+			connected[pad.Net.Number] = true
+		}
+	}
+	return connected
+}
+
+// unknownNets returns the names that no net of the board has.
+func unknownNets(board *pcb.Board, names []string) []string {
+	known := make(map[string]bool)
+	for _, name := range netNames(board) {
+		known[name] = true
+	}
+	unknown := []string{}
+	for _, name := range names {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mackeper/lin_router/pcb"
+)
+
+var updateGolden = flag.Bool("update", false, "Update golden files in test_data/golden")
+
+func routeTestFile(t *testing.T, path string, opts RouteOptions) string {
+	expr, err := ParsePcbFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error parsing %s, got %v", path, err)
+	}
+	routed, _, err := RouteExpr(expr, opts)
+	if err != nil {
+		t.Fatalf("Expected no error routing %s, got %v", path, err)
+	}
+	return routed.String()
+}
+
+func TestRouteExpr_DeterministicGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		golden string
+		opts   RouteOptions
+	}{
+		{
+			"trivial router",
+			"test_data/small_real.kicad_pcb",
+			"test_data/golden/small_real_trivial.kicad_pcb",
+			RouteOptions{Router: "trivial", MaxDistance: 3.0, Clearance: pcb.DefaultClearance, Seed: "golden"},
+		},
+		{
+			"trivial router in parallel",
+			"test_data/small_real.kicad_pcb",
+			"test_data/golden/small_real_trivial.kicad_pcb",
+			RouteOptions{Router: "trivial", MaxDistance: 3.0, Clearance: pcb.DefaultClearance, Seed: "golden", Workers: 4},
+		},
+		{
+			"pathfinder router",
+			"test_data/small_real.kicad_pcb",
+			"test_data/golden/small_real_pathfinder.kicad_pcb",
+			RouteOptions{Router: "pathfinder", PathFinder: pcb.DefaultPathFinderOptions(), Clearance: pcb.DefaultClearance, Seed: "golden", Workers: 1},
+		},
+		{
+			"pathfinder router in parallel",
+			"test_data/small_real.kicad_pcb",
+			"test_data/golden/small_real_pathfinder.kicad_pcb",
+			RouteOptions{Router: "pathfinder", PathFinder: pcb.DefaultPathFinderOptions(), Clearance: pcb.DefaultClearance, Seed: "golden", Workers: 8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			first := routeTestFile(t, tt.input, tt.opts)
+			second := routeTestFile(t, tt.input, tt.opts)
+
+			// Assert
+			if first != second {
+				t.Fatalf("Expected byte-identical output across runs")
+			}
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(tt.golden), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(tt.golden, []byte(first), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(tt.golden)
+			if err != nil {
+				t.Fatalf("Expected golden file %s (run with -update to create it): %v", tt.golden, err)
+			}
+			if first != string(expected) {
+				t.Errorf("Output differs from golden file %s", tt.golden)
+			}
+		})
+	}
+}
+
+func TestRouteExpr_KeepsOriginalText(t *testing.T) {
+	// Arrange
+	path := "test_data/main.kicad_pcb"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := strings.TrimRight(string(data), "\r\n)")
+
+	// Act
+	result := routeTestFile(t, path, RouteOptions{Router: "trivial", MaxDistance: 3.0, Clearance: pcb.DefaultClearance, Seed: "golden"})
+
+	// Assert
+	if !strings.HasPrefix(result, original) {
+		t.Errorf("Expected the original board text to be kept unchanged")
+	}
+	if !strings.Contains(result[len(original):], "(segment") {
+		t.Errorf("Expected new segments after the original board text")
+	}
+}
+
+func TestRouteExpr_PathFinderLeavesRoutedNets(t *testing.T) {
+	// Arrange
+	path := "test_data/main.kicad_pcb"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	result := routeTestFile(t, path, RouteOptions{Router: "pathfinder", PathFinder: pcb.DefaultPathFinderOptions(), Clearance: pcb.DefaultClearance, Seed: "golden"})
+
+	// Assert
+	if result != string(data) {
+		t.Errorf("Expected the fully routed board to be left unchanged")
+	}
+}
+
+func TestUnknownNets(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		expected []string
+	}{
+		{"none", nil, []string{}},
+		{"known", []string{"GND", "VCC"}, []string{}},
+		{"unknown", []string{"GND", "VDD", "gnd"}, []string{"VDD", "gnd"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			board, err := ExprToPCB(parseBoardString(t, ratsnestBase+")"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			unknown := unknownNets(board, tt.names)
+
+			// Assert
+			if !reflect.DeepEqual(unknown, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, unknown)
+			}
+		})
+	}
+}
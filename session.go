@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/specctra"
+)
+
+// ParseSessionFile reads a Specctra session (.ses) file.
+func ParseSessionFile(path string) (lexer.Expr, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return lexer.Expr{}, err
+	}
+	defer file.Close()
+	return specctra.Parse(file)
+}
+
+// ImportSession adds the wires and vias of a Specctra session to the board
+// described by expr, leaving out those already on it, and returns the tree
+// with them written in along with how many were added.
+func ImportSession(expr, session lexer.Expr) (lexer.Expr, int, int, error) {
+	board, err := ExprToPCB(expr)
+	if err != nil {
+		return lexer.Expr{}, 0, 0, fmt.Errorf("failed to convert expression to PCB: %w", err)
+	}
+	routes, err := specctra.ReadSession(session, board)
+	if err != nil {
+		return lexer.Expr{}, 0, 0, err
+	}
+	segments, vias := routes.AddTo(board)
+	expr, err = AddSegmentsToExpr(board, &expr)
+	if err != nil {
+		return lexer.Expr{}, 0, 0, fmt.Errorf("failed to convert PCB back to expression: %w", err)
+	}
+	return expr, segments, vias, nil
+}
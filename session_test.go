@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+	"github.com/mackeper/lin_router/specctra"
+)
+
+// sessionFromDesign returns a session routing the wiring of a design, as an
+// autorouter that changed nothing would write it.
+func sessionFromDesign(t *testing.T, dsn lexer.Expr) string {
+	t.Helper()
+	wiring, err := dsn.Child("wiring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// In units of 0.1 µm, with names as the design writes them
+	scaled := func(values []lexer.Value) string {
+		words := []string{}
+		for _, val := range values {
+			if number, ok := val.(lexer.NumberValue); ok {
+				words = append(words, lexer.FormatNumber(number.Value*10))
+			} else {
+				words = append(words, val.String())
+			}
+		}
+		return strings.Join(words, " ")
+	}
+	nets := make(map[string][]string)
+	order := []string{}
+	for _, val := range wiring.Values {
+		item := val.(lexer.ExprValue).Value
+		net, err := item.Child("net")
+		if err != nil {
+			continue
+		}
+		netName := net.Values[0].String()
+		if _, ok := nets[netName]; !ok {
+			order = append(order, netName)
+		}
+		switch item.Identifier {
+		case "wire":
+			path, _ := item.Child("path")
+			nets[netName] = append(nets[netName], "(wire (path "+scaled(path.Values)+"))")
+		case "via":
+			nets[netName] = append(nets[netName], "(via "+item.Values[0].String()+" "+scaled(item.Values[1:3])+")")
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(session board.ses (base_design board.dsn)\n(routes (resolution um 10) (parser (string_quote \") (host_cad test))\n(network_out\n")
+	for _, netName := range order {
+		fmt.Fprintf(&sb, "(net %s\n%s)\n", netName, strings.Join(nets[netName], "\n"))
+	}
+	sb.WriteString(")))\n")
+	return sb.String()
+}
+
+// withoutRoutes returns a board file's tree without its tracks and vias.
+func withoutRoutes(expr lexer.Expr) lexer.Expr {
+	stripped := expr
+	stripped.Values = nil
+	for _, val := range expr.Values {
+		if item, ok := val.(lexer.ExprValue); ok && (item.Value.Type == lexer.ExprSegment || item.Value.Type == lexer.ExprVia) {
+			continue
+		}
+		stripped.Values = append(stripped.Values, val)
+	}
+	return lexer.Expr{Type: stripped.Type, Identifier: stripped.Identifier, Values: stripped.Values}
+}
+
+func TestImportSession_RoundTrip(t *testing.T) {
+	for _, name := range []string{"main_with_traces", "small_real", "legacy_v5", "fat_cruiser", "small"} {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			expr, err := ParsePcbFile("test_data/" + name + ".kicad_pcb")
+			if err != nil {
+				t.Fatal(err)
+			}
+			original, err := ExprToPCB(expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			design, err := specctra.Parse(strings.NewReader(lexer.Format(specctra.BoardToDSN(original, specctra.DefaultOptions()))))
+			if err != nil {
+				t.Fatalf("Expected the design to read back, got %v", err)
+			}
+			session, err := specctra.Parse(strings.NewReader(sessionFromDesign(t, design)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			routed, segments, vias, err := ImportSession(withoutRoutes(expr), session)
+			_, again, againVias, errAgain := ImportSession(expr, session)
+
+			// Assert
+			if err != nil || errAgain != nil {
+				t.Fatalf("Expected no errors, got %v and %v", err, errAgain)
+			}
+			if again != 0 || againVias != 0 {
+				t.Errorf("Expected nothing to be added to the routed board, got %d tracks and %d vias", again, againVias)
+			}
+			board, err := ExprToPCB(routed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Tracks shorter than the design's 0.1 µm resolution are lost
+			want, got := make(map[string]int), make(map[string]int)
+			for _, seg := range original.Segments {
+				if key := segmentKey(seg); key != "" {
+					want[key]++
+				}
+			}
+			for _, via := range original.Vias {
+				want[viaKey(via)]++
+			}
+			for _, seg := range board.Segments {
+				got[segmentKey(seg)]++
+			}
+			for _, via := range board.Vias {
+				got[viaKey(via)]++
+			}
+			if segments+vias != len(board.Segments)+len(board.Vias) {
+				t.Errorf("Expected %d tracks and vias to be reported added, got %d", len(board.Segments)+len(board.Vias), segments+vias)
+			}
+			for key, count := range want {
+				if got[key] != count {
+					t.Errorf("Expected %d of %s, got %d", count, key, got[key])
+				}
+			}
+			for key, count := range got {
+				if want[key] == 0 {
+					t.Errorf("Expected no %s, got %d", key, count)
+				}
+			}
+		})
+	}
+}
+
+// designPoint returns a position in the 0.1 µm units of a design, rounded
+// the way BoardToDSN rounds it.
+func designPoint(p pcb.Position) [2]float64 {
+	return [2]float64{math.Round(p.X * 1000 * 10), math.Round(-p.Y * 1000 * 10)}
+}
+
+// segmentKey describes a track as written to a design, or is empty when it
+// is too short to be written.
+func segmentKey(seg pcb.Segment) string {
+	start, end := designPoint(seg.Start), designPoint(seg.End)
+	if start == end {
+		return ""
+	}
+	return fmt.Sprintf("segment %v %v %g %s %d", start, end, seg.Width, seg.Layer, seg.Net)
+}
+
+func viaKey(via pcb.Via) string {
+	return fmt.Sprintf("via %v %g %g %v %d", designPoint(via.Position), via.Size, via.Drill, via.Layers, via.Net)
+}
@@ -0,0 +1,578 @@
+// Package specctra writes boards as Specctra designs (.dsn) and reads the
+// sessions (.ses) autorouters such as FreeRouting write back.
+//
+// Coordinates are written in micrometres with a resolution of 0.1 µm, the
+// way pcbnew exports them, with the y axis pointing up instead of down.
+package specctra
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+// resolution is the number of coordinate units per micrometre, written as
+// (resolution um 10).
+const resolution = 10
+
+// DefaultClass is the net class every net is written in.
+const DefaultClass = "kicad_default"
+
+// Options configures BoardToDSN.
+type Options struct {
+	Name       string  // Design name, usually the board file's
+	TraceWidth float64 // Track width of the net class in mm
+	Clearance  float64 // Clearance of the net class in mm
+	ViaSize    float64 // Diameter of the vias the router adds in mm
+	ViaDrill   float64
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Name:       "board",
+		TraceWidth: pcb.DefaultTraceWidth,
+		Clearance:  pcb.DefaultClearance,
+		ViaSize:    pcb.DefaultViaSize,
+		ViaDrill:   pcb.DefaultViaDrill,
+	}
+}
+
+// node builds a list from values: strings become names, float64s numbers in
+// micrometres, lexer.Exprs nested lists, and lexer.Values and
+// []lexer.Values are kept as they are.
+func node(identifier string, values ...any) lexer.Expr {
+	expr := lexer.Expr{Type: lexer.IdentifierToExprType(identifier), Identifier: identifier}
+	for _, val := range values {
+		switch v := val.(type) {
+		case string:
+			expr.Values = append(expr.Values, name(v))
+		case int:
+			expr.Values = append(expr.Values, lexer.NumberValue{Value: float64(v)})
+		case float64:
+			expr.Values = append(expr.Values, micrometres(v))
+		case lexer.Expr:
+			expr.Values = append(expr.Values, lexer.ExprValue{Value: v})
+		case lexer.Value:
+			expr.Values = append(expr.Values, v)
+		case []lexer.Value:
+			expr.Values = append(expr.Values, v...)
+		default:
+			panic(fmt.Sprintf("specctra: cannot write %T", val))
+		}
+	}
+	return expr
+}
+
+// micrometres converts mm to the written unit.
+func micrometres(mm float64) lexer.Value {
+	return lexer.NumberValue{Value: math.Round(mm*1000*resolution) / resolution}
+}
+
+// point converts a board position to written x and y.
+func point(p pcb.Position) []lexer.Value {
+	return []lexer.Value{micrometres(p.X), micrometres(-p.Y)}
+}
+
+// CopperLayers returns the copper layers a board uses, front to back. F.Cu
+// and B.Cu are always included.
+func CopperLayers(board *pcb.Board) []string {
+	seen := map[string]bool{"F.Cu": true, "B.Cu": true}
+	add := func(layers ...string) {
+		for _, layer := range layers {
+			if strings.HasPrefix(layer, "In") && strings.HasSuffix(layer, ".Cu") {
+				seen[layer] = true
+			}
+		}
+	}
+	for _, pad := range board.Pads {
+		add(pad.Layers...)
+	}
+	for _, seg := range board.Segments {
+		add(seg.Layer)
+	}
+	for _, via := range board.Vias {
+		add(via.Layers...)
+	}
+
+	inner := []string{}
+	for layer := range seen {
+		if layer != "F.Cu" && layer != "B.Cu" {
+			inner = append(inner, layer)
+		}
+	}
+	sort.Slice(inner, func(i, j int) bool { return innerIndex(inner[i]) < innerIndex(inner[j]) })
+	return append(append([]string{"F.Cu"}, inner...), "B.Cu")
+}
+
+// innerIndex returns N of InN.Cu.
+func innerIndex(layer string) int {
+	n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(layer, "In"), ".Cu"))
+	return n
+}
+
+// dsnWriter collects the padstacks and images of a design while its parts
+// are written.
+type dsnWriter struct {
+	board      *pcb.Board
+	opts       Options
+	layers     []string
+	padstacks  []lexer.Expr
+	padstackOf map[string]string // Shapes to padstack name
+	names      map[string]bool   // Padstack and image names in use
+	netNames   map[int]string
+	netPins    map[int][]string
+}
+
+// BoardToDSN returns a board as a Specctra design: its outline, copper
+// layers, footprints as images with a padstack per pad shape, nets with
+// their pins, one net class with the options' rules and the existing tracks
+// and vias as protected wiring.
+//
+// Components on the back are written as mirrored in x and then rotated,
+// with padstack layers flipped. Pads without copper are left out, as are
+// np_thru_hole pads, which a router only needs as keepouts.
+func BoardToDSN(board *pcb.Board, opts Options) lexer.Expr {
+	w := &dsnWriter{
+		board:      board,
+		opts:       opts,
+		layers:     CopperLayers(board),
+		padstackOf: make(map[string]string),
+		names:      make(map[string]bool),
+		netNames:   netNames(board),
+		netPins:    make(map[int][]string),
+	}
+
+	placement, images := w.components()
+	structure := w.structure()
+	wiring := w.wiring()
+	library := node("library")
+	for _, image := range images {
+		library.Values = append(library.Values, lexer.ExprValue{Value: image})
+	}
+	for _, padstack := range w.padstacks {
+		library.Values = append(library.Values, lexer.ExprValue{Value: padstack})
+	}
+
+	return node("pcb", opts.Name,
+		node("parser",
+			node("string_quote", lexer.IdentifierValue{Value: `"`}),
+			node("space_in_quoted_tokens", "on"),
+			node("host_cad", "lin_router"),
+		),
+		node("resolution", "um", resolution),
+		node("unit", "um"),
+		structure,
+		placement,
+		library,
+		w.network(),
+		wiring,
+	)
+}
+
+// netNames returns the name of every net but 0, with unnamed nets called
+// Net-N.
+func netNames(board *pcb.Board) map[int]string {
+	names := make(map[int]string)
+	for _, net := range board.Nets {
+		names[net.Number] = net.Name
+	}
+	for _, pad := range board.Pads {
+		if pad.Net.Name != "" || names[pad.Net.Number] == "" {
+			names[pad.Net.Number] = pad.Net.Name
+		}
+	}
+	delete(names, 0)
+	for number, name := range names {
+		if name == "" {
+			names[number] = fmt.Sprintf("Net-%d", number)
+		}
+	}
+	return names
+}
+
+func (w *dsnWriter) structure() lexer.Expr {
+	structure := node("structure")
+	for i, layer := range w.layers {
+		structure.Values = append(structure.Values, lexer.ExprValue{Value: node("layer", layer,
+			node("type", "signal"),
+			node("property", node("index", i)),
+		)})
+	}
+	outline := []lexer.Value{}
+	for _, p := range boundary(w.board) {
+		outline = append(outline, point(p)...)
+	}
+	structure.Values = append(structure.Values,
+		lexer.ExprValue{Value: node("boundary", node("path", "pcb", 0, outline))},
+		lexer.ExprValue{Value: node("via", w.viaPadstack(w.opts.ViaSize, w.opts.ViaDrill, w.layers))},
+		lexer.ExprValue{Value: node("rule", node("width", w.opts.TraceWidth), node("clearance", w.opts.Clearance))},
+	)
+	return structure
+}
+
+// component is a footprint with its pads.
+type component struct {
+	reference string // Unique among the components
+	footprint pcb.Footprint
+	pads      []pcb.Pad
+}
+
+// groupPads returns the footprints with their pads, which are matched by
+// footprint UUID, or else by reference. Pads without a footprint go to one
+// at the origin named after their reference, or PAD.
+func groupPads(board *pcb.Board) []*component {
+	components := []*component{}
+	byUUID := make(map[string]*component)
+	byReference := make(map[string]*component)
+	for _, footprint := range board.Footprints {
+		c := &component{reference: footprint.Reference, footprint: footprint}
+		components = append(components, c)
+		if footprint.UUID != "" {
+			byUUID[footprint.UUID] = c
+		}
+		if _, ok := byReference[footprint.Reference]; !ok {
+			byReference[footprint.Reference] = c
+		}
+	}
+	for _, pad := range board.Pads {
+		c, ok := byUUID[pad.FootprintUUID]
+		if !ok || pad.FootprintUUID == "" {
+			c, ok = byReference[pad.Reference]
+		}
+		if !ok {
+			reference := pad.Reference
+			if reference == "" {
+				reference = "PAD"
+			}
+			c = &component{reference: reference, footprint: pcb.Footprint{Reference: reference, Name: reference, Layer: "F.Cu"}}
+			components = append(components, c)
+			byReference[pad.Reference] = c
+		}
+		c.pads = append(c.pads, pad)
+	}
+
+	used := make(map[string]int)
+	for _, c := range components {
+		if used[c.reference]++; used[c.reference] > 1 {
+			c.reference = fmt.Sprintf("%s_%d", c.reference, used[c.reference])
+		}
+	}
+	return components
+}
+
+// components returns the placement and the images of the footprints, and
+// records the pins of each net.
+func (w *dsnWriter) components() (lexer.Expr, []lexer.Expr) {
+	images := []lexer.Expr{}
+	imageOf := make(map[string]string) // Footprint name and pins to image name
+	placed := make(map[string][]lexer.Value)
+	order := []string{}
+
+	for _, c := range groupPads(w.board) {
+		back := c.footprint.Layer == "B.Cu"
+		rotation := c.footprint.Rotation
+		pins := []lexer.Value{}
+		pinUses := make(map[string]int)
+		for _, pad := range c.pads {
+			layers := w.padLayers(pad, back)
+			if pad.Type == "np_thru_hole" || len(layers) == 0 {
+				continue
+			}
+			pinName := pad.Number
+			if pinName == "" {
+				pinName = "~"
+			}
+			uses := pinUses[pinName]
+			pinUses[pinName]++
+			if uses > 0 {
+				pinName = fmt.Sprintf("%s@%d", pinName, uses)
+			}
+
+			// Undo the footprint's placement: move, rotate, and mirror on the back
+			dx, dy := pad.Position.X-c.footprint.Position.X, -(pad.Position.Y - c.footprint.Position.Y)
+			x, y := rotate(dx, dy, -rotation)
+			angle := pad.Rotation - rotation
+			if back {
+				x, angle = -x, -angle
+			}
+			pin := node("pin", w.padPadstack(pad, normalizeAngle(angle), layers), pinName, x, y)
+			pins = append(pins, lexer.ExprValue{Value: pin})
+
+			if pad.Net.Number != 0 {
+				w.netPins[pad.Net.Number] = append(w.netPins[pad.Net.Number], c.reference+"-"+pinName)
+			}
+		}
+
+		var key strings.Builder
+		key.WriteString(c.footprint.Name)
+		for _, pin := range pins {
+			key.WriteString(" " + pin.String())
+		}
+		imageName, ok := imageOf[key.String()]
+		if !ok {
+			imageName = w.uniqueName(c.footprint.Name, "::")
+			imageOf[key.String()] = imageName
+			images = append(images, node("image", imageName, pins))
+			order = append(order, imageName)
+		}
+
+		side := "front"
+		if back {
+			side = "back"
+		}
+		place := node("place", c.reference, point(c.footprint.Position), side, lexer.NumberValue{Value: normalizeAngle(rotation)})
+		placed[imageName] = append(placed[imageName], lexer.ExprValue{Value: place})
+	}
+
+	placement := node("placement")
+	for _, imageName := range order {
+		placement.Values = append(placement.Values, lexer.ExprValue{Value: node("component", imageName, placed[imageName])})
+	}
+	return placement, images
+}
+
+// padLayers returns the copper layers of a pad, as on the front for
+// footprints on the back.
+func (w *dsnWriter) padLayers(pad pcb.Pad, back bool) []string {
+	onPad := make(map[string]bool)
+	for _, layer := range pad.Layers {
+		switch layer {
+		case "*.Cu":
+			for _, l := range w.layers {
+				onPad[l] = true
+			}
+		case "F&B.Cu":
+			onPad["F.Cu"], onPad["B.Cu"] = true, true
+		default:
+			onPad[layer] = true
+		}
+	}
+	layers := []string{}
+	for i := range w.layers {
+		layer := w.layers[i]
+		if back {
+			layer = w.layers[len(w.layers)-1-i]
+		}
+		if onPad[layer] {
+			layers = append(layers, w.layers[i])
+		}
+	}
+	return layers
+}
+
+// padPadstack returns the name of the padstack of a pad turned by angle on
+// layers, adding it to the library when new. Shapes the router has no
+// equivalent of, such as rounded rectangles, are written as rectangles.
+func (w *dsnWriter) padPadstack(pad pcb.Pad, angle float64, layers []string) string {
+	width, height := pad.Size.Width, pad.Size.Height
+	size := formatMicrometres(width) + "x" + formatMicrometres(height)
+	if math.Mod(angle, 180) == 90 {
+		width, height = height, width
+		angle = 0
+	} else if math.Mod(angle, 180) == 0 {
+		angle = 0
+	}
+	// corner turns a point of the unrotated pad
+	corner := func(x, y float64) []lexer.Value {
+		x, y = rotate(x, y, angle)
+		return []lexer.Value{micrometres(x), micrometres(y)}
+	}
+
+	var kind string
+	var shape func(layer string) lexer.Expr
+	switch {
+	case pad.Shape == "circle" || (pad.Shape == "oval" && width == height):
+		kind, size, angle = "Round", formatMicrometres(width), 0
+		shape = func(layer string) lexer.Expr { return node("circle", layer, width) }
+	case pad.Shape == "oval":
+		kind = "Oval"
+		shape = func(layer string) lexer.Expr {
+			if width > height {
+				return node("path", layer, height, corner(-(width-height)/2, 0), corner((width-height)/2, 0))
+			}
+			return node("path", layer, width, corner(0, -(height-width)/2), corner(0, (height-width)/2))
+		}
+	case angle == 0:
+		kind = "Rect"
+		shape = func(layer string) lexer.Expr { return node("rect", layer, -width/2, -height/2, width/2, height/2) }
+	default:
+		kind = "Rect"
+		shape = func(layer string) lexer.Expr {
+			return node("polygon", layer, 0.0, corner(-width/2, -height/2), corner(width/2, -height/2),
+				corner(width/2, height/2), corner(-width/2, height/2), corner(-width/2, -height/2))
+		}
+	}
+
+	prefix := fmt.Sprintf("%s[%s]Pad_%s_um", kind, w.layerTag(layers), size)
+	if angle != 0 {
+		prefix += "_" + lexer.FormatNumber(angle)
+	}
+	return w.padstack(prefix, layers, shape)
+}
+
+// viaPadstack returns the name of the padstack of a via, adding it to the
+// library when new. The drill is only part of the name.
+func (w *dsnWriter) viaPadstack(size, drill float64, layers []string) string {
+	first, last := w.layerIndex(layers[0]), w.layerIndex(layers[len(layers)-1])
+	span := w.layers[first : last+1]
+	prefix := fmt.Sprintf("Via[%d-%d]_%s:%s_um", first, last, formatMicrometres(size), formatMicrometres(drill))
+	return w.padstack(prefix, span, func(layer string) lexer.Expr { return node("circle", layer, size) })
+}
+
+// padstack returns the name of the padstack with shape on every one of
+// layers, based on prefix and adding it to the library when new.
+func (w *dsnWriter) padstack(prefix string, layers []string, shape func(layer string) lexer.Expr) string {
+	padstack := node("padstack")
+	for _, layer := range layers {
+		padstack.Values = append(padstack.Values, lexer.ExprValue{Value: node("shape", shape(layer))})
+	}
+	padstack.Values = append(padstack.Values, lexer.ExprValue{Value: node("attach", "off")})
+
+	key := prefix + " " + lexer.FormatInline(padstack)
+	if padstackName, ok := w.padstackOf[key]; ok {
+		return padstackName
+	}
+	padstackName := w.uniqueName(prefix, "_")
+	w.padstackOf[key] = padstackName
+	padstack.Values = append([]lexer.Value{name(padstackName)}, padstack.Values...)
+	w.padstacks = append(w.padstacks, padstack)
+	return padstackName
+}
+
+// layerTag describes layers in padstack names like pcbnew: A for all, T for
+// the front, B for the back, else their indices.
+func (w *dsnWriter) layerTag(layers []string) string {
+	switch {
+	case len(layers) == len(w.layers):
+		return "A"
+	case len(layers) == 1 && layers[0] == w.layers[0]:
+		return "T"
+	case len(layers) == 1 && layers[0] == w.layers[len(w.layers)-1]:
+		return "B"
+	}
+	indices := []string{}
+	for _, layer := range layers {
+		indices = append(indices, strconv.Itoa(w.layerIndex(layer)))
+	}
+	return strings.Join(indices, ",")
+}
+
+// layerIndex returns the index of a copper layer, 0 for unknown layers.
+func (w *dsnWriter) layerIndex(layer string) int {
+	for i, l := range w.layers {
+		if l == layer {
+			return i
+		}
+	}
+	return 0
+}
+
+// uniqueName returns base, or base with separator and a number when base is
+// taken.
+func (w *dsnWriter) uniqueName(base, separator string) string {
+	candidate := base
+	for n := 2; w.names[candidate]; n++ {
+		candidate = fmt.Sprintf("%s%s%d", base, separator, n)
+	}
+	w.names[candidate] = true
+	return candidate
+}
+
+func (w *dsnWriter) network() lexer.Expr {
+	numbers := []int{}
+	for number := range w.netNames {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	network := node("network")
+	class := node("class", DefaultClass)
+	for _, number := range numbers {
+		net := node("net", w.netNames[number])
+		if pins := w.netPins[number]; len(pins) > 0 {
+			net.Values = append(net.Values, lexer.ExprValue{Value: node("pins", toValues(pins))})
+		}
+		network.Values = append(network.Values, lexer.ExprValue{Value: net})
+		class.Values = append(class.Values, name(w.netNames[number]))
+	}
+	class.Values = append(class.Values,
+		lexer.ExprValue{Value: node("circuit", node("use_via", w.viaPadstack(w.opts.ViaSize, w.opts.ViaDrill, w.layers)))},
+		lexer.ExprValue{Value: node("rule", node("width", w.opts.TraceWidth), node("clearance", w.opts.Clearance))},
+	)
+	network.Values = append(network.Values, lexer.ExprValue{Value: class})
+	return network
+}
+
+// wiring returns the tracks and vias already on the board, protected so the
+// router keeps them.
+func (w *dsnWriter) wiring() lexer.Expr {
+	wiring := node("wiring")
+	for _, seg := range w.board.Segments {
+		wire := node("wire", node("path", seg.Layer, seg.Width, point(seg.Start), point(seg.End)))
+		if netName, ok := w.netNames[seg.Net]; ok {
+			wire.Values = append(wire.Values, lexer.ExprValue{Value: node("net", netName)})
+		}
+		wire.Values = append(wire.Values, lexer.ExprValue{Value: node("type", "protect")})
+		wiring.Values = append(wiring.Values, lexer.ExprValue{Value: wire})
+	}
+	for _, via := range w.board.Vias {
+		layers := via.Layers
+		if len(layers) == 0 {
+			layers = w.layers
+		}
+		size, drill := via.Size, via.Drill
+		if size == 0 {
+			size = w.opts.ViaSize
+		}
+		if drill == 0 {
+			drill = w.opts.ViaDrill
+		}
+		v := node("via", w.viaPadstack(size, drill, w.sortLayers(layers)), point(via.Position))
+		if netName, ok := w.netNames[via.Net]; ok {
+			v.Values = append(v.Values, lexer.ExprValue{Value: node("net", netName)})
+		}
+		v.Values = append(v.Values, lexer.ExprValue{Value: node("type", "protect")})
+		wiring.Values = append(wiring.Values, lexer.ExprValue{Value: v})
+	}
+	return wiring
+}
+
+// sortLayers orders copper layers front to back.
+func (w *dsnWriter) sortLayers(layers []string) []string {
+	sorted := append([]string{}, layers...)
+	sort.SliceStable(sorted, func(i, j int) bool { return w.layerIndex(sorted[i]) < w.layerIndex(sorted[j]) })
+	return sorted
+}
+
+func toValues(names []string) []lexer.Value {
+	values := []lexer.Value{}
+	for _, n := range names {
+		values = append(values, name(n))
+	}
+	return values
+}
+
+// rotate turns (x, y) counterclockwise by degrees, with y pointing up.
+func rotate(x, y, degrees float64) (float64, float64) {
+	radians := degrees * math.Pi / 180
+	cos, sin := math.Cos(radians), math.Sin(radians)
+	return x*cos - y*sin, x*sin + y*cos
+}
+
+// normalizeAngle returns degrees in [0, 360).
+func normalizeAngle(degrees float64) float64 {
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+	return math.Round(degrees*1e6) / 1e6
+}
+
+func formatMicrometres(mm float64) string {
+	return lexer.FormatNumber(math.Round(mm*1000*resolution) / resolution)
+}
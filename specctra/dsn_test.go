@@ -0,0 +1,267 @@
+package specctra
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+// sampleBoard has a resistor turned by 90 degrees, a connector on the back
+// and a track and a via.
+func sampleBoard() *pcb.Board {
+	board := pcb.NewBoard()
+	board.Nets = append(board.Nets, pcb.Net{Number: 0}, pcb.Net{Number: 1, Name: "GND"}, pcb.Net{Number: 2, Name: "VCC"})
+	board.Footprints = append(board.Footprints,
+		pcb.Footprint{Reference: "R1", Name: "R_0805", Layer: "F.Cu", Position: pcb.Position{X: 10, Y: 10}, Rotation: 90, UUID: "f1"},
+		pcb.Footprint{Reference: "J1", Name: "Conn", Layer: "B.Cu", Position: pcb.Position{X: 20, Y: 10}, UUID: "f2"},
+	)
+	resistorPad := pcb.Pad{Type: "smd", Shape: "rect", Size: pcb.Size{Width: 1, Height: 0.5}, Rotation: 90, Layers: []string{"F.Cu", "F.Mask"}, Reference: "R1", FootprintUUID: "f1"}
+	board.AddPad(withPad(resistorPad, "1", pcb.Position{X: 10, Y: 11}, pcb.Net{Number: 1, Name: "GND"}))
+	board.AddPad(withPad(resistorPad, "2", pcb.Position{X: 10, Y: 9}, pcb.Net{Number: 2, Name: "VCC"}))
+	board.AddPad(pcb.Pad{Number: "1", Position: pcb.Position{X: 21, Y: 10}, Net: pcb.Net{Number: 1, Name: "GND"}, Type: "thru_hole", Shape: "circle",
+		Size: pcb.Size{Width: 1.6, Height: 1.6}, Drill: 0.8, Layers: []string{"F.Cu", "B.Cu"}, Reference: "J1", FootprintUUID: "f2"})
+	board.AddPad(pcb.Pad{Position: pcb.Position{X: 20, Y: 12}, Type: "np_thru_hole", Shape: "circle", Size: pcb.Size{Width: 2, Height: 2},
+		Drill: 2, Layers: []string{"F.Cu", "B.Cu"}, Reference: "J1", FootprintUUID: "f2"})
+	board.AddSegment(pcb.Segment{Start: pcb.Position{X: 10, Y: 11}, End: pcb.Position{X: 21, Y: 10}, Width: 0.25, Layer: "F.Cu", Net: 1, UUID: "s1"})
+	board.AddVia(pcb.Via{Position: pcb.Position{X: 15, Y: 5}, Size: 0.8, Drill: 0.4, Layers: []string{"F.Cu", "B.Cu"}, Net: 2, UUID: "v1"})
+	board.Outline = append(board.Outline, pcb.OutlineShape{Kind: "rect", Points: []pcb.Position{{X: 0, Y: 0}, {X: 30, Y: 20}}})
+	return board
+}
+
+func withPad(pad pcb.Pad, number string, position pcb.Position, net pcb.Net) pcb.Pad {
+	pad.Number, pad.Position, pad.Net = number, position, net
+	return pad
+}
+
+func TestBoardToDSN(t *testing.T) {
+	// Act
+	dsn := BoardToDSN(sampleBoard(), DefaultOptions())
+
+	// Assert
+	expected := map[string]string{
+		"structure": `(structure (layer F.Cu (type signal) (property (index 0))) (layer B.Cu (type signal) (property (index 1)))` +
+			` (boundary (path pcb 0 0 0 30000 0 30000 -20000 0 -20000 0 0)) (via Via[0-1]_600:300_um) (rule (width 200) (clearance 200)))`,
+		"placement": `(placement (component R_0805 (place R1 10000 -10000 front 90)) (component Conn (place J1 20000 -10000 back 0)))`,
+		"library": `(library (image R_0805 (pin Rect[T]Pad_1000x500_um "1" -1000 0) (pin Rect[T]Pad_1000x500_um "2" 1000 0))` +
+			` (image Conn (pin Round[A]Pad_1600_um "1" -1000 0))` +
+			` (padstack Rect[T]Pad_1000x500_um (shape (rect F.Cu -500 -250 500 250)) (attach off))` +
+			` (padstack Round[A]Pad_1600_um (shape (circle F.Cu 1600)) (shape (circle B.Cu 1600)) (attach off))` +
+			` (padstack Via[0-1]_600:300_um (shape (circle F.Cu 600)) (shape (circle B.Cu 600)) (attach off))` +
+			` (padstack Via[0-1]_800:400_um (shape (circle F.Cu 800)) (shape (circle B.Cu 800)) (attach off)))`,
+		"network": `(network (net GND (pins R1-1 J1-1)) (net VCC (pins R1-2))` +
+			` (class kicad_default GND VCC (circuit (use_via Via[0-1]_600:300_um)) (rule (width 200) (clearance 200))))`,
+		"wiring": `(wiring (wire (path F.Cu 250 10000 -11000 21000 -10000) (net GND) (type protect))` +
+			` (via Via[0-1]_800:400_um 15000 -5000 (net VCC) (type protect)))`,
+	}
+	for identifier, want := range expected {
+		got, ok := child(dsn, identifier)
+		if !ok {
+			t.Errorf("Expected a (%s ...), got none", identifier)
+			continue
+		}
+		if text := lexer.FormatInline(got); text != want {
+			t.Errorf("Expected\n%s\ngot\n%s", want, text)
+		}
+	}
+}
+
+func TestBoardToDSN_ReadsBack(t *testing.T) {
+	// Arrange
+	dsn := BoardToDSN(sampleBoard(), DefaultOptions())
+
+	// Act
+	parsed, err := Parse(strings.NewReader(lexer.Format(dsn)))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got, want := lexer.FormatInline(parsed), lexer.FormatInline(dsn); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+// placedPins returns where the pins of a design end up on the board, by
+// component and pin name, in mm with y down.
+func placedPins(t *testing.T, design lexer.Expr) map[string]pcb.Position {
+	t.Helper()
+	dsn, err := Parse(strings.NewReader(lexer.Format(design)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	library, _ := child(dsn, "library")
+	images := make(map[string]lexer.Expr)
+	for _, image := range children(library, "image") {
+		imageName, _ := text(image.Values[0])
+		images[imageName] = image
+	}
+	placement, _ := child(dsn, "placement")
+	pins := make(map[string]pcb.Position)
+	for _, component := range children(placement, "component") {
+		imageName, _ := text(component.Values[0])
+		for _, place := range children(component, "place") {
+			values := atoms(place)
+			reference, _ := text(values[0])
+			x, y := values[1].(lexer.NumberValue).Value, values[2].(lexer.NumberValue).Value
+			side, _ := text(values[3])
+			rotation := values[4].(lexer.NumberValue).Value
+			for _, pin := range children(images[imageName], "pin") {
+				pinValues := atoms(pin)
+				pinName, _ := text(pinValues[1])
+				px, py := pinValues[2].(lexer.NumberValue).Value, pinValues[3].(lexer.NumberValue).Value
+				if side == "back" {
+					px = -px
+				}
+				px, py = rotate(px, py, rotation)
+				pins[reference+"-"+pinName] = pcb.Position{X: (x + px) / 1000, Y: -(y + py) / 1000}
+			}
+		}
+	}
+	return pins
+}
+
+func TestBoardToDSN_PinsWherePadsAre(t *testing.T) {
+	tests := []struct {
+		name     string
+		rotation float64
+		layer    string
+	}{
+		{"front", 0, "F.Cu"},
+		{"front turned", 30, "F.Cu"},
+		{"back", 0, "B.Cu"},
+		{"back turned", -120, "B.Cu"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			board := pcb.NewBoard()
+			board.Footprints = append(board.Footprints, pcb.Footprint{Reference: "U1", Name: "SOT", Layer: tt.layer, Position: pcb.Position{X: 5, Y: 7}, Rotation: tt.rotation})
+			radians := tt.rotation * math.Pi / 180
+			for i, local := range []pcb.Position{{X: -1, Y: 0.5}, {X: 1, Y: 0.5}, {X: 0, Y: -1.2}} {
+				// Placed the way pcbnew does, turned counterclockwise on screen
+				position := pcb.Position{
+					X: 5 + local.X*math.Cos(radians) + local.Y*math.Sin(radians),
+					Y: 7 - local.X*math.Sin(radians) + local.Y*math.Cos(radians),
+				}
+				board.AddPad(pcb.Pad{Number: string(rune('1' + i)), Position: position, Type: "smd", Shape: "rect", Size: pcb.Size{Width: 1, Height: 1},
+					Rotation: tt.rotation, Layers: []string{tt.layer}, Reference: "U1"})
+			}
+
+			// Act
+			pins := placedPins(t, BoardToDSN(board, DefaultOptions()))
+
+			// Assert
+			for _, pad := range board.Pads {
+				got, ok := pins["U1-"+pad.Number]
+				if !ok || math.Abs(got.X-pad.Position.X) > 1e-4 || math.Abs(got.Y-pad.Position.Y) > 1e-4 {
+					t.Errorf("Expected pin %s at %v, got %v", pad.Number, pad.Position, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBoardToDSN_DuplicateReferences(t *testing.T) {
+	// Arrange
+	board := pcb.NewBoard()
+	pad := pcb.Pad{Number: "1", Type: "thru_hole", Shape: "circle", Size: pcb.Size{Width: 2, Height: 2}, Layers: []string{"F.Cu", "B.Cu"}, Reference: "REF**"}
+	board.Footprints = append(board.Footprints,
+		pcb.Footprint{Reference: "REF**", Name: "Hole", Layer: "F.Cu", Position: pcb.Position{X: 0, Y: 0}, UUID: "a"},
+		pcb.Footprint{Reference: "REF**", Name: "Hole", Layer: "F.Cu", Position: pcb.Position{X: 1, Y: 0}, UUID: "b"},
+	)
+	// The pad of b is nearer to a
+	pad.Position, pad.FootprintUUID = pcb.Position{X: 0, Y: 0}, "a"
+	board.AddPad(pad)
+	pad.Position, pad.FootprintUUID = pcb.Position{X: -5, Y: 0}, "b"
+	board.AddPad(pad)
+
+	// Act
+	pins := placedPins(t, BoardToDSN(board, DefaultOptions()))
+
+	// Assert
+	expected := map[string]pcb.Position{"REF**-1": {X: 0, Y: 0}, "REF**_2-1": {X: -5, Y: 0}}
+	if !reflect.DeepEqual(pins, expected) {
+		t.Errorf("Expected %v, got %v", expected, pins)
+	}
+}
+
+func TestBoundary(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    *pcb.Board
+		expected []pcb.Position
+	}{
+		{
+			"lines out of order",
+			&pcb.Board{Outline: []pcb.OutlineShape{
+				{Kind: "line", Points: []pcb.Position{{X: 0, Y: 0}, {X: 10, Y: 0}}},
+				{Kind: "line", Points: []pcb.Position{{X: 0, Y: 5}, {X: 10, Y: 5}}},
+				{Kind: "line", Points: []pcb.Position{{X: 0, Y: 5}, {X: 0, Y: 0}}},
+				{Kind: "line", Points: []pcb.Position{{X: 10, Y: 0}, {X: 10, Y: 5}}},
+			}},
+			[]pcb.Position{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}, {X: 0, Y: 5}, {X: 0, Y: 0}},
+		},
+		{
+			"largest loop",
+			&pcb.Board{Outline: []pcb.OutlineShape{
+				{Kind: "rect", Points: []pcb.Position{{X: 2, Y: 2}, {X: 3, Y: 3}}},
+				{Kind: "rect", Points: []pcb.Position{{X: 0, Y: 0}, {X: 10, Y: 5}}},
+			}},
+			[]pcb.Position{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}, {X: 0, Y: 5}, {X: 0, Y: 0}},
+		},
+		{
+			"open outline",
+			&pcb.Board{Outline: []pcb.OutlineShape{
+				{Kind: "line", Points: []pcb.Position{{X: 0, Y: 0}, {X: 10, Y: 0}}},
+				{Kind: "line", Points: []pcb.Position{{X: 10, Y: 0}, {X: 10, Y: 5}}},
+			}},
+			[]pcb.Position{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}, {X: 0, Y: 5}, {X: 0, Y: 0}},
+		},
+		{
+			"no outline",
+			&pcb.Board{Pads: []pcb.Pad{{Position: pcb.Position{X: 1, Y: 1}}, {Position: pcb.Position{X: 4, Y: 2}}}},
+			[]pcb.Position{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 5, Y: 3}, {X: 0, Y: 3}, {X: 0, Y: 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := boundary(tt.board); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestArcPoints(t *testing.T) {
+	tests := []struct {
+		name  string
+		mid   pcb.Position
+		sweep float64 // Expected angle from start to end, counterclockwise with y down
+	}{
+		{"through the top", pcb.Position{X: 0, Y: -1}, -180},
+		{"through the bottom", pcb.Position{X: 0, Y: 1}, 180},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			points := arcPoints(pcb.Position{X: 1, Y: 0}, tt.mid, pcb.Position{X: -1, Y: 0})
+
+			// Assert
+			if len(points) != int(math.Abs(tt.sweep)/arcStep)+1 {
+				t.Fatalf("Expected %d points, got %d", int(math.Abs(tt.sweep)/arcStep)+1, len(points))
+			}
+			for _, p := range points {
+				if math.Abs(math.Hypot(p.X, p.Y)-1) > 1e-9 || p.Y*tt.mid.Y < -1e-9 {
+					t.Errorf("Expected points on the unit circle on the side of %v, got %v", tt.mid, p)
+				}
+			}
+		})
+	}
+}
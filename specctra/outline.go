@@ -0,0 +1,183 @@
+package specctra
+
+import (
+	"math"
+
+	"github.com/mackeper/lin_router/pcb"
+)
+
+const (
+	joinTolerance = 1e-3 // mm between outline ends that meet
+	arcStep       = 5.0  // Degrees per line of an arc or circle
+)
+
+// boundary returns the board outline as a closed polygon, first point
+// repeated last. Lines and arcs are joined end to end and the largest loop
+// is used, so cutouts are left out. Without a closed outline it is the
+// bounding box of the outline, or of the copper with a 1 mm margin.
+func boundary(board *pcb.Board) []pcb.Position {
+	var best []pcb.Position
+	bestArea := 0.0
+	for _, loop := range outlineLoops(board.Outline) {
+		if area := math.Abs(polygonArea(loop)); area > bestArea {
+			best, bestArea = loop, area
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	points := []pcb.Position{}
+	margin := 0.0
+	for _, shape := range board.Outline {
+		points = append(points, shapePoints(shape)...)
+	}
+	if len(points) == 0 {
+		margin = 1
+		for _, pad := range board.Pads {
+			points = append(points, pad.Position)
+		}
+		for _, seg := range board.Segments {
+			points = append(points, seg.Start, seg.End)
+		}
+		for _, via := range board.Vias {
+			points = append(points, via.Position)
+		}
+	}
+	if len(points) == 0 {
+		points = []pcb.Position{{}}
+	}
+	minX, minY, maxX, maxY := points[0].X, points[0].Y, points[0].X, points[0].Y
+	for _, p := range points {
+		minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+		maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+	}
+	minX, minY, maxX, maxY = minX-margin, minY-margin, maxX+margin, maxY+margin
+	return []pcb.Position{{X: minX, Y: minY}, {X: maxX, Y: minY}, {X: maxX, Y: maxY}, {X: minX, Y: maxY}, {X: minX, Y: minY}}
+}
+
+// outlineLoops returns the closed polygons of an outline: rectangles,
+// circles and polygons as they are, and lines and arcs joined end to end.
+func outlineLoops(shapes []pcb.OutlineShape) [][]pcb.Position {
+	loops := [][]pcb.Position{}
+	pieces := [][]pcb.Position{}
+	for _, shape := range shapes {
+		points := shapePoints(shape)
+		switch shape.Kind {
+		case "rect", "circle", "poly":
+			if len(points) > 2 {
+				loops = append(loops, append(append([]pcb.Position{}, points...), points[0]))
+			}
+		case "line", "arc":
+			pieces = append(pieces, points)
+		}
+	}
+
+	used := make([]bool, len(pieces))
+	for i := range pieces {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		loop := append([]pcb.Position{}, pieces[i]...)
+		for !near(loop[0], loop[len(loop)-1]) {
+			next := -1
+			for j, piece := range pieces {
+				if used[j] {
+					continue
+				}
+				end := loop[len(loop)-1]
+				if near(piece[0], end) {
+					next = j
+					break
+				}
+				if near(piece[len(piece)-1], end) {
+					reversed := make([]pcb.Position, len(piece))
+					for k, p := range piece {
+						reversed[len(piece)-1-k] = p
+					}
+					pieces[j] = reversed
+					next = j
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+			used[next] = true
+			loop = append(loop, pieces[next][1:]...)
+		}
+		if len(loop) > 3 && near(loop[0], loop[len(loop)-1]) {
+			loop[len(loop)-1] = loop[0]
+			loops = append(loops, loop)
+		}
+	}
+	return loops
+}
+
+// shapePoints returns the corners of a shape, with arcs and circles as
+// lines of at most arcStep degrees.
+func shapePoints(shape pcb.OutlineShape) []pcb.Position {
+	points := shape.Points
+	switch {
+	case shape.Kind == "rect" && len(points) == 2:
+		a, b := points[0], points[1]
+		return []pcb.Position{a, {X: b.X, Y: a.Y}, b, {X: a.X, Y: b.Y}}
+	case shape.Kind == "circle" && len(points) == 2:
+		centre, radius := points[0], points[0].Distance(points[1])
+		circle := []pcb.Position{}
+		for angle := 0.0; angle < 360; angle += arcStep {
+			radians := angle * math.Pi / 180
+			circle = append(circle, pcb.Position{X: centre.X + radius*math.Cos(radians), Y: centre.Y + radius*math.Sin(radians)})
+		}
+		return circle
+	case shape.Kind == "arc" && len(points) == 3:
+		return arcPoints(points[0], points[1], points[2])
+	}
+	return points
+}
+
+// arcPoints returns points along the arc from start through mid to end.
+func arcPoints(start, mid, end pcb.Position) []pcb.Position {
+	// The centre is where the perpendicular bisectors of the chords meet
+	ax, ay := mid.X-start.X, mid.Y-start.Y
+	bx, by := end.X-start.X, end.Y-start.Y
+	d := 2 * (ax*by - ay*bx)
+	if math.Abs(d) < 1e-12 {
+		return []pcb.Position{start, end}
+	}
+	ux := (by*(ax*ax+ay*ay) - ay*(bx*bx+by*by)) / d
+	uy := (ax*(bx*bx+by*by) - bx*(ax*ax+ay*ay)) / d
+	centre := pcb.Position{X: start.X + ux, Y: start.Y + uy}
+	radius := math.Hypot(ux, uy)
+
+	angleOf := func(p pcb.Position) float64 { return math.Atan2(p.Y-centre.Y, p.X-centre.X) }
+	from, through, to := angleOf(start), angleOf(mid), angleOf(end)
+	sweep := math.Mod(to-from+4*math.Pi, 2*math.Pi)
+	if math.Mod(through-from+4*math.Pi, 2*math.Pi) > sweep {
+		sweep -= 2 * math.Pi // Clockwise, through mid
+	}
+	steps := int(math.Ceil(math.Abs(sweep) * 180 / math.Pi / arcStep))
+	if steps < 1 {
+		steps = 1
+	}
+	points := []pcb.Position{start}
+	for i := 1; i < steps; i++ {
+		angle := from + sweep*float64(i)/float64(steps)
+		points = append(points, pcb.Position{X: centre.X + radius*math.Cos(angle), Y: centre.Y + radius*math.Sin(angle)})
+	}
+	return append(points, end)
+}
+
+func near(a, b pcb.Position) bool {
+	return a.Distance(b) <= joinTolerance
+}
+
+// polygonArea is the signed area of a closed polygon.
+func polygonArea(points []pcb.Position) float64 {
+	area := 0.0
+	for i := 0; i+1 < len(points); i++ {
+		area += points[i].X*points[i+1].Y - points[i+1].X*points[i].Y
+	}
+	return area / 2
+}
@@ -0,0 +1,298 @@
+package specctra
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+// Routes are the wires and vias of a session.
+type Routes struct {
+	Segments []pcb.Segment
+	Vias     []pcb.Via
+}
+
+// unitSizes are the Specctra units in mm.
+var unitSizes = map[string]float64{
+	"inch": 25.4,
+	"mil":  0.0254,
+	"cm":   10,
+	"mm":   1,
+	"um":   0.001,
+}
+
+// viaName matches the padstack names BoardToDSN and pcbnew give vias,
+// e.g. Via[0-1]_600:300_um.
+var viaName = regexp.MustCompile(`^Via\[(\d+)-(\d+)\]_([0-9.]+):([0-9.]+)_um`)
+
+// ReadSession returns the wires and vias of the (routes (network_out ...))
+// of a session, for the board its design was written from. Nets are
+// matched by name and layers must be copper layers of the board. Via sizes
+// and drills are read from pcbnew style padstack names such as
+// Via[0-1]_600:300_um, since a session does not give drills.
+func ReadSession(session lexer.Expr, board *pcb.Board) (Routes, error) {
+	routes := Routes{Segments: []pcb.Segment{}, Vias: []pcb.Via{}}
+	if session.Identifier != "session" {
+		return routes, fmt.Errorf("specctra: expected a session, got (%s", session.Identifier)
+	}
+	routesExpr, ok := child(session, "routes")
+	if !ok {
+		return routes, nil
+	}
+	scale, err := readResolution(routesExpr)
+	if err != nil {
+		return routes, err
+	}
+	coord := func(v lexer.Value) (float64, error) {
+		number, ok := v.(lexer.NumberValue)
+		if !ok {
+			return 0, fmt.Errorf("specctra: expected a number, got %s", v)
+		}
+		return roundMM(number.Value * scale), nil
+	}
+
+	layers := CopperLayers(board)
+	viaPadstacks := make(map[string]pcb.Via)
+	if library, ok := child(routesExpr, "library_out"); ok {
+		for _, padstack := range children(library, "padstack") {
+			via, err := readViaPadstack(padstack, scale)
+			if err != nil {
+				return routes, err
+			}
+			padstackName, _ := text(atoms(padstack)[0])
+			viaPadstacks[padstackName] = via
+		}
+	}
+
+	netNumbers := make(map[string]int)
+	for number, netName := range netNames(board) {
+		netNumbers[netName] = number
+	}
+	network, ok := child(routesExpr, "network_out")
+	if !ok {
+		return routes, nil
+	}
+	for _, net := range children(network, "net") {
+		values := atoms(net)
+		if len(values) == 0 {
+			return routes, fmt.Errorf("specctra: net without a name")
+		}
+		netName, _ := text(values[0])
+		number, ok := netNumbers[netName]
+		if !ok {
+			return routes, fmt.Errorf("specctra: unknown net %q", netName)
+		}
+
+		for _, wire := range children(net, "wire") {
+			path, ok := child(wire, "path")
+			if !ok {
+				return routes, fmt.Errorf("specctra: net %q: only wires with a path are supported, got %s", netName, lexer.FormatInline(wire))
+			}
+			values := atoms(path)
+			if len(values) < 6 || len(values)%2 != 0 {
+				return routes, fmt.Errorf("specctra: net %q: malformed %s", netName, lexer.FormatInline(path))
+			}
+			layer, _ := text(values[0])
+			if !slices.Contains(layers, layer) {
+				return routes, fmt.Errorf("specctra: net %q: wire on %q, which is not a copper layer of the board", netName, layer)
+			}
+			width, err := coord(values[1])
+			if err != nil {
+				return routes, err
+			}
+			points := []pcb.Position{}
+			for i := 2; i < len(values); i += 2 {
+				x, err := coord(values[i])
+				if err != nil {
+					return routes, err
+				}
+				y, err := coord(values[i+1])
+				if err != nil {
+					return routes, err
+				}
+				points = append(points, pcb.Position{X: x, Y: roundMM(-y)})
+			}
+			for i := 1; i < len(points); i++ {
+				if points[i] != points[i-1] {
+					routes.Segments = append(routes.Segments, pcb.Segment{Start: points[i-1], End: points[i], Width: width, Layer: layer, Net: number})
+				}
+			}
+		}
+
+		for _, via := range children(net, "via") {
+			values := atoms(via)
+			if len(values) < 3 {
+				return routes, fmt.Errorf("specctra: net %q: malformed %s", netName, lexer.FormatInline(via))
+			}
+			padstackName, _ := text(values[0])
+			v, err := sessionVia(padstackName, viaPadstacks, layers)
+			if err != nil {
+				return routes, fmt.Errorf("specctra: net %q: %w", netName, err)
+			}
+			x, err := coord(values[1])
+			if err != nil {
+				return routes, err
+			}
+			y, err := coord(values[2])
+			if err != nil {
+				return routes, err
+			}
+			v.Position = pcb.Position{X: x, Y: roundMM(-y)}
+			v.Net = number
+			routes.Vias = append(routes.Vias, v)
+		}
+	}
+	return routes, nil
+}
+
+// readResolution returns the size in mm of a coordinate unit of the
+// (resolution UNIT N) of e.
+func readResolution(e lexer.Expr) (float64, error) {
+	res, ok := child(e, "resolution")
+	if !ok {
+		return 0, fmt.Errorf("specctra: routes without a resolution")
+	}
+	values := atoms(res)
+	if len(values) != 2 {
+		return 0, fmt.Errorf("specctra: malformed %s", lexer.FormatInline(res))
+	}
+	unit, _ := text(values[0])
+	size, ok := unitSizes[unit]
+	if !ok {
+		return 0, fmt.Errorf("specctra: unknown unit %q", unit)
+	}
+	perUnit, ok := values[1].(lexer.NumberValue)
+	if !ok || perUnit.Value <= 0 {
+		return 0, fmt.Errorf("specctra: malformed %s", lexer.FormatInline(res))
+	}
+	return size / perUnit.Value, nil
+}
+
+// readViaPadstack reads the size and layers of a (padstack NAME (shape
+// (circle LAYER DIAMETER)) ...) of a session's library_out.
+func readViaPadstack(padstack lexer.Expr, scale float64) (pcb.Via, error) {
+	via := pcb.Via{}
+	if len(atoms(padstack)) == 0 {
+		return via, fmt.Errorf("specctra: padstack without a name")
+	}
+	for _, shape := range children(padstack, "shape") {
+		circle, ok := child(shape, "circle")
+		if !ok {
+			continue
+		}
+		values := atoms(circle)
+		if len(values) < 2 {
+			return via, fmt.Errorf("specctra: malformed %s", lexer.FormatInline(circle))
+		}
+		layer, _ := text(values[0])
+		if diameter, ok := values[1].(lexer.NumberValue); ok {
+			via.Size = math.Max(via.Size, roundMM(diameter.Value*scale))
+		}
+		via.Layers = append(via.Layers, layer)
+	}
+	return via, nil
+}
+
+// sessionVia returns a via of the padstack called padstackName, with its
+// size, drill and first and last layer. The layers of the padstack in the
+// library, if it is there, take precedence over those in its name.
+func sessionVia(padstackName string, library map[string]pcb.Via, layers []string) (pcb.Via, error) {
+	via := pcb.Via{}
+	fromLibrary, inLibrary := library[padstackName]
+	match := viaName.FindStringSubmatch(padstackName)
+	if match == nil {
+		if inLibrary {
+			return via, fmt.Errorf("via padstack %q does not give a drill, want a name such as Via[0-1]_600:300_um", padstackName)
+		}
+		return via, fmt.Errorf("unknown via padstack %q", padstackName)
+	}
+	firstLayer, _ := strconv.Atoi(match[1])
+	lastLayer, _ := strconv.Atoi(match[2])
+	size, _ := strconv.ParseFloat(match[3], 64)
+	drill, _ := strconv.ParseFloat(match[4], 64)
+	if firstLayer < len(layers) && lastLayer < len(layers) {
+		via.Layers = []string{layers[firstLayer], layers[lastLayer]}
+	}
+	via.Size, via.Drill = size/1000, drill/1000
+
+	if inLibrary && len(fromLibrary.Layers) > 0 {
+		index := make(map[string]int)
+		for i, layer := range layers {
+			index[layer] = i
+		}
+		first, last := fromLibrary.Layers[0], fromLibrary.Layers[0]
+		for _, layer := range fromLibrary.Layers {
+			if _, ok := index[layer]; !ok {
+				return via, fmt.Errorf("via padstack %q is on %q, which is not a copper layer of the board", padstackName, layer)
+			}
+			if index[layer] < index[first] {
+				first = layer
+			}
+			if index[layer] > index[last] {
+				last = layer
+			}
+		}
+		via.Layers = []string{first, last}
+	}
+	if len(via.Layers) == 0 {
+		via.Layers = []string{layers[0], layers[len(layers)-1]}
+	}
+	return via, nil
+}
+
+// AddTo adds the segments and vias of r that the board does not have yet,
+// such as the protected wiring of its design, and returns how many were
+// added. Positions within the 0.1 µm of a design's resolution are the same.
+func (r Routes) AddTo(board *pcb.Board) (segments, vias int) {
+	for _, seg := range r.Segments {
+		if !hasSegment(board, seg) {
+			board.AddSegment(seg)
+			segments++
+		}
+	}
+	for _, via := range r.Vias {
+		if !hasVia(board, via) {
+			board.AddVia(via)
+			vias++
+		}
+	}
+	return segments, vias
+}
+
+const sameTolerance = 1e-4 // mm
+
+func samePosition(a, b pcb.Position) bool {
+	return math.Abs(a.X-b.X) <= sameTolerance && math.Abs(a.Y-b.Y) <= sameTolerance
+}
+
+func hasSegment(board *pcb.Board, seg pcb.Segment) bool {
+	for _, other := range board.Segments {
+		if other.Net != seg.Net || other.Layer != seg.Layer || math.Abs(other.Width-seg.Width) > sameTolerance {
+			continue
+		}
+		if (samePosition(other.Start, seg.Start) && samePosition(other.End, seg.End)) ||
+			(samePosition(other.Start, seg.End) && samePosition(other.End, seg.Start)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVia(board *pcb.Board, via pcb.Via) bool {
+	for _, other := range board.Vias {
+		if other.Net == via.Net && samePosition(other.Position, via.Position) {
+			return true
+		}
+	}
+	return false
+}
+
+// roundMM rounds to the nanometres of a board file.
+func roundMM(mm float64) float64 {
+	return math.Round(mm*1e6) / 1e6
+}
@@ -0,0 +1,170 @@
+package specctra
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      string
+	}{
+		{"bare names", "(net /SDA (pins U1-3))", "(net /SDA (pins U1-3))", ""},
+		{"numbers", "(via V 600 -12.5 .5)", "(via V 600 -12.5 0.5)", ""},
+		{"no quote yet", `(net "a b")`, `(net "a b")`, ""},
+		{"quoted", "(pcb (parser (string_quote \")) (net \"a b\"))", "(pcb (parser (string_quote \")) (net \"a b\"))", ""},
+		{"other quote", "(pcb (parser (string_quote ')) (net 'a b'))", `(pcb (parser (string_quote ')) (net "a b"))`, ""},
+		{"unterminated string", "(pcb (parser (string_quote \"))\n(net \"a b))", "", "specctra: line 2: unterminated string"},
+		{"unclosed", "(pcb (net a)", "", "specctra: line 1: expected ')' to close (pcb, got end of file"},
+		{"trailing", "(pcb) x", "", `specctra: line 1: unexpected 'x' after the end of the file`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			expr, err := Parse(strings.NewReader(tt.input))
+
+			// Assert
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("Expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := lexer.FormatInline(expr); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func sessionBoard() *pcb.Board {
+	board := pcb.NewBoard()
+	board.Nets = append(board.Nets, pcb.Net{Number: 0}, pcb.Net{Number: 1, Name: "GND"}, pcb.Net{Number: 2, Name: "/SDA"})
+	board.AddPad(pcb.Pad{Net: pcb.Net{Number: 1, Name: "GND"}, Layers: []string{"F.Cu", "B.Cu"}})
+	return board
+}
+
+func TestReadSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		session  string
+		expected Routes
+		err      string
+	}{
+		{
+			"wire",
+			`(session s (routes (resolution um 10) (network_out (net GND (wire (path F.Cu 2500 0 0 10000 -10000 10000 -20000))))))`,
+			Routes{
+				Segments: []pcb.Segment{
+					{Start: pcb.Position{X: 0, Y: 0}, End: pcb.Position{X: 1, Y: 1}, Width: 0.25, Layer: "F.Cu", Net: 1},
+					{Start: pcb.Position{X: 1, Y: 1}, End: pcb.Position{X: 1, Y: 2}, Width: 0.25, Layer: "F.Cu", Net: 1},
+				},
+				Vias: []pcb.Via{},
+			},
+			"",
+		},
+		{
+			"vias",
+			`(session s (routes (resolution mm 1000)
+				(library_out (padstack Via[0-1]_600:300_um (shape (circle B.Cu 600)) (shape (circle F.Cu 600))))
+				(network_out (net /SDA (via Via[0-1]_800:400_um 1000 -2000) (via Via[0-1]_600:300_um 3000 0)))))`,
+			Routes{
+				Segments: []pcb.Segment{},
+				Vias: []pcb.Via{
+					{Position: pcb.Position{X: 1, Y: 2}, Size: 0.8, Drill: 0.4, Layers: []string{"F.Cu", "B.Cu"}, Net: 2},
+					{Position: pcb.Position{X: 3, Y: 0}, Size: 0.6, Drill: 0.3, Layers: []string{"F.Cu", "B.Cu"}, Net: 2},
+				},
+			},
+			"",
+		},
+		{"no routes", `(session s)`, Routes{Segments: []pcb.Segment{}, Vias: []pcb.Via{}}, ""},
+		{"not a session", `(pcb s)`, Routes{}, "specctra: expected a session, got (pcb"},
+		{"unknown net", `(session s (routes (resolution um 10) (network_out (net VCC))))`, Routes{}, `specctra: unknown net "VCC"`},
+		{"unknown unit", `(session s (routes (resolution furlong 10)))`, Routes{}, `specctra: unknown unit "furlong"`},
+		{"unknown via", `(session s (routes (resolution um 10) (network_out (net GND (via V 0 0)))))`, Routes{}, `specctra: net "GND": unknown via padstack "V"`},
+		{
+			"via without a drill",
+			`(session s (routes (resolution um 10) (library_out (padstack V (shape (circle F.Cu 500)))) (network_out (net GND (via V 0 0)))))`,
+			Routes{},
+			`specctra: net "GND": via padstack "V" does not give a drill, want a name such as Via[0-1]_600:300_um`,
+		},
+		{
+			"via on an unknown layer",
+			`(session s (routes (resolution um 10) (library_out (padstack Via[0-1]_600:300_um (shape (circle Top 600)))) (network_out (net GND (via Via[0-1]_600:300_um 0 0)))))`,
+			Routes{},
+			`specctra: net "GND": via padstack "Via[0-1]_600:300_um" is on "Top", which is not a copper layer of the board`,
+		},
+		{
+			"wire on an unknown layer",
+			`(session s (routes (resolution um 10) (network_out (net GND (wire (path Top 2500 0 0 10000 0))))))`,
+			Routes{},
+			`specctra: net "GND": wire on "Top", which is not a copper layer of the board`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			session, err := Parse(strings.NewReader(tt.session))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			routes, err := ReadSession(session, sessionBoard())
+
+			// Assert
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("Expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(routes, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, routes)
+			}
+		})
+	}
+}
+
+func TestRoutes_AddTo(t *testing.T) {
+	// Arrange
+	board := sessionBoard()
+	board.AddSegment(pcb.Segment{Start: pcb.Position{X: 1, Y: 1}, End: pcb.Position{X: 0, Y: 0}, Width: 0.25, Layer: "F.Cu", Net: 1})
+	board.AddVia(pcb.Via{Position: pcb.Position{X: 1, Y: 2}, Size: 0.8, Drill: 0.4, Net: 2})
+	routes := Routes{
+		Segments: []pcb.Segment{
+			{Start: pcb.Position{X: 0, Y: 0.00001}, End: pcb.Position{X: 1, Y: 1}, Width: 0.25, Layer: "F.Cu", Net: 1},
+			{Start: pcb.Position{X: 0, Y: 0}, End: pcb.Position{X: 1, Y: 1}, Width: 0.25, Layer: "B.Cu", Net: 1},
+		},
+		Vias: []pcb.Via{
+			{Position: pcb.Position{X: 1, Y: 2}, Size: 0.8, Drill: 0.4, Net: 2},
+			{Position: pcb.Position{X: 1, Y: 3}, Size: 0.8, Drill: 0.4, Net: 2},
+		},
+	}
+
+	// Act
+	segments, vias := routes.AddTo(board)
+
+	// Assert
+	if segments != 1 || vias != 1 {
+		t.Errorf("Expected 1 track and 1 via added, got %d and %d", segments, vias)
+	}
+	if len(board.Segments) != 2 || len(board.Vias) != 2 {
+		t.Errorf("Expected 2 tracks and 2 vias, got %d and %d", len(board.Segments), len(board.Vias))
+	}
+}
@@ -0,0 +1,189 @@
+package specctra
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mackeper/lin_router/lexer"
+)
+
+// Specctra files are S-expressions, but not KiCad ones: names such as /SDA
+// or Via[0-1]_600:300_um are written bare, strings have no escapes and the
+// quote character is whatever (string_quote X) sets. Parse reads them into
+// lexer.Expr trees, with bare words as IdentifierValues and quoted ones as
+// StringValues.
+
+// Parse reads a Specctra design (.dsn) or session (.ses) file.
+func Parse(r io.Reader) (lexer.Expr, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return lexer.Expr{}, err
+	}
+	p := &parser{data: string(data)}
+	p.skipSpace()
+	if p.pos >= len(p.data) || p.data[p.pos] != '(' {
+		return lexer.Expr{}, p.errorf("expected '('")
+	}
+	expr, err := p.list()
+	if err != nil {
+		return lexer.Expr{}, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.data) {
+		return lexer.Expr{}, p.errorf("unexpected %q after the end of the file", p.data[p.pos])
+	}
+	return expr, nil
+}
+
+type parser struct {
+	data  string
+	pos   int
+	quote byte // 0 until (string_quote X) is read
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	line := 1 + strings.Count(p.data[:p.pos], "\n")
+	return fmt.Errorf("specctra: line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) && isSpace(p.data[p.pos]) {
+		p.pos++
+	}
+}
+
+// list reads a list starting at '('.
+func (p *parser) list() (lexer.Expr, error) {
+	p.pos++
+	p.skipSpace()
+	identifier := p.word()
+	if identifier == "" {
+		return lexer.Expr{}, p.errorf("expected an identifier after '('")
+	}
+	expr := lexer.Expr{Type: lexer.IdentifierToExprType(identifier), Identifier: identifier}
+
+	if identifier == "string_quote" {
+		// The quote character itself is the value, e.g. (string_quote ")
+		p.skipSpace()
+		if p.pos >= len(p.data) || p.data[p.pos] == ')' {
+			return lexer.Expr{}, p.errorf("expected a character after string_quote")
+		}
+		p.quote = p.data[p.pos]
+		expr.Values = append(expr.Values, lexer.IdentifierValue{Value: string(p.quote)})
+		p.pos++
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return lexer.Expr{}, p.errorf("expected ')' to close (%s, got end of file", identifier)
+		}
+		switch ch := p.data[p.pos]; {
+		case ch == ')':
+			p.pos++
+			return expr, nil
+		case ch == '(':
+			child, err := p.list()
+			if err != nil {
+				return lexer.Expr{}, err
+			}
+			expr.Values = append(expr.Values, lexer.ExprValue{Value: child})
+		case p.quote != 0 && ch == p.quote:
+			end := strings.IndexByte(p.data[p.pos+1:], p.quote)
+			if end < 0 {
+				return lexer.Expr{}, p.errorf("unterminated string")
+			}
+			expr.Values = append(expr.Values, lexer.StringValue{Value: p.data[p.pos+1 : p.pos+1+end]})
+			p.pos += end + 2
+		default:
+			word := p.word()
+			if number, ok := parseNumber(word); ok {
+				expr.Values = append(expr.Values, lexer.NumberValue{Value: number})
+			} else {
+				expr.Values = append(expr.Values, lexer.IdentifierValue{Value: word})
+			}
+		}
+	}
+}
+
+// word reads up to the next space or parenthesis.
+func (p *parser) word() string {
+	start := p.pos
+	for p.pos < len(p.data) && !isSpace(p.data[p.pos]) && p.data[p.pos] != '(' && p.data[p.pos] != ')' {
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+// parseNumber reads words such as 600, -12.5 and .5 as numbers, but not the
+// Inf, NaN and hexadecimal forms strconv also accepts.
+func parseNumber(word string) (float64, bool) {
+	if word == "" || strings.ContainsAny(word, "xXnN_") {
+		return 0, false
+	}
+	if ch := word[0]; !(ch >= '0' && ch <= '9') && ch != '-' && ch != '+' && ch != '.' {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(word, 64)
+	return number, err == nil
+}
+
+// name returns a name to write, quoted with " when it would not read back
+// as the same bare word. Specctra strings have no escapes, so quotes in
+// names become apostrophes.
+func name(s string) lexer.Value {
+	if _, ok := parseNumber(s); !ok && s != "" && !strings.ContainsAny(s, " \t\r\n()\"'") {
+		return lexer.IdentifierValue{Value: s}
+	}
+	return lexer.IdentifierValue{Value: `"` + strings.ReplaceAll(s, `"`, "'") + `"`}
+}
+
+// text returns the name of a bare or quoted word.
+func text(v lexer.Value) (string, bool) {
+	switch v := v.(type) {
+	case lexer.IdentifierValue:
+		return v.Value, true
+	case lexer.StringValue:
+		return v.Value, true
+	case lexer.NumberValue:
+		return lexer.FormatNumber(v.Value), true
+	}
+	return "", false
+}
+
+// children returns the nested lists of e named identifier.
+func children(e lexer.Expr, identifier string) []lexer.Expr {
+	found := []lexer.Expr{}
+	for _, val := range e.Values {
+		if child, ok := val.(lexer.ExprValue); ok && child.Value.Identifier == identifier {
+			found = append(found, child.Value)
+		}
+	}
+	return found
+}
+
+// child returns the first nested list of e named identifier.
+func child(e lexer.Expr, identifier string) (lexer.Expr, bool) {
+	found := children(e, identifier)
+	if len(found) == 0 {
+		return lexer.Expr{}, false
+	}
+	return found[0], true
+}
+
+// atoms returns the values of e that are not nested lists.
+func atoms(e lexer.Expr) []lexer.Value {
+	values := []lexer.Value{}
+	for _, val := range e.Values {
+		if _, ok := val.(lexer.ExprValue); !ok {
+			values = append(values, val)
+		}
+	}
+	return values
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mackeper/lin_router/lexer"
+	"github.com/mackeper/lin_router/pcb"
+)
+
+// BoardStats counts what a board holds and how much of it is routed.
+type BoardStats struct {
+	Nets        int                `json:"nets"` // Net 0, unconnected, is not counted
+	Footprints  int                `json:"footprints"`
+	Pads        int                `json:"pads"`
+	Tracks      int                `json:"tracks"`
+	Vias        int                `json:"vias"`
+	TrackLength map[string]float64 `json:"track_length"` // mm by layer
+	Unrouted    int                `json:"unrouted"`     // Connections in the ratsnest
+}
+
+// ComputeStats counts the items of a board and its unrouted connections.
+func ComputeStats(board *pcb.Board) BoardStats {
+	stats := BoardStats{
+		Footprints:  len(board.Footprints),
+		Pads:        len(board.Pads),
+		Tracks:      len(board.Segments),
+		Vias:        len(board.Vias),
+		TrackLength: make(map[string]float64),
+		Unrouted:    len(Ratsnest(board)),
+	}
+	for number := range netNames(board) {
+		if number != 0 {
+			stats.Nets++
+		}
+	}
+	for _, seg := range board.Segments {
+		stats.TrackLength[seg.Layer] += seg.Length()
+	}
+	return stats
+}
+
+// String lists the counts one per line, e.g.
+//
+//	nets: 2
+//	...
+//	track length F.Cu: 12.5 mm
+func (s BoardStats) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "nets: %d\nfootprints: %d\npads: %d\ntracks: %d\nvias: %d\nunrouted: %d",
+		s.Nets, s.Footprints, s.Pads, s.Tracks, s.Vias, s.Unrouted)
+	layers := []string{}
+	for layer := range s.TrackLength {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+	for _, layer := range layers {
+		fmt.Fprintf(&sb, "\ntrack length %s: %s mm", layer, lexer.FormatNumber(s.TrackLength[layer]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mackeper/lin_router/pcb"
+)
+
+// Route statuses of a RunSummary.
+const (
+	statusRouted  = "routed"  // Every net is connected
+	statusPartial = "partial" // Some nets still have unrouted connections
+	statusUnsafe  = "unsafe"  // Routing added clearance violations
+	statusError   = "error"   // The board could not be read, routed or written
+)
+
+// RunSummary is the result of `lin_router route -report`, for CI and
+// scripts.
+type RunSummary struct {
+	Status        string             `json:"status"`
+	Error         string             `json:"error,omitempty"`
+	Nets          NetSummary         `json:"nets"`
+	SegmentsAdded int                `json:"segments_added"`
+	ViasAdded     int                `json:"vias_added"`
+	WireLength    map[string]float64 `json:"wire_length"` // mm by layer, of every track on the routed board
+	Violations    []Violation        `json:"violations"`
+	NewViolations int                `json:"new_violations"` // Of Violations, those the board did not have before routing
+	Runtime       float64            `json:"runtime"`        // Seconds
+}
+
+// NetSummary lists the nets with two or more pads by name, split by whether
+// they are connected after routing. Nets without a name are listed as
+// "net N".
+type NetSummary struct {
+	Total  int      `json:"total"`
+	Routed []string `json:"routed"`
+	Failed []string `json:"failed"`
+}
+
+// SummarizeRoute compares a board before and after routing. A net counts as
+// routed when the ratsnest of the routed board has no connection left for
+// it, whichever router added its tracks. Violations are checked against
+// clearance, and any the board did not have before make the run unsafe
+// however many nets were connected.
+func SummarizeRoute(before, after *pcb.Board, clearance float64) RunSummary {
+	summary := RunSummary{
+		Status:        statusRouted,
+		Nets:          NetSummary{Routed: []string{}, Failed: []string{}},
+		SegmentsAdded: len(after.Segments) - len(before.Segments),
+		ViasAdded:     len(after.Vias) - len(before.Vias),
+		WireLength:    make(map[string]float64),
+		Violations:    CheckClearance(after, clearance),
+	}
+
+	names := netNames(after)
+	pads := make(map[int]int)
+	for _, pad := range after.Pads {
+		if pad.Net.Number != 0 {
+			pads[pad.Net.Number]++
+		}
+	}
+	nets := []int{}
+	for net, count := range pads {
+		if count > 1 {
+			nets = append(nets, net)
+		}
+	}
+	sort.Ints(nets)
+
+	unrouted := ratsnestByNet(after)
+	for _, net := range nets {
+		name := names[net]
+		if name == "" {
+			name = fmt.Sprintf("net %d", net)
+		}
+		if len(unrouted[net]) > 0 {
+			summary.Nets.Failed = append(summary.Nets.Failed, name)
+		} else {
+			summary.Nets.Routed = append(summary.Nets.Routed, name)
+		}
+	}
+	summary.Nets.Total = len(nets)
+	if len(summary.Nets.Failed) > 0 {
+		summary.Status = statusPartial
+	}
+
+	existing := make(map[Violation]int)
+	for _, violation := range CheckClearance(before, clearance) {
+		existing[violation]++
+	}
+	for _, violation := range summary.Violations {
+		if existing[violation] > 0 {
+			existing[violation]--
+		} else {
+			summary.NewViolations++
+		}
+	}
+	if summary.NewViolations > 0 {
+		summary.Status = statusUnsafe
+	}
+
+	for _, seg := range after.Segments {
+		summary.WireLength[seg.Layer] += seg.Length()
+	}
+	for layer, length := range summary.WireLength {
+		summary.WireLength[layer] = math.Round(length*1e6) / 1e6
+	}
+	return summary
+}
+
+// writeRunSummary writes summary as indented JSON to path, stamped with the
+// time since start. Lists left nil by a failed run are written empty.
+func writeRunSummary(path string, summary RunSummary, start time.Time) error {
+	if summary.Nets.Routed == nil {
+		summary.Nets.Routed = []string{}
+	}
+	if summary.Nets.Failed == nil {
+		summary.Nets.Failed = []string{}
+	}
+	if summary.WireLength == nil {
+		summary.WireLength = make(map[string]float64)
+	}
+	if summary.Violations == nil {
+		summary.Violations = []Violation{}
+	}
+	summary.Runtime = math.Round(time.Since(start).Seconds()*1e3) / 1e3
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeRoute(t *testing.T) {
+	gnd := `(segment (start 9 10) (end 9 20) (width 0.2) (layer "F.Cu") (net 1))`
+	vcc := `(segment (start 11 10) (end 11 20) (width 0.2) (layer "B.Cu") (net 2))
+		(via (at 11 10) (size 0.6) (drill 0.3) (layers "F.Cu" "B.Cu") (net 2))
+		(via (at 11 20) (size 0.6) (drill 0.3) (layers "F.Cu" "B.Cu") (net 2))`
+	near := `(segment (start 9.3 12) (end 9.3 18) (width 0.2) (layer "F.Cu") (net 2))`
+	violation := Violation{Items: [2]string{"segment GND", "segment VCC"}, Layer: "F.Cu", Position: diffPoint{X: 9.3, Y: 12}, Distance: 0.1, Required: 0.2}
+	tests := []struct {
+		name     string
+		before   string // Appended to ratsnestBase
+		after    string
+		expected RunSummary
+	}{
+		{
+			"nothing routed",
+			"",
+			"",
+			RunSummary{
+				Status:     statusPartial,
+				Nets:       NetSummary{Total: 2, Routed: []string{}, Failed: []string{"GND", "VCC"}},
+				WireLength: map[string]float64{},
+				Violations: []Violation{},
+			},
+		},
+		{
+			"one net routed",
+			"",
+			gnd,
+			RunSummary{
+				Status:        statusPartial,
+				Nets:          NetSummary{Total: 2, Routed: []string{"GND"}, Failed: []string{"VCC"}},
+				SegmentsAdded: 1,
+				WireLength:    map[string]float64{"F.Cu": 10},
+				Violations:    []Violation{},
+			},
+		},
+		{
+			"rest routed through vias",
+			gnd,
+			gnd + vcc,
+			RunSummary{
+				Status:        statusRouted,
+				Nets:          NetSummary{Total: 2, Routed: []string{"GND", "VCC"}, Failed: []string{}},
+				SegmentsAdded: 1,
+				ViasAdded:     2,
+				WireLength:    map[string]float64{"F.Cu": 10, "B.Cu": 10},
+				Violations:    []Violation{},
+			},
+		},
+		{
+			"violation added",
+			"",
+			gnd + near,
+			RunSummary{
+				Status:        statusUnsafe,
+				Nets:          NetSummary{Total: 2, Routed: []string{"GND"}, Failed: []string{"VCC"}},
+				SegmentsAdded: 2,
+				WireLength:    map[string]float64{"F.Cu": 16},
+				Violations:    []Violation{violation},
+				NewViolations: 1,
+			},
+		},
+		{
+			"violation kept",
+			gnd + near,
+			gnd + near + vcc,
+			RunSummary{
+				Status:        statusRouted,
+				Nets:          NetSummary{Total: 2, Routed: []string{"GND", "VCC"}, Failed: []string{}},
+				SegmentsAdded: 1,
+				ViasAdded:     2,
+				WireLength:    map[string]float64{"F.Cu": 16, "B.Cu": 10},
+				Violations:    []Violation{violation},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			before, err := ExprToPCB(parseBoardString(t, ratsnestBase+tt.before+")"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := ExprToPCB(parseBoardString(t, ratsnestBase+tt.after+")"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			summary := SummarizeRoute(before, after, 0.2)
+
+			// Assert
+			if !reflect.DeepEqual(summary, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, summary)
+			}
+		})
+	}
+}
+
+// badFootprint is well formed but has a pad size that is not a number.
+const badFootprint = `(footprint "R_0805" (layer "F.Cu") (at 30 30)
+	(pad "1" smd rect (at -1 0) (size x 1) (layers "F.Cu") (net 1 "GND")))
+`
+
+func TestRunRoute_ExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    string // Written to the input file unless empty
+		args     []string
+		expected int
+		status   string
+	}{
+		{"fully routed", ratsnestBase + `(segment (start 9 10) (end 9 20) (width 0.2) (layer "F.Cu") (net 1))
+			(segment (start 11 10) (end 11 20) (width 0.2) (layer "F.Cu") (net 2)))`, nil, exitOK, statusRouted},
+		{"partially routed", ratsnestBase + ")", nil, exitFindings, statusPartial},
+		{"missing input", "", nil, exitError, statusError},
+		{"invalid grid", ratsnestBase + ")", []string{"-router", "pathfinder", "-grid", "0"}, exitError, statusError},
+		{"unreadable footprint", ratsnestBase + badFootprint + ")", nil, exitError, statusError},
+		{"unreadable footprint recovered", ratsnestBase + badFootprint + `(segment (start 9 10) (end 9 20) (width 0.2) (layer "F.Cu") (net 1))
+			(segment (start 11 10) (end 11 20) (width 0.2) (layer "F.Cu") (net 2)))`, []string{"-recover"}, exitOK, statusRouted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			dir := t.TempDir()
+			input := filepath.Join(dir, "board.kicad_pcb")
+			if tt.board != "" {
+				if err := os.WriteFile(input, []byte(tt.board), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			reportPath := filepath.Join(dir, "report.json")
+
+			// Act
+			args := append([]string{"route", "-o", filepath.Join(dir, "routed.kicad_pcb"), "-report", reportPath}, tt.args...)
+			code := run(append(args, input))
+
+			// Assert
+			if code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, code)
+			}
+			data, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatalf("Expected a report, got %v", err)
+			}
+			var summary RunSummary
+			if err := json.Unmarshal(data, &summary); err != nil {
+				t.Fatal(err)
+			}
+			if summary.Status != tt.status {
+				t.Errorf("Expected status %q, got %q", tt.status, summary.Status)
+			}
+		})
+	}
+}
@@ -2,15 +2,51 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
+	"strings"
 )
 
+// uuidNamespace is the namespace from which seeded UUIDs are derived.
+const uuidNamespace = "3b0c4a8e-4f0d-5c1e-9a57-6c696e5f726f"
+
 func GenerateUUID() string {
 	b := make([]byte, 16)
 	_, err := rand.Read(b)
 	if err != nil {
 		panic(fmt.Sprintf("failed to generate UUID: %v", err))
 	}
+	return formatUUID(b)
+}
+
+// GenerateUUIDv5 returns the name-based UUID (RFC 4122 version 5) of name in
+// the given namespace UUID.
+func GenerateUUIDv5(namespace, name string) string {
+	h := sha1.New()
+	h.Write(parseUUID(namespace))
+	h.Write([]byte(name))
+	b := h.Sum(nil)[:16]
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUUID(b)
+}
+
+// GenerateSeededUUID returns a UUID that only depends on the seed and name, so
+// the same inputs always give the same UUID.
+func GenerateSeededUUID(seed, name string) string {
+	return GenerateUUIDv5(GenerateUUIDv5(uuidNamespace, seed), name)
+}
+
+func formatUUID(b []byte) string {
 	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
 		b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
+
+func parseUUID(s string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil {
+		panic(fmt.Sprintf("invalid UUID %q: %v", s, err))
+	}
+	return b
+}
@@ -32,3 +32,35 @@ func TestGenerateUUID_Uniqueness(t *testing.T) {
 		t.Errorf("UUIDs not unique: %s, %s, %s", uuid1, uuid2, uuid3)
 	}
 }
+
+func TestGenerateUUIDv5_KnownValue(t *testing.T) {
+	// Arrange
+	dnsNamespace := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+	// Act
+	uuid := GenerateUUIDv5(dnsNamespace, "python.org")
+
+	// Assert
+	if uuid != "886313e1-3b8a-5372-9b90-0c9aee199e5d" {
+		t.Errorf("Unexpected UUIDv5: %s", uuid)
+	}
+}
+
+func TestGenerateSeededUUID_Deterministic(t *testing.T) {
+	// Act
+	uuid1 := GenerateSeededUUID("seed", "segment:1")
+	uuid2 := GenerateSeededUUID("seed", "segment:1")
+	uuid3 := GenerateSeededUUID("seed", "segment:2")
+	uuid4 := GenerateSeededUUID("other", "segment:1")
+
+	// Assert
+	if uuid1 != uuid2 {
+		t.Errorf("Expected equal UUIDs for equal inputs, got %s and %s", uuid1, uuid2)
+	}
+	if uuid1 == uuid3 || uuid1 == uuid4 {
+		t.Errorf("Expected different UUIDs for different inputs, got %s, %s, %s", uuid1, uuid3, uuid4)
+	}
+	if uuid1[14] != '5' {
+		t.Errorf("Expected version 5 UUID, got %s", uuid1)
+	}
+}